@@ -0,0 +1,139 @@
+package cidrtree
+
+import (
+	"net/netip"
+
+	"github.com/gaissmai/extnetip"
+)
+
+// NextFree returns the lowest sub-prefix of scope with the given bits, that doesn't overlap
+// any entry of the table, e.g. the next free /24 in a /16 or the next free host with bits 32 or 128.
+// An entry equal to scope occupies the whole scope, entries strictly covering scope are
+// the pool and not counted as allocations.
+// Returns false if scope is exhausted or bits is not within scope.Bits() and the address bit length.
+//
+// Free, singly allocated and fully allocated parts of scope are skipped in O(log n), e.g. the
+// allocated hosts of AllocateNext. Only parts with allocations of mixed sizes must be descended.
+func (t Table[V]) NextFree(scope netip.Prefix, bits int) (netip.Prefix, bool) {
+	scope = scope.Masked() // always canonicalize!
+	if !scope.IsValid() || bits < scope.Bits() || bits > scope.Addr().BitLen() {
+		return netip.Prefix{}, false
+	}
+
+	n := t.root6
+	if scope.Addr().Is4() {
+		n = t.root4
+	}
+
+	// the supernets of scope are the pool, not checked
+	return n.nextFree(scope, bits)
+}
+
+// AllocateNext inserts the next free host address of scope with value, see [Table.NextFree].
+// Returns false if scope is exhausted.
+func (t *Table[V]) AllocateNext(scope netip.Prefix, value V) (netip.Addr, bool) {
+	t.mustNotBeFrozen()
+
+	pfx, ok := t.NextFree(scope, scope.Addr().BitLen())
+	if !ok {
+		return netip.Addr{}, false
+	}
+
+	t.Insert(pfx, value)
+	return pfx.Addr(), true
+}
+
+// nextFree rec-descent, binary search in the address space of p for the lowest free sub-prefix with bits.
+// Fully allocated parts of p are skipped with the augmented counts and prefix lengths, see within.
+func (n *node[V]) nextFree(p netip.Prefix, bits int) (netip.Prefix, bool) {
+	// p itself is occupied, the supernets of p within scope are already checked on the way down
+	if n.find(p) != nil {
+		return netip.Prefix{}, false
+	}
+
+	// nothing allocated in p, take the first sub-prefix
+	count, minBits, maxBits := n.within(p)
+	if count == 0 {
+		return netip.PrefixFrom(p.Addr(), bits), true
+	}
+
+	if p.Bits() == bits {
+		return netip.Prefix{}, false
+	}
+
+	// all sub-prefixes with bits are allocated, e.g. all host addresses
+	if minBits == bits && maxBits == bits && bits-p.Bits() < 31 && count == 1<<(bits-p.Bits()) {
+		return netip.Prefix{}, false
+	}
+
+	lo := netip.PrefixFrom(p.Addr(), p.Bits()+1)
+	if pfx, ok := n.nextFree(lo, bits); ok {
+		return pfx, true
+	}
+	return n.nextFree(siblingOf(lo), bits)
+}
+
+// within returns the number of entries strictly covered by the canonical p, and the min and
+// max prefix length of these entries, in O(log n) with the augmented sizes and prefix lengths.
+//
+// The entries within p are a contiguous range in the BST order, the successors of p up to the
+// last address of p.
+func (n *node[V]) within(p netip.Prefix) (count, minBits, maxBits int) {
+	_, last := extnetip.Range(p)
+	inRange := func(m *node[V]) (after, before bool) {
+		return compare(m.cidr, p) > 0, m.cidr.Addr().Compare(last) <= 0
+	}
+
+	// find the top node of the range
+	for n != nil {
+		after, before := inRange(n)
+		if !after {
+			n = n.right
+			continue
+		}
+		if !before {
+			n = n.left
+			continue
+		}
+		break
+	}
+	if n == nil {
+		return 0, 0, 0
+	}
+
+	minBits, maxBits = n.cidr.Bits(), n.cidr.Bits()
+	add := func(m *node[V]) {
+		if m == nil {
+			return
+		}
+		count += m.getSize()
+		minBits = min(minBits, int(m.minBits))
+		maxBits = max(maxBits, int(m.maxBits))
+	}
+	count = 1
+
+	// left boundary of the range, all right subtrees on the way are within
+	for m := n.left; m != nil; {
+		if after, _ := inRange(m); !after {
+			m = m.right
+			continue
+		}
+		count++
+		minBits, maxBits = min(minBits, m.cidr.Bits()), max(maxBits, m.cidr.Bits())
+		add(m.right)
+		m = m.left
+	}
+
+	// right boundary of the range, all left subtrees on the way are within
+	for m := n.right; m != nil; {
+		if _, before := inRange(m); !before {
+			m = m.left
+			continue
+		}
+		count++
+		minBits, maxBits = min(minBits, m.cidr.Bits()), max(maxBits, m.cidr.Bits())
+		add(m.left)
+		m = m.right
+	}
+	return count, minBits, maxBits
+}
@@ -0,0 +1,116 @@
+package cidrtree_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gaissmai/cidrtree"
+)
+
+func TestNextFree(t *testing.T) {
+	t.Parallel()
+
+	rtbl := new(cidrtree.Table[string])
+	rtbl.Insert(mustPfx("10.0.0.0/8"), "pool")
+	rtbl.Insert(mustPfx("10.0.0.0/24"), "a")
+	rtbl.Insert(mustPfx("10.0.1.0/25"), "b")
+	rtbl.Insert(mustPfx("10.0.3.0/24"), "c")
+
+	tcs := []struct {
+		scope  string
+		bits   int
+		want   string
+		wantOK bool
+	}{
+		{"10.0.0.0/16", 24, "10.0.2.0/24", true},
+		{"10.0.0.0/16", 25, "10.0.1.128/25", true},
+		{"10.0.0.0/16", 32, "10.0.1.128/32", true},
+		{"10.0.0.0/16", 16, "", false},
+		{"10.0.0.0/16", 15, "", false},
+		{"10.0.1.0/24", 26, "10.0.1.128/26", true},
+		// the scope entry itself is occupied
+		{"10.0.3.0/24", 32, "", false},
+		{"10.0.3.0/24", 24, "", false},
+		// the covering entry is the pool
+		{"10.1.0.0/16", 24, "10.1.0.0/24", true},
+		{"10.0.0.0/8", 24, "", false},
+		{"2001:db8::/32", 64, "2001:db8::/64", true},
+	}
+
+	for _, tc := range tcs {
+		got, ok := rtbl.NextFree(mustPfx(tc.scope), tc.bits)
+		if ok != tc.wantOK || (ok && got != mustPfx(tc.want)) {
+			t.Errorf("NextFree(%s, %d), got (%v, %v), want (%s, %v)", tc.scope, tc.bits, got, ok, tc.want, tc.wantOK)
+		}
+	}
+}
+
+func TestAllocateNext(t *testing.T) {
+	t.Parallel()
+
+	rtbl := new(cidrtree.Table[int])
+	scope := mustPfx("192.168.0.0/22")
+
+	for i := 0; i < 1024; i++ {
+		addr, ok := rtbl.AllocateNext(scope, i)
+		if !ok {
+			t.Fatalf("AllocateNext(%v) #%d, got false, want true", scope, i)
+		}
+		if i == 0 && addr != mustAddr("192.168.0.0") {
+			t.Fatalf("AllocateNext(%v), got %v, want %v", scope, addr, "192.168.0.0")
+		}
+	}
+
+	if _, ok := rtbl.AllocateNext(scope, 0); ok {
+		t.Errorf("AllocateNext(%v) exhausted, got true, want false", scope)
+	}
+
+	// released addresses are reused, the lowest first
+	rtbl.Delete(mustPfx("192.168.2.7/32"))
+	rtbl.Delete(mustPfx("192.168.1.9/32"))
+	if addr, _ := rtbl.AllocateNext(scope, 0); addr != mustAddr("192.168.1.9") {
+		t.Errorf("AllocateNext(%v), got %v, want %v", scope, addr, "192.168.1.9")
+	}
+	if rtbl.Size() != 1023 {
+		t.Errorf("Size(), got %d, want %d", rtbl.Size(), 1023)
+	}
+
+	// an existing host entry as scope is occupied, not overwritten
+	host := mustPfx("10.0.0.1/32")
+	rtbl.Insert(host, -1)
+	if addr, ok := rtbl.AllocateNext(host, 2); ok {
+		t.Errorf("AllocateNext(%v), got (%v, %v), want false", host, addr, ok)
+	}
+	if _, value, _ := rtbl.LookupPrefix(host); value != -1 {
+		t.Errorf("AllocateNext(%v) overwrote the entry, got value %v, want %v", host, value, -1)
+	}
+}
+
+func TestAllocateNextMostlyFull(t *testing.T) {
+	t.Parallel()
+
+	rtbl := new(cidrtree.Table[int])
+	scope := mustPfx("10.0.0.0/16")
+
+	// the fully allocated parts are skipped, no walk over all allocations per call
+	start := time.Now()
+	for i := 0; i < 60_000; i++ {
+		if _, ok := rtbl.AllocateNext(scope, i); !ok {
+			t.Fatalf("AllocateNext(%v) #%d, got false, want true", scope, i)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("60000 x AllocateNext(%v), took %v", scope, elapsed)
+	}
+
+	start = time.Now()
+	if got, ok := rtbl.NextFree(scope, 32); !ok || got != mustPfx("10.0.234.96/32") {
+		t.Errorf("NextFree(%v, 32), got (%v, %v), want %v", scope, got, ok, "10.0.234.96/32")
+	}
+	if got, ok := rtbl.NextFree(scope, 24); !ok || got != mustPfx("10.0.235.0/24") {
+		t.Errorf("NextFree(%v, 24), got (%v, %v), want %v", scope, got, ok, "10.0.235.0/24")
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("NextFree(%v) of mostly full scope, took %v", scope, elapsed)
+	}
+}
@@ -0,0 +1,108 @@
+package cidrtree
+
+import (
+	"net/netip"
+	"sync"
+	"sync/atomic"
+)
+
+// Atomic is a routing table for concurrent use, the zero value is ready to use.
+//
+// Readers get lock-free and consistent snapshots, writers are serialized and publish
+// a new version with the immutable methods, copy-on-write, and an atomic swap.
+// The published tables are frozen, see [Table.Freeze].
+// This is the building block for lookup services with concurrent updates.
+type Atomic[V any] struct {
+	mu  sync.Mutex // serializes the writers
+	ptr atomic.Pointer[Table[V]]
+}
+
+// Load returns the current snapshot of the table, never nil.
+// The snapshot is frozen, use Update or Store instead.
+func (a *Atomic[V]) Load() *Table[V] {
+	if t := a.ptr.Load(); t != nil {
+		return t
+	}
+	return &Table[V]{frozen: true}
+}
+
+// Store replaces the table, t is frozen.
+func (a *Atomic[V]) Store(t *Table[V]) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	t.Freeze()
+	a.ptr.Store(t)
+}
+
+// Update publishes the table returned by fn. The writers are serialized, fn gets the current
+// snapshot and must not modify it, use the immutable methods like InsertImmutable.
+// The returned table is frozen.
+func (a *Atomic[V]) Update(fn func(t Table[V]) *Table[V]) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	next := fn(*a.Load())
+	next.Freeze()
+	a.ptr.Store(next)
+}
+
+// Insert adds pfx to the table, see [Table.Insert].
+func (a *Atomic[V]) Insert(pfx netip.Prefix, value V) {
+	a.Update(func(t Table[V]) *Table[V] {
+		return t.InsertImmutable(pfx, value)
+	})
+}
+
+// CompareAndInsert adds pfx to the table if value wins against the current value,
+// see [Table.CompareAndInsert]. The comparison and the insert are atomic for all writers.
+func (a *Atomic[V]) CompareAndInsert(pfx netip.Prefix, value V, better func(value, old V) bool) (won bool) {
+	a.Update(func(t Table[V]) *Table[V] {
+		next, ok := t.CompareAndInsertImmutable(pfx, value, better)
+		won = ok
+		return next
+	})
+	return won
+}
+
+// CompareAndSwap replaces the value of pfx with value if the current value equals old,
+// see [Table.CompareAndSwap]. The comparison and the swap are atomic for all writers.
+func (a *Atomic[V]) CompareAndSwap(pfx netip.Prefix, old, value V, equal func(a, b V) bool) (swapped bool) {
+	a.Update(func(t Table[V]) *Table[V] {
+		next, ok := t.CompareAndSwapImmutable(pfx, old, value, equal)
+		swapped = ok
+		return next
+	})
+	return swapped
+}
+
+// Delete removes pfx from the table, see [Table.Delete].
+func (a *Atomic[V]) Delete(pfx netip.Prefix) (found bool) {
+	a.Update(func(t Table[V]) *Table[V] {
+		next, ok := t.DeleteImmutable(pfx)
+		found = ok
+		return next
+	})
+	return found
+}
+
+// Lookup returns the longest-prefix-match for ip in the current snapshot, see [Table.Lookup].
+func (a *Atomic[V]) Lookup(ip netip.Addr) (lpm netip.Prefix, value V, ok bool) {
+	return a.Load().Lookup(ip)
+}
+
+// LookupPrefix returns the longest-prefix-match for pfx in the current snapshot, see [Table.LookupPrefix].
+func (a *Atomic[V]) LookupPrefix(pfx netip.Prefix) (lpm netip.Prefix, value V, ok bool) {
+	return a.Load().LookupPrefix(pfx)
+}
+
+// Iter returns a snapshot-stable iterator over the current table, see [Iterator].
+// Concurrent updates are not visible to the iterator.
+func (a *Atomic[V]) Iter() *Iterator[V] {
+	return a.Load().Iter()
+}
+
+// Walk iterates the current snapshot in ascending order, see [Table.Walk].
+// Concurrent updates are not visible to the walk.
+func (a *Atomic[V]) Walk(cb func(pfx netip.Prefix, value V) bool) {
+	a.Load().Walk(cb)
+}
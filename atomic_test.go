@@ -0,0 +1,113 @@
+package cidrtree_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/gaissmai/cidrtree"
+)
+
+func TestAtomic(t *testing.T) {
+	t.Parallel()
+
+	var at cidrtree.Atomic[any]
+	if _, _, ok := at.Lookup(mustAddr("10.0.0.1")); ok {
+		t.Errorf("Lookup() of zero value, got %v, want false", ok)
+	}
+
+	snapshot := at.Load()
+
+	var wg sync.WaitGroup
+	for _, r := range routes {
+		wg.Add(1)
+		go func(r route) {
+			defer wg.Done()
+			at.Insert(r.cidr, r.nextHop)
+			_, _, _ = at.Lookup(r.cidr.Addr())
+		}(r)
+	}
+	wg.Wait()
+
+	if got, want := at.Load().String(), asTopoStr; got != want {
+		t.Errorf("Atomic after concurrent Insert, got:\n%v\nwant:\n%v", got, want)
+	}
+
+	if got := snapshot.String(); got != "" {
+		t.Errorf("snapshot was modified, got:\n%v", got)
+	}
+
+	if lpm, _, _ := at.LookupPrefix(mustPfx("10.0.1.0/25")); lpm != mustPfx("10.0.1.0/24") {
+		t.Errorf("LookupPrefix(%v), got %v, want %v", "10.0.1.0/25", lpm, "10.0.1.0/24")
+	}
+
+	if !at.Delete(mustPfx("10.0.1.0/24")) {
+		t.Errorf("Delete(%v), got false, want true", "10.0.1.0/24")
+	}
+	if at.Delete(mustPfx("10.0.1.0/24")) {
+		t.Errorf("Delete(%v), got true, want false", "10.0.1.0/24")
+	}
+
+	at.Store(new(cidrtree.Table[any]))
+	if at.Load().String() != "" {
+		t.Errorf("Store(), table isn't empty")
+	}
+}
+
+func TestAtomicCompareAndInsert(t *testing.T) {
+	t.Parallel()
+
+	var at cidrtree.Atomic[int]
+	pfx := mustPfx("10.0.0.0/8")
+	higher := func(value, old int) bool { return value > old }
+
+	var wg sync.WaitGroup
+	for i := 1; i <= 100; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			at.CompareAndInsert(pfx, i, higher)
+		}(i)
+	}
+	wg.Wait()
+
+	if _, value, _ := at.LookupPrefix(pfx); value != 100 {
+		t.Errorf("CompareAndInsert concurrent, got value %v, want %v", value, 100)
+	}
+
+	if won := at.CompareAndInsert(pfx, 42, higher); won {
+		t.Errorf("CompareAndInsert(%v, %v), got %v, want false", pfx, 42, won)
+	}
+}
+
+func TestAtomicCompareAndSwap(t *testing.T) {
+	t.Parallel()
+
+	var at cidrtree.Atomic[int]
+	pfx := mustPfx("10.0.0.0/8")
+	at.Insert(pfx, 0)
+	equal := func(a, b int) bool { return a == b }
+
+	// optimistic increments, retry on conflict
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				_, old, _ := at.LookupPrefix(pfx)
+				if at.CompareAndSwap(pfx, old, old+1, equal) {
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if _, value, _ := at.LookupPrefix(pfx); value != 100 {
+		t.Errorf("CompareAndSwap concurrent, got value %v, want %v", value, 100)
+	}
+
+	if swapped := at.CompareAndSwap(pfx, 42, 43, equal); swapped {
+		t.Errorf("CompareAndSwap(%v, 42, 43), got %v, want false", pfx, swapped)
+	}
+}
@@ -0,0 +1,74 @@
+package cidrtree
+
+import "net/netip"
+
+// Backend is the minimal routing table API, call sites written against Backend
+// can switch the data structure without changes, e.g. a radix or ART-style tree
+// in another package. The treap [Table] is the default implementation.
+type Backend[V any] interface {
+	Insert(pfx netip.Prefix, value V)
+	Delete(pfx netip.Prefix) bool
+	Lookup(ip netip.Addr) (lpm netip.Prefix, value V, ok bool)
+	LookupPrefix(pfx netip.Prefix) (lpm netip.Prefix, value V, ok bool)
+	Walk(cb func(pfx netip.Prefix, value V) bool)
+}
+
+var (
+	_ Backend[any] = (*Table[any])(nil)
+	_ Backend[any] = (*Atomic[any])(nil)
+	_ Backend[any] = (*Sharded[any])(nil)
+	_ Backend[any] = (*LazyTable[any])(nil)
+	_ Backend[any] = (*Mirror[any])(nil)
+)
+
+// Mirror is a Backend for A/B tests of two backends against production traffic.
+// All mutations are applied to both backends, the lookups are answered by Primary
+// and compared with the lookups of Candidate.
+type Mirror[V any] struct {
+	Primary   Backend[V]
+	Candidate Backend[V]
+
+	// OnMismatch is called if the candidate matches another prefix than the primary,
+	// the query is an [netip.Addr] for Lookup or a [netip.Prefix] for LookupPrefix.
+	// The values aren't compared, V may not be comparable.
+	OnMismatch func(query any, want, got netip.Prefix)
+}
+
+// Insert inserts pfx into both backends.
+func (m *Mirror[V]) Insert(pfx netip.Prefix, value V) {
+	m.Primary.Insert(pfx, value)
+	m.Candidate.Insert(pfx, value)
+}
+
+// Delete deletes pfx from both backends, returns the result of the primary.
+func (m *Mirror[V]) Delete(pfx netip.Prefix) bool {
+	m.Candidate.Delete(pfx)
+	return m.Primary.Delete(pfx)
+}
+
+// Lookup returns the lookup of the primary, the candidate is compared.
+func (m *Mirror[V]) Lookup(ip netip.Addr) (lpm netip.Prefix, value V, ok bool) {
+	lpm, value, ok = m.Primary.Lookup(ip)
+
+	got, _, _ := m.Candidate.Lookup(ip)
+	if got != lpm && m.OnMismatch != nil {
+		m.OnMismatch(ip, lpm, got)
+	}
+	return
+}
+
+// LookupPrefix returns the lookup of the primary, the candidate is compared.
+func (m *Mirror[V]) LookupPrefix(pfx netip.Prefix) (lpm netip.Prefix, value V, ok bool) {
+	lpm, value, ok = m.Primary.LookupPrefix(pfx)
+
+	got, _, _ := m.Candidate.LookupPrefix(pfx)
+	if got != lpm && m.OnMismatch != nil {
+		m.OnMismatch(pfx, lpm, got)
+	}
+	return
+}
+
+// Walk iterates the primary.
+func (m *Mirror[V]) Walk(cb func(pfx netip.Prefix, value V) bool) {
+	m.Primary.Walk(cb)
+}
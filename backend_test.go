@@ -0,0 +1,46 @@
+package cidrtree_test
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/gaissmai/cidrtree"
+)
+
+func TestMirror(t *testing.T) {
+	t.Parallel()
+
+	var mismatches int
+	m := &cidrtree.Mirror[int]{
+		Primary:   new(cidrtree.Table[int]),
+		Candidate: cidrtree.NewSharded[int](4),
+		OnMismatch: func(query any, want, got netip.Prefix) {
+			mismatches++
+			t.Errorf("mismatch for %v, want %v, got %v", query, want, got)
+		},
+	}
+
+	var b cidrtree.Backend[int] = m
+	cidrs := shuffleFullTable(10_000)
+	for i, cidr := range cidrs {
+		b.Insert(cidr, i)
+	}
+	for _, cidr := range cidrs[:1_000] {
+		b.Delete(cidr)
+	}
+	for _, cidr := range cidrs {
+		b.Lookup(cidr.Addr())
+		b.LookupPrefix(cidr)
+	}
+
+	// a broken candidate
+	m.Candidate.Delete(cidrs[5_000])
+	m.OnMismatch = func(any, netip.Prefix, netip.Prefix) { mismatches++ }
+
+	if lpm, _, _ := b.LookupPrefix(cidrs[5_000]); lpm != cidrs[5_000] {
+		t.Errorf("LookupPrefix(%v), got %v, want the primary answer", cidrs[5_000], lpm)
+	}
+	if mismatches != 1 {
+		t.Errorf("mismatches, got %d, want %d", mismatches, 1)
+	}
+}
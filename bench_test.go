@@ -1,14 +1,10 @@
 package cidrtree_test
 
 import (
-	"bufio"
-	"compress/gzip"
 	"fmt"
 	"log"
 	mrand "math/rand"
 	"net/netip"
-	"os"
-	"strings"
 	"testing"
 
 	"github.com/gaissmai/cidrtree"
@@ -43,6 +39,26 @@ func BenchmarkLookup(b *testing.B) {
 	}
 }
 
+func BenchmarkContains(b *testing.B) {
+	for k := 1; k <= 100_000; k *= 10 {
+		rt := new(cidrtree.Table[any])
+		cidrs := shuffleFullTable(k)
+		for _, cidr := range cidrs {
+			rt.Insert(cidr, nil)
+		}
+		probe := cidrs[mrand.Intn(k)]
+		ip := probe.Addr()
+		name := fmt.Sprintf("In%10s", intMap[k])
+
+		b.ResetTimer()
+		b.Run(name, func(b *testing.B) {
+			for n := 0; n < b.N; n++ {
+				_ = rt.Contains(ip)
+			}
+		})
+	}
+}
+
 func BenchmarkLookupPrefix(b *testing.B) {
 	for k := 1; k <= 100_000; k *= 10 {
 		rt := new(cidrtree.Table[any])
@@ -97,6 +113,43 @@ func BenchmarkInsert(b *testing.B) {
 	}
 }
 
+// BenchmarkBuildParallel, many tables are built concurrently, the random priorities must not contend.
+func BenchmarkBuildParallel(b *testing.B) {
+	cidrs := shuffleFullTable(1_000)
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			rt := new(cidrtree.Table[any])
+			for _, cidr := range cidrs {
+				rt.Insert(cidr, nil)
+			}
+		}
+	})
+}
+
+func BenchmarkInsertManyImmutable(b *testing.B) {
+	for k := 1; k <= 100_000; k *= 10 {
+		rt := new(cidrtree.Table[any])
+		for _, cidr := range shuffleFullTable(100_000) {
+			rt.Insert(cidr, nil)
+		}
+
+		var entries []cidrtree.Entry[any]
+		for _, cidr := range shuffleFullTable(k) {
+			entries = append(entries, cidrtree.Entry[any]{Prefix: cidr})
+		}
+		name := fmt.Sprintf("%10s", intMap[k])
+
+		b.ResetTimer()
+		b.Run(name, func(b *testing.B) {
+			for n := 0; n < b.N; n++ {
+				_ = rt.InsertManyImmutable(entries)
+			}
+		})
+	}
+}
+
 func BenchmarkDelete(b *testing.B) {
 	for k := 1; k <= 100_000; k *= 10 {
 		rt := new(cidrtree.Table[any])
@@ -116,6 +169,28 @@ func BenchmarkDelete(b *testing.B) {
 	}
 }
 
+func BenchmarkCompiledLookup(b *testing.B) {
+	for k := 1; k <= 100_000; k *= 10 {
+		rt := new(cidrtree.Table[any])
+		cidrs := shuffleFullTable(k)
+		for _, cidr := range cidrs {
+			rt.Insert(cidr, nil)
+		}
+		c := rt.Compile()
+
+		probe := cidrs[mrand.Intn(k)]
+		ip := probe.Addr()
+		name := fmt.Sprintf("In%10s", intMap[k])
+
+		b.ResetTimer()
+		b.Run(name, func(b *testing.B) {
+			for n := 0; n < b.N; n++ {
+				_, _, _ = c.Lookup(ip)
+			}
+		})
+	}
+}
+
 // #####################################################
 // helpers
 // #####################################################
@@ -141,27 +216,29 @@ func shuffleFullTable(n int) []netip.Prefix {
 }
 
 func loadFullTable() []netip.Prefix {
-	var routes []netip.Prefix
-
-	file, err := os.Open(prefixFile)
+	routes, err := cidrtree.LoadPrefixFile(prefixFile)
 	if err != nil {
 		log.Fatal(err)
 	}
+	return routes
+}
 
-	rgz, err := gzip.NewReader(file)
-	if err != nil {
-		log.Fatal(err)
-	}
+func BenchmarkFilterByPrefix(b *testing.B) {
+	for k := 1_000; k <= 100_000; k *= 10 {
+		rt := new(cidrtree.Table[any])
+		cidrs := shuffleFullTable(k)
+		for _, cidr := range cidrs {
+			rt.Insert(cidr, nil)
+		}
+		probe := cidrs[mrand.Intn(k)]
+		name := fmt.Sprintf("In%10s", intMap[k])
 
-	scanner := bufio.NewScanner(rgz)
-	for scanner.Scan() {
-		line := scanner.Text()
-		line = strings.TrimSpace(line)
-		cidr := netip.MustParsePrefix(line)
-		routes = append(routes, cidr)
-	}
-	if err := scanner.Err(); err != nil {
-		log.Printf("reading from %v, %v", rgz, err)
+		b.ResetTimer()
+		b.Run(name, func(b *testing.B) {
+			for n := 0; n < b.N; n++ {
+				for it := rt.Iter().FilterByPrefix(probe); it.Next(); {
+				}
+			}
+		})
 	}
-	return routes
 }
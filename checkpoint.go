@@ -0,0 +1,191 @@
+package cidrtree
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Store persists snapshots of a table, see [Checkpointer].
+type Store[V any] interface {
+	Save(t *Table[V]) error
+}
+
+// FileStore saves the table as versioned JSON snapshot to the file at Path,
+// the snapshots of older versions of this package are migrated by Load.
+// The file is replaced atomically, a crash never leaves a partially written snapshot.
+type FileStore[V any] struct {
+	Path string
+}
+
+// Save writes the table to a temporary file in the same directory and renames it to Path,
+// the directory is synced to make the rename durable.
+func (s FileStore[V]) Save(t *Table[V]) error {
+	data, err := t.marshalSnapshot()
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.Path), filepath.Base(s.Path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("cidrtree: checkpoint: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // no-op after a successful rename
+
+	if _, err = tmp.Write(data); err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), s.Path)
+	}
+	if err == nil {
+		err = syncDir(filepath.Dir(s.Path))
+	}
+	if err != nil {
+		return fmt.Errorf("cidrtree: checkpoint: %w", err)
+	}
+	return nil
+}
+
+// syncDir flushes the directory entries to disk, e.g. after a rename.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	err = d.Sync()
+	if cerr := d.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Load reads the table from the file at Path.
+func (s FileStore[V]) Load() (*Table[V], error) {
+	data, err := os.ReadFile(s.Path)
+	if err != nil {
+		return nil, fmt.Errorf("cidrtree: checkpoint: %w", err)
+	}
+
+	t := new(Table[V])
+	if err := t.UnmarshalJSON(data); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Checkpointer saves snapshots of an [Atomic] table to a [Store], on an interval
+// and/or after a number of mutations. The snapshots are free, the published tables
+// of Atomic are immutable. Unchanged snapshots are not saved again.
+//
+// The mutations must be made with the methods of the Checkpointer to be counted.
+type Checkpointer[V any] struct {
+	table *Atomic[V]
+	store Store[V]
+
+	// Interval between the checkpoints in Run, 0 disables the timer.
+	Interval time.Duration
+
+	// Every is the number of mutations after which Run makes a checkpoint, 0 disables counting.
+	Every int
+
+	// OnError is called with the errors of the checkpoints in Run, may be nil.
+	OnError func(error)
+
+	mutations atomic.Int64
+	kick      chan struct{}
+
+	mu   sync.Mutex // serializes the saves
+	last *Table[V]  // the last saved snapshot
+}
+
+// NewCheckpointer returns a Checkpointer for the table and store.
+func NewCheckpointer[V any](table *Atomic[V], store Store[V]) *Checkpointer[V] {
+	return &Checkpointer[V]{
+		table: table,
+		store: store,
+		kick:  make(chan struct{}, 1),
+	}
+}
+
+// Checkpoint saves the current snapshot now, unless it is already saved.
+func (c *Checkpointer[V]) Checkpoint() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	snap := c.table.Load()
+	if snap == c.last {
+		return nil
+	}
+
+	// the mutations during the save are counted for the next checkpoint
+	n := c.mutations.Load()
+	if err := c.store.Save(snap); err != nil {
+		return err
+	}
+	c.mutations.Add(-n)
+	c.last = snap
+	return nil
+}
+
+// Run makes the checkpoints until ctx is done, then a final checkpoint is made and its error returned.
+func (c *Checkpointer[V]) Run(ctx context.Context) error {
+	var tick <-chan time.Time
+	if c.Interval > 0 {
+		ticker := time.NewTicker(c.Interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return c.Checkpoint()
+		case <-tick:
+		case <-c.kick:
+		}
+
+		if err := c.Checkpoint(); err != nil && c.OnError != nil {
+			c.OnError(err)
+		}
+	}
+}
+
+// Update publishes the table returned by fn and counts the mutation, see [Atomic.Update].
+func (c *Checkpointer[V]) Update(fn func(t Table[V]) *Table[V]) {
+	c.table.Update(fn)
+	c.mutated()
+}
+
+// Insert adds pfx to the table and counts the mutation.
+func (c *Checkpointer[V]) Insert(pfx netip.Prefix, value V) {
+	c.table.Insert(pfx, value)
+	c.mutated()
+}
+
+// Delete removes pfx from the table and counts the mutation.
+func (c *Checkpointer[V]) Delete(pfx netip.Prefix) bool {
+	found := c.table.Delete(pfx)
+	if found {
+		c.mutated()
+	}
+	return found
+}
+
+// mutated counts the mutations and kicks Run after Every mutations.
+func (c *Checkpointer[V]) mutated() {
+	if n := c.mutations.Add(1); c.Every > 0 && n >= int64(c.Every) {
+		select {
+		case c.kick <- struct{}{}:
+		default: // already kicked
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package cidrtree_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gaissmai/cidrtree"
+)
+
+// chanStore sends the saved snapshots
+type chanStore struct {
+	saved chan string
+}
+
+func (s chanStore) Save(t *cidrtree.Table[int]) error {
+	s.saved <- t.String()
+	return nil
+}
+
+func TestFileStore(t *testing.T) {
+	t.Parallel()
+
+	store := cidrtree.FileStore[any]{Path: filepath.Join(t.TempDir(), "table.json")}
+
+	rtbl := new(cidrtree.Table[any])
+	for _, route := range routes {
+		rtbl.Insert(route.cidr, route.nextHop.String())
+	}
+
+	if err := store.Save(rtbl); err != nil {
+		t.Fatal(err)
+	}
+
+	// overwrite
+	if err := store.Save(rtbl); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.String() != asTopoStr {
+		t.Errorf("FileStore.Load, got:\n%v\nwant:\n%v", got, asTopoStr)
+	}
+
+	matches, _ := filepath.Glob(store.Path + ".tmp*")
+	if len(matches) != 0 {
+		t.Errorf("FileStore.Save, temporary files left: %v", matches)
+	}
+
+	if _, err := (cidrtree.FileStore[any]{Path: filepath.Join(t.TempDir(), "missing")}).Load(); err == nil {
+		t.Errorf("FileStore.Load of missing file, expected error")
+	}
+}
+
+func TestCheckpointer(t *testing.T) {
+	t.Parallel()
+
+	store := chanStore{saved: make(chan string, 10)}
+	c := cidrtree.NewCheckpointer[int](new(cidrtree.Atomic[int]), store)
+	c.Every = 2
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- c.Run(ctx) }()
+
+	c.Insert(mustPfx("10.0.0.0/8"), 1)
+	c.Insert(mustPfx("::/0"), 2)
+
+	select {
+	case got := <-store.saved:
+		if want := "▼\n└─ 10.0.0.0/8 (1)\n▼\n└─ ::/0 (2)\n"; got != want {
+			t.Errorf("Checkpoint after 2 mutations, got:\n%v\nwant:\n%v", got, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no checkpoint after 2 mutations")
+	}
+
+	// unchanged, not saved again
+	if err := c.Checkpoint(); err != nil {
+		t.Fatal(err)
+	}
+
+	c.Delete(mustPfx("::/0"))
+	cancel()
+
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := <-store.saved, "▼\n└─ 10.0.0.0/8 (1)\n"; got != want {
+		t.Errorf("final checkpoint, got:\n%v\nwant:\n%v", got, want)
+	}
+	if len(store.saved) != 0 {
+		t.Errorf("unexpected checkpoints: %d", len(store.saved))
+	}
+}
+
+// failStore fails the first save
+type failStore struct {
+	chanStore
+	failed *atomic.Bool
+}
+
+func (s failStore) Save(t *cidrtree.Table[int]) error {
+	if s.failed.CompareAndSwap(false, true) {
+		return errors.New("disk full")
+	}
+	return s.chanStore.Save(t)
+}
+
+func TestCheckpointerSaveError(t *testing.T) {
+	t.Parallel()
+
+	store := failStore{chanStore{saved: make(chan string, 10)}, new(atomic.Bool)}
+	c := cidrtree.NewCheckpointer[int](new(cidrtree.Atomic[int]), store)
+	c.Every = 2
+
+	errs := make(chan error, 10)
+	c.OnError = func(err error) { errs <- err }
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = c.Run(ctx) }()
+
+	c.Insert(mustPfx("10.0.0.0/8"), 1)
+	c.Insert(mustPfx("::/0"), 2)
+
+	select {
+	case <-errs:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no failed checkpoint after 2 mutations")
+	}
+
+	// the failed checkpoint didn't reset the count, the next mutation makes a checkpoint
+	c.Insert(mustPfx("192.168.0.0/16"), 3)
+
+	select {
+	case got := <-store.saved:
+		if want := "▼\n├─ 10.0.0.0/8 (1)\n└─ 192.168.0.0/16 (3)\n▼\n└─ ::/0 (2)\n"; got != want {
+			t.Errorf("Checkpoint after the failed one, got:\n%v\nwant:\n%v", got, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no checkpoint after the failed one")
+	}
+}
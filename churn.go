@@ -0,0 +1,103 @@
+package cidrtree
+
+import (
+	"net/netip"
+	"sync"
+	"time"
+)
+
+// Flap is a prefix with its decayed update and withdrawal counts, see [Churn].
+type Flap struct {
+	Prefix      netip.Prefix
+	Updates     float64
+	Withdrawals float64
+}
+
+// Score is the churn of the prefix, the sum of updates and withdrawals.
+func (f Flap) Score() float64 {
+	return f.Updates + f.Withdrawals
+}
+
+// Churn reports the approximately most unstable prefixes, e.g. flapping routes.
+//
+// The counts decay exponentially with the given half-life, a route that stopped flapping fades out.
+// Churn uses the space-saving algorithm with a bounded number of counters as [TopK],
+// the memory is independent of the number of distinct prefixes.
+//
+// Churn is safe for concurrent use.
+type Churn struct {
+	mu       sync.Mutex
+	k        int
+	counters spaceSaving // counts: updates, withdrawals
+}
+
+// NewChurn returns a tracker for the k most unstable prefixes.
+// A halfLife <= 0 disables the decay.
+func NewChurn(k int, halfLife time.Duration) *Churn {
+	k = max(k, 1)
+
+	// twice as many counters as reported, better accuracy for the k-th item
+	return &Churn{k: k, counters: newSpaceSaving(2*k, halfLife)}
+}
+
+// Update counts an announcement or an update of pfx.
+func (c *Churn) Update(pfx netip.Prefix) {
+	c.count(pfx.Masked(), [2]float64{1, 0})
+}
+
+// Withdraw counts a withdrawal of pfx.
+func (c *Churn) Withdraw(pfx netip.Prefix) {
+	c.count(pfx.Masked(), [2]float64{0, 1})
+}
+
+func (c *Churn) count(pfx netip.Prefix, counts [2]float64) {
+	now := time.Now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.counters.add(pfx, counts, now)
+}
+
+// Top returns the k most unstable prefixes, sorted by descending score.
+func (c *Churn) Top() []Flap {
+	now := time.Now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	top := c.counters.top(c.k, now)
+	flaps := make([]Flap, 0, len(top))
+	for _, e := range top {
+		flaps = append(flaps, Flap{Prefix: e.pfx, Updates: e.counts[0], Withdrawals: e.counts[1]})
+	}
+	return flaps
+}
+
+// Reset clears all counters.
+func (c *Churn) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.counters.reset()
+}
+
+// ChurnTable is a table with churn statistics, the mutations are counted in Churn.
+// ChurnTable has no overhead for the other methods of the embedded table.
+type ChurnTable[V any] struct {
+	*Table[V]
+	Churn *Churn
+}
+
+// Insert adds pfx to the table and counts an update, see [Table.Insert].
+func (t ChurnTable[V]) Insert(pfx netip.Prefix, value V) {
+	t.Table.Insert(pfx, value)
+	t.Churn.Update(pfx)
+}
+
+// Delete removes pfx from the table and counts a withdrawal if pfx was present, see [Table.Delete].
+func (t ChurnTable[V]) Delete(pfx netip.Prefix) bool {
+	found := t.Table.Delete(pfx)
+	if found {
+		t.Churn.Withdraw(pfx)
+	}
+	return found
+}
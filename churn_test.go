@@ -0,0 +1,76 @@
+package cidrtree_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gaissmai/cidrtree"
+)
+
+func TestChurn(t *testing.T) {
+	t.Parallel()
+
+	// enough counters for exact counts
+	churn := cidrtree.NewChurn(len(routes), 0)
+	rtbl := cidrtree.ChurnTable[any]{Table: new(cidrtree.Table[any]), Churn: churn}
+
+	// stable routes
+	for _, route := range routes {
+		rtbl.Insert(route.cidr, route.nextHop)
+	}
+
+	// flapping routes
+	for i := 0; i < 10; i++ {
+		rtbl.Delete(mustPfx("10.0.1.0/24"))
+		rtbl.Insert(mustPfx("10.0.1.0/24"), nil)
+	}
+	for i := 0; i < 5; i++ {
+		rtbl.Delete(mustPfx("fc00::/7"))
+		rtbl.Insert(mustPfx("fc00::/7"), nil)
+	}
+
+	// not present, not counted
+	rtbl.Delete(mustPfx("192.0.2.0/24"))
+
+	got := churn.Top()
+	if len(got) != len(routes) {
+		t.Fatalf("Top(), got %d flaps, want %d", len(got), len(routes))
+	}
+	if got[0].Prefix != mustPfx("10.0.1.0/24") || got[0].Withdrawals != 10 || got[0].Updates != 11 {
+		t.Errorf("Top()[0], got %+v", got[0])
+	}
+	if got[1].Prefix != mustPfx("fc00::/7") || got[1].Score() != 11 {
+		t.Errorf("Top()[1], got %+v", got[1])
+	}
+
+	if rtbl.Size() != len(routes) {
+		t.Errorf("Size(), got %d, want %d", rtbl.Size(), len(routes))
+	}
+
+	churn.Reset()
+	if got := churn.Top(); len(got) != 0 {
+		t.Errorf("Top() after Reset, got %v, want []", got)
+	}
+}
+
+func TestChurnDecay(t *testing.T) {
+	t.Parallel()
+
+	churn := cidrtree.NewChurn(1, 10*time.Millisecond)
+
+	for i := 0; i < 100; i++ {
+		churn.Withdraw(mustPfx("10.0.0.0/8"))
+	}
+
+	// 10 half-lives, the old score is decayed below 1
+	time.Sleep(100 * time.Millisecond)
+
+	for i := 0; i < 5; i++ {
+		churn.Update(mustPfx("::/0"))
+	}
+
+	got := churn.Top()
+	if len(got) != 1 || got[0].Prefix != mustPfx("::/0") {
+		t.Errorf("Top() after decay, got %v, want %v", got, "::/0")
+	}
+}
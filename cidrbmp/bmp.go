@@ -0,0 +1,462 @@
+// Package cidrbmp maintains per-peer routing tables from a BGP Monitoring Protocol (BMP) stream, RFC 7854.
+//
+// The route monitoring messages of the monitored peers are applied to one table per peer,
+// the announced prefixes are inserted and the withdrawn prefixes deleted. A peer up message
+// starts with an empty table, a peer down message removes the tables of the peer.
+// The tables are [cidrtree.Atomic], the readers of a looking-glass or monitoring service
+// get lock-free snapshots while the stream is consumed.
+//
+// IPv4 and IPv6 unicast routes are supported, the IPv6 routes in MP_REACH_NLRI and MP_UNREACH_NLRI.
+// Other address families, ADD-PATH and the statistics, initiation and termination messages are skipped.
+package cidrbmp
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"slices"
+	"sync"
+
+	"github.com/gaissmai/cidrtree"
+)
+
+// BMP message types
+const (
+	typeRouteMonitoring = 0
+	typeStatistics      = 1
+	typePeerDown        = 2
+	typePeerUp          = 3
+	typeInitiation      = 4
+	typeTermination     = 5
+	typeRouteMirroring  = 6
+)
+
+// per-peer header flags
+const (
+	flagIPv6       = 0x80
+	flagPostPolicy = 0x40
+	flagAS2        = 0x20
+)
+
+// BGP path attributes
+const (
+	attrOrigin      = 1
+	attrASPath      = 2
+	attrNextHop     = 3
+	attrMPReach     = 14
+	attrMPUnreach   = 15
+	attrFlagExtLen  = 0x10
+	afiIPv4         = 1
+	afiIPv6         = 2
+	safiUnicast     = 1
+	commonHeaderLen = 6
+	peerHeaderLen   = 42
+	bgpHeaderLen    = 19
+	maxMessageLen   = 1 << 24
+)
+
+// PeerKey identifies a monitored peer, the pre-policy and post-policy routes are separate tables.
+type PeerKey struct {
+	Distinguisher uint64
+	Address       netip.Addr
+	PostPolicy    bool
+}
+
+// Peer is a monitored peer.
+type Peer struct {
+	PeerKey
+	AS    uint32
+	BGPID netip.Addr
+}
+
+// Route is the value of the per-peer tables.
+type Route struct {
+	NextHop netip.Addr
+	ASPath  []uint32 // AS_SEQUENCE and AS_SET members in wire order
+	Origin  uint8    // 0 IGP, 1 EGP, 2 INCOMPLETE
+}
+
+// Collector consumes BMP messages and maintains the per-peer tables, it is safe for concurrent use.
+type Collector struct {
+	mu    sync.RWMutex
+	peers map[PeerKey]*peerState
+}
+
+type peerState struct {
+	peer  Peer
+	table cidrtree.Atomic[Route]
+}
+
+// NewCollector returns an empty collector.
+func NewCollector() *Collector {
+	return &Collector{peers: make(map[PeerKey]*peerState)}
+}
+
+// Serve reads and handles the BMP messages from r until EOF, e.g. from the TCP connection of a router.
+func (c *Collector) Serve(r io.Reader) error {
+	for {
+		msg, err := ReadMessage(r)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := c.Handle(msg); err != nil {
+			return err
+		}
+	}
+}
+
+// ReadMessage reads the next BMP message from r, including the common header.
+// At the end of the stream io.EOF is returned.
+func ReadMessage(r io.Reader) ([]byte, error) {
+	var hdr [commonHeaderLen]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, fmt.Errorf("cidrbmp: truncated header: %w", err)
+		}
+		return nil, err
+	}
+
+	if hdr[0] != 3 {
+		return nil, fmt.Errorf("cidrbmp: unsupported BMP version %d", hdr[0])
+	}
+
+	length := binary.BigEndian.Uint32(hdr[1:5])
+	if length < commonHeaderLen || length > maxMessageLen {
+		return nil, fmt.Errorf("cidrbmp: invalid message length %d", length)
+	}
+
+	msg := make([]byte, length)
+	copy(msg, hdr[:])
+	if _, err := io.ReadFull(r, msg[commonHeaderLen:]); err != nil {
+		return nil, fmt.Errorf("cidrbmp: truncated message: %w", err)
+	}
+	return msg, nil
+}
+
+// Handle applies a single BMP message, including the common header.
+func (c *Collector) Handle(msg []byte) error {
+	if len(msg) < commonHeaderLen || msg[0] != 3 || int(binary.BigEndian.Uint32(msg[1:5])) != len(msg) {
+		return fmt.Errorf("cidrbmp: malformed common header")
+	}
+
+	typ := msg[5]
+	body := msg[commonHeaderLen:]
+
+	switch typ {
+	case typeRouteMonitoring, typePeerDown, typePeerUp:
+	case typeStatistics, typeInitiation, typeTermination, typeRouteMirroring:
+		return nil
+	default:
+		return fmt.Errorf("cidrbmp: unknown message type %d", typ)
+	}
+
+	if len(body) < peerHeaderLen {
+		return fmt.Errorf("cidrbmp: truncated per-peer header")
+	}
+	peer, flags := parsePeerHeader(body)
+	body = body[peerHeaderLen:]
+
+	// the session state applies to the pre-policy and post-policy tables
+	if typ == typePeerUp || typ == typePeerDown {
+		c.mu.Lock()
+		for _, post := range []bool{false, true} {
+			key := peer.PeerKey
+			key.PostPolicy = post
+			delete(c.peers, key)
+		}
+		if typ == typePeerUp {
+			c.peers[peer.PeerKey] = &peerState{peer: peer}
+		}
+		c.mu.Unlock()
+		return nil
+	}
+
+	announced, withdrawn, err := parseUpdate(body, flags&flagAS2 != 0)
+	if err != nil {
+		return fmt.Errorf("cidrbmp: peer %v: %w", peer.Address, err)
+	}
+
+	c.state(peer).table.Update(func(t cidrtree.Table[Route]) *cidrtree.Table[Route] {
+		next := &t
+		for _, pfx := range withdrawn {
+			next, _ = next.DeleteImmutable(pfx)
+		}
+		return next.InsertManyImmutable(announced)
+	})
+
+	return nil
+}
+
+// state returns the state of the peer, created by the first route monitoring message.
+func (c *Collector) state(peer Peer) *peerState {
+	c.mu.RLock()
+	ps := c.peers[peer.PeerKey]
+	c.mu.RUnlock()
+	if ps != nil {
+		return ps
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if ps = c.peers[peer.PeerKey]; ps == nil {
+		ps = &peerState{peer: peer}
+		c.peers[peer.PeerKey] = ps
+	}
+	return ps
+}
+
+// Peers returns the monitored peers, sorted by address.
+func (c *Collector) Peers() []Peer {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	peers := make([]Peer, 0, len(c.peers))
+	for _, ps := range c.peers {
+		peers = append(peers, ps.peer)
+	}
+
+	slices.SortFunc(peers, func(a, b Peer) int {
+		if c := a.Address.Compare(b.Address); c != 0 {
+			return c
+		}
+		if a.Distinguisher != b.Distinguisher {
+			if a.Distinguisher < b.Distinguisher {
+				return -1
+			}
+			return 1
+		}
+		if a.PostPolicy == b.PostPolicy {
+			return 0
+		}
+		if b.PostPolicy {
+			return -1
+		}
+		return 1
+	})
+	return peers
+}
+
+// Table returns the current snapshot of the table of the peer, false if the peer isn't monitored.
+func (c *Collector) Table(key PeerKey) (*cidrtree.Table[Route], bool) {
+	c.mu.RLock()
+	ps := c.peers[key]
+	c.mu.RUnlock()
+
+	if ps == nil {
+		return nil, false
+	}
+	return ps.table.Load(), true
+}
+
+// parsePeerHeader, the body has at least peerHeaderLen bytes.
+func parsePeerHeader(b []byte) (Peer, byte) {
+	flags := b[1]
+
+	var addr netip.Addr
+	if flags&flagIPv6 != 0 {
+		addr = netip.AddrFrom16([16]byte(b[10:26]))
+	} else {
+		addr = netip.AddrFrom4([4]byte(b[22:26]))
+	}
+
+	return Peer{
+		PeerKey: PeerKey{
+			Distinguisher: binary.BigEndian.Uint64(b[2:10]),
+			Address:       addr,
+			PostPolicy:    flags&flagPostPolicy != 0,
+		},
+		AS:    binary.BigEndian.Uint32(b[26:30]),
+		BGPID: netip.AddrFrom4([4]byte(b[30:34])),
+	}, flags
+}
+
+// parseUpdate parses the BGP UPDATE message of a route monitoring message.
+func parseUpdate(b []byte, as2 bool) (announced []cidrtree.Entry[Route], withdrawn []netip.Prefix, err error) {
+	if len(b) < bgpHeaderLen+4 {
+		return nil, nil, fmt.Errorf("truncated BGP message")
+	}
+	if int(binary.BigEndian.Uint16(b[16:18])) != len(b) || b[18] != 2 {
+		return nil, nil, fmt.Errorf("malformed BGP UPDATE")
+	}
+	b = b[bgpHeaderLen:]
+
+	wLen := int(binary.BigEndian.Uint16(b))
+	if len(b) < 2+wLen+2 {
+		return nil, nil, fmt.Errorf("truncated withdrawn routes")
+	}
+	if withdrawn, err = appendNLRI(withdrawn, b[2:2+wLen], afiIPv4); err != nil {
+		return nil, nil, err
+	}
+	b = b[2+wLen:]
+
+	aLen := int(binary.BigEndian.Uint16(b))
+	if len(b) < 2+aLen {
+		return nil, nil, fmt.Errorf("truncated path attributes")
+	}
+	attrs, nlri := b[2:2+aLen], b[2+aLen:]
+
+	var route Route
+	var nextHop6 netip.Addr
+	var announced4, announced6 []netip.Prefix
+
+	for len(attrs) > 0 {
+		if len(attrs) < 3 {
+			return nil, nil, fmt.Errorf("truncated path attribute")
+		}
+		flags, typ := attrs[0], attrs[1]
+
+		hdr, length := 3, int(attrs[2])
+		if flags&attrFlagExtLen != 0 {
+			if len(attrs) < 4 {
+				return nil, nil, fmt.Errorf("truncated path attribute")
+			}
+			hdr, length = 4, int(binary.BigEndian.Uint16(attrs[2:4]))
+		}
+		if len(attrs) < hdr+length {
+			return nil, nil, fmt.Errorf("truncated path attribute %d", typ)
+		}
+		value := attrs[hdr : hdr+length]
+		attrs = attrs[hdr+length:]
+
+		switch typ {
+		case attrOrigin:
+			if len(value) == 1 {
+				route.Origin = value[0]
+			}
+
+		case attrASPath:
+			if route.ASPath, err = parseASPath(value, as2); err != nil {
+				return nil, nil, err
+			}
+
+		case attrNextHop:
+			if len(value) == 4 {
+				route.NextHop = netip.AddrFrom4([4]byte(value))
+			}
+
+		case attrMPReach:
+			if announced6, nextHop6, err = parseMPReach(announced6, value); err != nil {
+				return nil, nil, err
+			}
+
+		case attrMPUnreach:
+			if withdrawn, err = parseMPUnreach(withdrawn, value); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	if announced4, err = appendNLRI(announced4, nlri, afiIPv4); err != nil {
+		return nil, nil, err
+	}
+
+	for _, pfx := range announced4 {
+		announced = append(announced, cidrtree.Entry[Route]{Prefix: pfx, Value: route})
+	}
+
+	route6 := route
+	route6.NextHop = nextHop6
+	for _, pfx := range announced6 {
+		announced = append(announced, cidrtree.Entry[Route]{Prefix: pfx, Value: route6})
+	}
+	return announced, withdrawn, nil
+}
+
+// parseASPath returns the ASNs of all segments, 2-byte ASNs for the legacy format.
+func parseASPath(b []byte, as2 bool) ([]uint32, error) {
+	size := 4
+	if as2 {
+		size = 2
+	}
+
+	var path []uint32
+	for len(b) > 0 {
+		if len(b) < 2 || len(b) < 2+int(b[1])*size {
+			return nil, fmt.Errorf("truncated AS_PATH")
+		}
+		n := int(b[1])
+		b = b[2:]
+
+		for i := 0; i < n; i++ {
+			if as2 {
+				path = append(path, uint32(binary.BigEndian.Uint16(b)))
+			} else {
+				path = append(path, binary.BigEndian.Uint32(b))
+			}
+			b = b[size:]
+		}
+	}
+	return path, nil
+}
+
+// parseMPReach appends the IPv6 unicast NLRI, returns the global next hop.
+func parseMPReach(pfxs []netip.Prefix, b []byte) ([]netip.Prefix, netip.Addr, error) {
+	if len(b) < 5 {
+		return nil, netip.Addr{}, fmt.Errorf("truncated MP_REACH_NLRI")
+	}
+	afi, safi, nhLen := binary.BigEndian.Uint16(b), b[2], int(b[3])
+	if len(b) < 4+nhLen+1 {
+		return nil, netip.Addr{}, fmt.Errorf("truncated MP_REACH_NLRI")
+	}
+	if afi != afiIPv6 || safi != safiUnicast {
+		return pfxs, netip.Addr{}, nil
+	}
+
+	var nh netip.Addr
+	if nhLen == 16 || nhLen == 32 {
+		// global address, followed by the link-local address
+		nh = netip.AddrFrom16([16]byte(b[4:20]))
+	}
+
+	// skip the reserved byte
+	pfxs, err := appendNLRI(pfxs, b[4+nhLen+1:], afiIPv6)
+	return pfxs, nh, err
+}
+
+// parseMPUnreach appends the withdrawn IPv6 unicast NLRI.
+func parseMPUnreach(pfxs []netip.Prefix, b []byte) ([]netip.Prefix, error) {
+	if len(b) < 3 {
+		return nil, fmt.Errorf("truncated MP_UNREACH_NLRI")
+	}
+	if binary.BigEndian.Uint16(b) != afiIPv6 || b[2] != safiUnicast {
+		return pfxs, nil
+	}
+	return appendNLRI(pfxs, b[3:], afiIPv6)
+}
+
+// appendNLRI appends the prefixes of the NLRI encoding, length in bits followed by the significant bytes.
+func appendNLRI(pfxs []netip.Prefix, b []byte, afi uint16) ([]netip.Prefix, error) {
+	maxBits := 32
+	if afi == afiIPv6 {
+		maxBits = 128
+	}
+
+	for len(b) > 0 {
+		bits := int(b[0])
+		n := (bits + 7) / 8
+		if bits > maxBits || len(b) < 1+n {
+			return nil, fmt.Errorf("malformed NLRI")
+		}
+
+		var addr netip.Addr
+		if afi == afiIPv6 {
+			var a [16]byte
+			copy(a[:], b[1:1+n])
+			addr = netip.AddrFrom16(a)
+		} else {
+			var a [4]byte
+			copy(a[:], b[1:1+n])
+			addr = netip.AddrFrom4(a)
+		}
+
+		pfxs = append(pfxs, netip.PrefixFrom(addr, bits).Masked())
+		b = b[1+n:]
+	}
+	return pfxs, nil
+}
@@ -0,0 +1,170 @@
+package cidrbmp_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net/netip"
+	"reflect"
+	"testing"
+
+	"github.com/gaissmai/cidrtree/cidrbmp"
+)
+
+var (
+	peerAddr = netip.MustParseAddr("192.0.2.1")
+	peerKey  = cidrbmp.PeerKey{Address: peerAddr}
+)
+
+// message returns a BMP message with common header and per-peer header.
+func message(typ byte, flags byte, body []byte) []byte {
+	var b []byte
+	b = append(b, 3, 0, 0, 0, 0, typ)
+
+	peer := make([]byte, 42)
+	peer[1] = flags
+	copy(peer[22:26], peerAddr.AsSlice())
+	binary.BigEndian.PutUint32(peer[26:30], 64500)
+	copy(peer[30:34], peerAddr.AsSlice())
+
+	b = append(b, peer...)
+	b = append(b, body...)
+	binary.BigEndian.PutUint32(b[1:5], uint32(len(b)))
+	return b
+}
+
+func nlri(pfxs ...string) []byte {
+	var b []byte
+	for _, s := range pfxs {
+		pfx := netip.MustParsePrefix(s)
+		b = append(b, byte(pfx.Bits()))
+		b = append(b, pfx.Addr().AsSlice()[:(pfx.Bits()+7)/8]...)
+	}
+	return b
+}
+
+func attr(typ byte, value []byte) []byte {
+	return append([]byte{0x40, typ, byte(len(value))}, value...)
+}
+
+// update returns a BGP UPDATE message.
+func update(withdrawn, attrs, announced []byte) []byte {
+	b := bytes.Repeat([]byte{0xff}, 16)
+	b = append(b, 0, 0, 2)
+	b = binary.BigEndian.AppendUint16(b, uint16(len(withdrawn)))
+	b = append(b, withdrawn...)
+	b = binary.BigEndian.AppendUint16(b, uint16(len(attrs)))
+	b = append(b, attrs...)
+	b = append(b, announced...)
+	binary.BigEndian.PutUint16(b[16:18], uint16(len(b)))
+	return b
+}
+
+func TestCollector(t *testing.T) {
+	t.Parallel()
+
+	asPath := []byte{2, 2, 0, 0, 0xfb, 0xf4, 0, 0, 0xfd, 0xe8} // AS_SEQUENCE 64500 65000
+	attrs := append(attr(1, []byte{0}), attr(2, asPath)...)
+	attrs = append(attrs, attr(3, []byte{192, 0, 2, 1})...)
+
+	mpReach := []byte{0, 2, 1, 16}
+	mpReach = append(mpReach, netip.MustParseAddr("2001:db8::1").AsSlice()...)
+	mpReach = append(mpReach, 0)
+	mpReach = append(mpReach, nlri("2001:db8:1::/48")...)
+	attrs6 := append(attr(2, asPath), append([]byte{0x90, 14, 0, byte(len(mpReach))}, mpReach...)...)
+
+	var stream bytes.Buffer
+	stream.Write(message(3, 0, make([]byte, 20)))
+	stream.Write(message(0, 0, update(nil, attrs, nlri("10.0.0.0/8", "10.1.0.0/16", "198.51.100.0/24"))))
+	stream.Write(message(0, 0, update(nlri("10.1.0.0/16"), nil, nil)))
+	stream.Write(message(0, 0, update(nil, attrs6, nil)))
+	stream.Write(message(1, 0, make([]byte, 4))) // statistics, skipped
+
+	c := cidrbmp.NewCollector()
+	if err := c.Serve(&stream); err != nil {
+		t.Fatal(err)
+	}
+
+	peers := c.Peers()
+	if len(peers) != 1 || peers[0].Address != peerAddr || peers[0].AS != 64500 {
+		t.Fatalf("Peers(), got %+v", peers)
+	}
+
+	rtbl, ok := c.Table(peerKey)
+	if !ok {
+		t.Fatalf("Table(%v), got false, want true", peerKey)
+	}
+
+	var got []string
+	rtbl.Walk(func(pfx netip.Prefix, _ cidrbmp.Route) bool {
+		got = append(got, pfx.String())
+		return true
+	})
+	want := []string{"10.0.0.0/8", "198.51.100.0/24", "2001:db8:1::/48"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Walk, got %v, want %v", got, want)
+	}
+
+	_, route, _ := rtbl.Lookup(netip.MustParseAddr("10.1.2.3"))
+	if route.NextHop != peerAddr || !reflect.DeepEqual(route.ASPath, []uint32{64500, 65000}) {
+		t.Errorf("Lookup(10.1.2.3), got %+v", route)
+	}
+
+	_, route, _ = rtbl.Lookup(netip.MustParseAddr("2001:db8:1::1"))
+	if route.NextHop != netip.MustParseAddr("2001:db8::1") {
+		t.Errorf("Lookup(2001:db8:1::1), got %+v", route)
+	}
+
+	// peer down
+	if err := c.Handle(message(2, 0, []byte{2})); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.Table(peerKey); ok {
+		t.Errorf("Table(%v) after peer down, got true, want false", peerKey)
+	}
+}
+
+func TestCollectorPostPolicy(t *testing.T) {
+	t.Parallel()
+
+	c := cidrbmp.NewCollector()
+	if err := c.Handle(message(0, 0x40|0x20, update(nil, attr(2, []byte{2, 1, 0xfb, 0xf4}), nlri("10.0.0.0/8")))); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := c.Table(peerKey); ok {
+		t.Errorf("pre-policy table, got true, want false")
+	}
+
+	post := peerKey
+	post.PostPolicy = true
+	rtbl, ok := c.Table(post)
+	if !ok {
+		t.Fatalf("post-policy table, got false, want true")
+	}
+
+	// 2-byte AS path
+	if _, route, _ := rtbl.Lookup(netip.MustParseAddr("10.0.0.1")); !reflect.DeepEqual(route.ASPath, []uint32{64500}) {
+		t.Errorf("AS path, got %v, want %v", route.ASPath, []uint32{64500})
+	}
+}
+
+func TestHandleError(t *testing.T) {
+	t.Parallel()
+
+	c := cidrbmp.NewCollector()
+
+	truncated := message(0, 0, update(nil, nil, nlri("10.0.0.0/8")))
+	truncated = truncated[:len(truncated)-1]
+	binary.BigEndian.PutUint32(truncated[1:5], uint32(len(truncated)))
+
+	for _, msg := range [][]byte{
+		{3, 0, 0, 0, 6},
+		{2, 0, 0, 0, 6, 0},
+		message(9, 0, nil),
+		truncated,
+	} {
+		if err := c.Handle(msg); err == nil {
+			t.Errorf("Handle(%x), expected error", msg)
+		}
+	}
+}
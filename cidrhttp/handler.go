@@ -0,0 +1,180 @@
+// Package cidrhttp provides HTTP handlers for lookups and administration of a routing table.
+//
+//	GET    /lookup?ip=192.0.2.1        longest-prefix-match for ip
+//	GET    /lookup?prefix=192.0.2.0/24 longest-prefix-match for prefix
+//	GET    /prefixes                   all entries in ascending order
+//	PUT    /prefix?prefix=192.0.2.0/24 insert or update the entry, the JSON request body is the value
+//	DELETE /prefix?prefix=192.0.2.0/24 delete the entry
+//
+// Entries are encoded as JSON objects {"prefix": "192.0.2.0/24", "value": ...}.
+// Lookups are served from lock-free snapshots, updates are serialized, see [cidrtree.Atomic].
+package cidrhttp
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/netip"
+
+	"github.com/gaissmai/cidrtree"
+)
+
+// maxBodySize limits the size of a value in a PUT request.
+const maxBodySize = 1 << 20
+
+// Entry is the JSON encoding of a table entry.
+type Entry[V any] struct {
+	Prefix netip.Prefix `json:"prefix"`
+	Value  V            `json:"value"`
+}
+
+// Handler serves the lookup and admin endpoints for the table.
+type Handler[V any] struct {
+	table *cidrtree.Atomic[V]
+
+	// ReadOnly disables the PUT and DELETE endpoints.
+	ReadOnly bool
+}
+
+// New returns a handler for the table.
+func New[V any](table *cidrtree.Atomic[V]) *Handler[V] {
+	return &Handler[V]{table: table}
+}
+
+// ServeHTTP implements [http.Handler].
+func (h *Handler[V]) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/lookup":
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			methodNotAllowed(w, http.MethodGet)
+			return
+		}
+		h.lookup(w, r)
+	case "/prefixes":
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			methodNotAllowed(w, http.MethodGet)
+			return
+		}
+		h.prefixes(w, r)
+	case "/prefix":
+		if h.ReadOnly {
+			http.Error(w, "read-only", http.StatusForbidden)
+			return
+		}
+		switch r.Method {
+		case http.MethodPut:
+			h.put(w, r)
+		case http.MethodDelete:
+			h.delete(w, r)
+		default:
+			methodNotAllowed(w, http.MethodPut+", "+http.MethodDelete)
+		}
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func methodNotAllowed(w http.ResponseWriter, allow string) {
+	w.Header().Set("Allow", allow)
+	http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+}
+
+func (h *Handler[V]) lookup(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	var lpm netip.Prefix
+	var value V
+	var ok bool
+
+	switch {
+	case query.Has("ip"):
+		ip, err := netip.ParseAddr(query.Get("ip"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		lpm, value, ok = h.table.Lookup(ip)
+	case query.Has("prefix"):
+		pfx, err := netip.ParsePrefix(query.Get("prefix"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		lpm, value, ok = h.table.LookupPrefix(pfx)
+	default:
+		http.Error(w, "missing ip or prefix parameter", http.StatusBadRequest)
+		return
+	}
+
+	if !ok {
+		http.Error(w, "no match", http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, Entry[V]{Prefix: lpm, Value: value})
+}
+
+func (h *Handler[V]) prefixes(w http.ResponseWriter, _ *http.Request) {
+	entries := []Entry[V]{}
+	h.table.Load().Walk(func(pfx netip.Prefix, value V) bool {
+		entries = append(entries, Entry[V]{Prefix: pfx, Value: value})
+		return true
+	})
+
+	writeJSON(w, entries)
+}
+
+func (h *Handler[V]) put(w http.ResponseWriter, r *http.Request) {
+	pfx, ok := prefixParam(w, r)
+	if !ok {
+		return
+	}
+
+	var value V
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxBodySize))
+	if err == nil {
+		err = json.Unmarshal(body, &value)
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("decoding value: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	h.table.Insert(pfx, value)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler[V]) delete(w http.ResponseWriter, r *http.Request) {
+	pfx, ok := prefixParam(w, r)
+	if !ok {
+		return
+	}
+
+	if !h.table.Delete(pfx) {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// prefixParam parses the prefix parameter, writes the error response if invalid.
+func prefixParam(w http.ResponseWriter, r *http.Request) (netip.Prefix, bool) {
+	pfx, err := netip.ParsePrefix(r.URL.Query().Get("prefix"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return pfx, false
+	}
+	return pfx.Masked(), true
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	buf, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(append(buf, '\n'))
+}
@@ -0,0 +1,75 @@
+package cidrhttp_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gaissmai/cidrtree"
+	"github.com/gaissmai/cidrtree/cidrhttp"
+)
+
+func do(t *testing.T, h http.Handler, method, target, body string) (int, string) {
+	t.Helper()
+
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	return rec.Code, rec.Body.String()
+}
+
+func TestHandler(t *testing.T) {
+	t.Parallel()
+
+	h := cidrhttp.New(new(cidrtree.Atomic[string]))
+
+	tests := []struct {
+		method   string
+		target   string
+		body     string
+		wantCode int
+		wantBody string
+	}{
+		{http.MethodGet, "/prefixes", "", http.StatusOK, "[]\n"},
+		{http.MethodGet, "/lookup?ip=10.0.0.1", "", http.StatusNotFound, "no match\n"},
+		{http.MethodPut, "/prefix?prefix=10.0.0.0/8", `"eth0"`, http.StatusNoContent, ""},
+		{http.MethodPut, "/prefix?prefix=10.0.1.1/24", `"eth1"`, http.StatusNoContent, ""},
+		{http.MethodPut, "/prefix?prefix=::/0", `"eth2"`, http.StatusNoContent, ""},
+		{http.MethodPut, "/prefix?prefix=::/0", `no json`, http.StatusBadRequest, ""},
+		{http.MethodPut, "/prefix?prefix=foo", `"eth3"`, http.StatusBadRequest, ""},
+		{http.MethodGet, "/lookup?ip=10.0.1.1", "", http.StatusOK, `{"prefix":"10.0.1.0/24","value":"eth1"}` + "\n"},
+		{http.MethodGet, "/lookup?ip=10.0.2.1", "", http.StatusOK, `{"prefix":"10.0.0.0/8","value":"eth0"}` + "\n"},
+		{http.MethodGet, "/lookup?prefix=10.0.1.0/23", "", http.StatusOK, `{"prefix":"10.0.0.0/8","value":"eth0"}` + "\n"},
+		{http.MethodGet, "/lookup?ip=foo", "", http.StatusBadRequest, ""},
+		{http.MethodGet, "/lookup", "", http.StatusBadRequest, ""},
+		{http.MethodPost, "/lookup?ip=10.0.0.1", "", http.StatusMethodNotAllowed, ""},
+		{http.MethodGet, "/prefix?prefix=10.0.0.0/8", "", http.StatusMethodNotAllowed, ""},
+		{http.MethodDelete, "/prefix?prefix=10.0.0.0/8", "", http.StatusNoContent, ""},
+		{http.MethodDelete, "/prefix?prefix=10.0.0.0/8", "", http.StatusNotFound, ""},
+		{http.MethodGet, "/prefixes", "", http.StatusOK, `[{"prefix":"10.0.1.0/24","value":"eth1"},{"prefix":"::/0","value":"eth2"}]` + "\n"},
+		{http.MethodGet, "/foo", "", http.StatusNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		code, body := do(t, h, tt.method, tt.target, tt.body)
+		if code != tt.wantCode {
+			t.Errorf("%s %s, got status %d, want %d", tt.method, tt.target, code, tt.wantCode)
+		}
+		if tt.wantBody != "" && body != tt.wantBody {
+			t.Errorf("%s %s, got body %q, want %q", tt.method, tt.target, body, tt.wantBody)
+		}
+	}
+}
+
+func TestHandlerReadOnly(t *testing.T) {
+	t.Parallel()
+
+	h := cidrhttp.New(new(cidrtree.Atomic[int]))
+	h.ReadOnly = true
+
+	if code, _ := do(t, h, http.MethodPut, "/prefix?prefix=10.0.0.0/8", "1"); code != http.StatusForbidden {
+		t.Errorf("PUT on read-only handler, got status %d, want %d", code, http.StatusForbidden)
+	}
+}
@@ -0,0 +1,227 @@
+// Command cidrbench evaluates the cidrtree package against your own prefix data.
+//
+// It loads a prefix file, one prefix per line, optionally gzipped, generates probe traffic
+// and reports the lookup latency percentiles, the insert and delete throughput and
+// the memory consumption of the table.
+//
+// The lookups are timed in batches, a single lookup is in the range of the time.Now overhead.
+// The latency percentiles are percentiles of the batch means, not of single lookups,
+// the outliers within a batch are averaged out.
+//
+// Usage:
+//
+//	cidrbench [-probes n] [-random fraction] [-seed n] prefix-file
+//
+// Most probes are addresses within the loaded prefixes, like real traffic to routed destinations,
+// the given fraction of probes are random addresses.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/netip"
+	"os"
+	"runtime"
+	"slices"
+	"time"
+
+	"github.com/gaissmai/cidrtree"
+)
+
+// a batch of lookups per time measurement, the time.Now overhead is in the range of a single lookup
+const batchSize = 64
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("cidrbench: ")
+
+	err := run(os.Args[1:], os.Stdout, os.Stderr)
+	switch {
+	case errors.Is(err, flag.ErrHelp):
+		os.Exit(2)
+	case err != nil:
+		log.Fatal(err)
+	}
+}
+
+// run the benchmarks with the command line args, the report is written to w.
+// Usage errors are reported to stderr and returned as flag.ErrHelp.
+func run(args []string, w, stderr io.Writer) error {
+	fs := flag.NewFlagSet("cidrbench", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	probes := fs.Int("probes", 1_000_000, "number of lookup probes")
+	random := fs.Float64("random", 0.1, "fraction of random probe addresses")
+	seed := fs.Int64("seed", 1, "seed for the probe generator")
+
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: cidrbench [flags] prefix-file\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return flag.ErrHelp
+	}
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+
+	pfxs, err := cidrtree.LoadPrefixFile(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	if len(pfxs) == 0 {
+		return fmt.Errorf("no prefixes in %s", fs.Arg(0))
+	}
+
+	prng := rand.New(rand.NewSource(*seed))
+
+	fmt.Fprintf(w, "prefixes: %d\n\n", len(pfxs))
+
+	rtbl := benchInsert(w, pfxs)
+	benchMemory(w, pfxs)
+	benchLookup(w, rtbl, makeProbes(prng, pfxs, *probes, *random))
+	benchDelete(w, rtbl, pfxs)
+	return nil
+}
+
+func benchInsert(w io.Writer, pfxs []netip.Prefix) *cidrtree.Table[int] {
+	rtbl := new(cidrtree.Table[int])
+
+	start := time.Now()
+	for i, pfx := range pfxs {
+		rtbl.Insert(pfx, i)
+	}
+	elapsed := time.Since(start)
+
+	fmt.Fprintf(w, "insert:   %12s total, %10.0f ops/s\n", elapsed, float64(len(pfxs))/elapsed.Seconds())
+	return rtbl
+}
+
+func benchDelete(w io.Writer, rtbl *cidrtree.Table[int], pfxs []netip.Prefix) {
+	start := time.Now()
+	for _, pfx := range pfxs {
+		rtbl.Delete(pfx)
+	}
+	elapsed := time.Since(start)
+
+	fmt.Fprintf(w, "delete:   %12s total, %10.0f ops/s\n", elapsed, float64(len(pfxs))/elapsed.Seconds())
+}
+
+func benchMemory(w io.Writer, pfxs []netip.Prefix) {
+	var before, after runtime.MemStats
+
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+
+	rtbl := new(cidrtree.Table[int])
+	for i, pfx := range pfxs {
+		rtbl.Insert(pfx, i)
+	}
+
+	runtime.GC()
+	runtime.ReadMemStats(&after)
+	runtime.KeepAlive(rtbl)
+
+	heap := int64(after.HeapAlloc) - int64(before.HeapAlloc)
+	fmt.Fprintf(w, "memory:   %12.2f MiB heap, %10.1f bytes/prefix\n\n",
+		float64(heap)/(1<<20), float64(heap)/float64(len(pfxs)))
+}
+
+// benchLookup reports the percentiles of the batch means, see batchSize.
+func benchLookup(w io.Writer, rtbl *cidrtree.Table[int], probes []netip.Addr) {
+	var found int
+	var means []time.Duration
+
+	start := time.Now()
+	for i := 0; i+batchSize <= len(probes); i += batchSize {
+		t0 := time.Now()
+		for _, ip := range probes[i : i+batchSize] {
+			if _, _, ok := rtbl.Lookup(ip); ok {
+				found++
+			}
+		}
+		means = append(means, time.Since(t0)/batchSize)
+	}
+	elapsed := time.Since(start)
+
+	if len(means) == 0 {
+		return
+	}
+	slices.Sort(means)
+
+	lookups := len(means) * batchSize
+	fmt.Fprintf(w, "lookup:   %12s total, %10.0f ops/s, %d/%d matched\n",
+		elapsed, float64(lookups)/elapsed.Seconds(), found, lookups)
+
+	fmt.Fprintf(w, "  latency, means of %d lookups per batch\n", batchSize)
+	for _, p := range []float64{50, 90, 99, 99.9} {
+		fmt.Fprintf(w, "  p%-5v  %12s\n", p, percentile(means, p))
+	}
+	fmt.Fprintf(w, "  max     %12s\n\n", means[len(means)-1])
+}
+
+// percentile of sorted durations.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	i := int(float64(len(sorted)-1) * p / 100)
+	return sorted[i]
+}
+
+// makeProbes returns n probe addresses, addresses within random prefixes of pfxs
+// and with the given fraction fully random addresses of both versions.
+func makeProbes(prng *rand.Rand, pfxs []netip.Prefix, n int, random float64) []netip.Addr {
+	probes := make([]netip.Addr, 0, n)
+
+	for i := 0; i < n; i++ {
+		if prng.Float64() < random {
+			probes = append(probes, randAddr(prng, prng.Intn(2) == 0))
+			continue
+		}
+
+		pfx := pfxs[prng.Intn(len(pfxs))]
+		probes = append(probes, randAddrIn(prng, pfx))
+	}
+
+	return probes
+}
+
+// randAddr returns a random IPv4 or IPv6 address.
+func randAddr(prng *rand.Rand, is4 bool) netip.Addr {
+	if is4 {
+		var b [4]byte
+		prng.Read(b[:])
+		return netip.AddrFrom4(b)
+	}
+
+	var b [16]byte
+	prng.Read(b[:])
+	return netip.AddrFrom16(b)
+}
+
+// randAddrIn returns a random address within pfx, the host bits are random.
+func randAddrIn(prng *rand.Rand, pfx netip.Prefix) netip.Addr {
+	is4 := pfx.Addr().Is4()
+	host := randAddr(prng, is4).AsSlice()
+	net := pfx.Addr().AsSlice()
+
+	bits := pfx.Bits()
+	for i := range net {
+		switch {
+		case bits >= 8:
+			host[i] = net[i]
+			bits -= 8
+		case bits > 0:
+			mask := byte(0xff << (8 - bits))
+			host[i] = net[i]&mask | host[i]&^mask
+			bits = 0
+		}
+	}
+
+	addr, _ := netip.AddrFromSlice(host)
+	return addr
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"math/rand"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRun(t *testing.T) {
+	t.Parallel()
+
+	file := filepath.Join(t.TempDir(), "prefixes.txt")
+	if err := os.WriteFile(file, []byte("10.0.0.0/8\n192.168.0.0/16\n2001:db8::/32\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	w := new(strings.Builder)
+	if err := run([]string{"-probes", "1000", "-random", "0", file}, w, new(strings.Builder)); err != nil {
+		t.Fatal(err)
+	}
+
+	// 15 full batches of 64 lookups, all probes within the prefixes
+	for _, want := range []string{"prefixes: 3\n", "insert:", "memory:", "960/960 matched", "means of 64 lookups per batch", "p99.9", "delete:"} {
+		if !strings.Contains(w.String(), want) {
+			t.Errorf("run, output without %q:\n%s", want, w)
+		}
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	t.Parallel()
+
+	empty := filepath.Join(t.TempDir(), "empty.txt")
+	if err := os.WriteFile(empty, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, args := range [][]string{{}, {"-probes"}, {"a", "b"}} {
+		stderr := new(strings.Builder)
+		if err := run(args, new(strings.Builder), stderr); !errors.Is(err, flag.ErrHelp) || !strings.Contains(stderr.String(), "usage:") {
+			t.Errorf("run(%q), got (%v, %q), want usage", args, err, stderr)
+		}
+	}
+
+	for _, file := range []string{empty, filepath.Join(t.TempDir(), "missing")} {
+		if err := run([]string{file}, new(strings.Builder), new(strings.Builder)); err == nil || errors.Is(err, flag.ErrHelp) {
+			t.Errorf("run(%q), got %v, want error", file, err)
+		}
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	t.Parallel()
+
+	sorted := make([]time.Duration, 100)
+	for i := range sorted {
+		sorted[i] = time.Duration(i + 1)
+	}
+
+	for p, want := range map[float64]time.Duration{0: 1, 50: 50, 99: 99, 100: 100} {
+		if got := percentile(sorted, p); got != want {
+			t.Errorf("percentile(%v), got %v, want %v", p, got, want)
+		}
+	}
+}
+
+func TestMakeProbes(t *testing.T) {
+	t.Parallel()
+
+	prng := rand.New(rand.NewSource(1))
+	pfxs := []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/8"),
+		netip.MustParsePrefix("192.168.1.0/25"),
+		netip.MustParsePrefix("2001:db8::/33"),
+		netip.MustParsePrefix("198.51.100.7/32"),
+	}
+
+	probes := makeProbes(prng, pfxs, 1000, 0)
+	if len(probes) != 1000 {
+		t.Fatalf("makeProbes, got %d probes, want %d", len(probes), 1000)
+	}
+
+	for _, ip := range probes {
+		within := false
+		for _, pfx := range pfxs {
+			within = within || pfx.Contains(ip)
+		}
+		if !within {
+			t.Errorf("makeProbes, probe %v not within the prefixes", ip)
+		}
+	}
+
+	// all fully random, both versions
+	var is4 int
+	for _, ip := range makeProbes(prng, pfxs, 1000, 1) {
+		if ip.Is4() {
+			is4++
+		}
+	}
+	if is4 == 0 || is4 == 1000 {
+		t.Errorf("makeProbes of random addresses, got %d of %d IPv4", is4, 1000)
+	}
+}
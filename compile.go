@@ -0,0 +1,143 @@
+package cidrtree
+
+import (
+	"encoding/binary"
+	"net/netip"
+	"sort"
+)
+
+// Compiled is an immutable, lookup-optimized representation of a [Table].
+//
+// The address space is partitioned into segments with the same longest-prefix-match,
+// the start addresses are stored in flat sorted arrays. A lookup is a binary search
+// in contiguous memory instead of pointer-chasing in the treap.
+type Compiled[V any] struct {
+	starts4 []uint32
+	lpms4   []int32 // index into entries, -1 for uncovered segments
+	starts6 []uint128
+	lpms6   []int32
+	entries []compiledEntry[V]
+}
+
+type compiledEntry[V any] struct {
+	cidr   netip.Prefix
+	value  V
+	parent int32 // index of the covering entry, -1 for top level entries
+}
+
+// uint128 is an IP address as unsigned 128 bit integer, IPv4 addresses are IPv4-mapped.
+// The order is the order of netip.Addr within an IP version.
+type uint128 struct {
+	hi, lo uint64
+}
+
+// Compile converts the table into an immutable, lookup-optimized [Compiled] representation.
+// Later changes of the table are not reflected, compile it again after the build phase.
+func (t Table[V]) Compile() *Compiled[V] {
+	c := new(Compiled[V])
+
+	index := make(map[*node[V]]int32)
+	for _, root := range []*node[V]{t.root4, t.root6} {
+		root.walkNested(func(n *node[V], parents []*node[V]) bool {
+			parent := int32(-1)
+			if len(parents) > 0 {
+				parent = index[parents[len(parents)-1]]
+			}
+
+			index[n] = int32(len(c.entries))
+			c.entries = append(c.entries, compiledEntry[V]{cidr: n.cidr, value: n.value, parent: parent})
+			return true
+		})
+	}
+
+	lpm := func(n *node[V]) int32 {
+		if n == nil {
+			return -1
+		}
+		return index[n]
+	}
+
+	for _, seg := range t.root4.segments(netip.IPv4Unspecified()) {
+		c.starts4 = append(c.starts4, key4(seg.start))
+		c.lpms4 = append(c.lpms4, lpm(seg.node))
+	}
+
+	for _, seg := range t.root6.segments(netip.IPv6Unspecified()) {
+		c.starts6 = append(c.starts6, key6(seg.start))
+		c.lpms6 = append(c.lpms6, lpm(seg.node))
+	}
+
+	return c
+}
+
+// Lookup returns the longest-prefix-match (lpm) for given ip, see [Table.Lookup].
+//
+// Lookup does not allocate memory.
+func (c *Compiled[V]) Lookup(ip netip.Addr) (lpm netip.Prefix, value V, ok bool) {
+	i := c.lookup(ip)
+	if i < 0 {
+		return
+	}
+	e := &c.entries[i]
+	return e.cidr, e.value, true
+}
+
+// LookupPrefix returns the longest-prefix-match (lpm) for given prefix, see [Table.LookupPrefix].
+//
+// LookupPrefix does not allocate memory.
+func (c *Compiled[V]) LookupPrefix(pfx netip.Prefix) (lpm netip.Prefix, value V, ok bool) {
+	if !pfx.IsValid() {
+		return
+	}
+	pfx = pfx.Masked() // always canonicalize!
+
+	// the lpm of the first address, or a less specific parent, covers the prefix
+	i := c.lookup(pfx.Addr())
+	for i >= 0 && c.entries[i].cidr.Bits() > pfx.Bits() {
+		i = c.entries[i].parent
+	}
+
+	if i < 0 {
+		return
+	}
+	e := &c.entries[i]
+	return e.cidr, e.value, true
+}
+
+// Len returns the number of entries.
+func (c *Compiled[V]) Len() int {
+	return len(c.entries)
+}
+
+// lookup returns the index of the lpm entry or -1.
+func (c *Compiled[V]) lookup(ip netip.Addr) int32 {
+	if ip.Is4() {
+		if len(c.starts4) == 0 {
+			return -1 // zero value
+		}
+		key := key4(ip)
+		i := sort.Search(len(c.starts4), func(i int) bool { return c.starts4[i] > key })
+		return c.lpms4[i-1]
+	}
+
+	if !ip.IsValid() || len(c.starts6) == 0 {
+		return -1
+	}
+
+	key := key6(ip)
+	i := sort.Search(len(c.starts6), func(i int) bool {
+		s := c.starts6[i]
+		return s.hi > key.hi || s.hi == key.hi && s.lo > key.lo
+	})
+	return c.lpms6[i-1]
+}
+
+func key4(ip netip.Addr) uint32 {
+	a := ip.As4()
+	return binary.BigEndian.Uint32(a[:])
+}
+
+func key6(ip netip.Addr) uint128 {
+	a := ip.As16()
+	return uint128{hi: binary.BigEndian.Uint64(a[:8]), lo: binary.BigEndian.Uint64(a[8:])}
+}
@@ -0,0 +1,90 @@
+package cidrtree_test
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/gaissmai/cidrtree"
+)
+
+func TestCompileZeroValue(t *testing.T) {
+	t.Parallel()
+
+	var zero cidrtree.Compiled[any]
+	if _, _, ok := zero.Lookup(mustAddr("10.0.0.1")); ok {
+		t.Errorf("Lookup() of zero value, got %v, want false", ok)
+	}
+
+	c := new(cidrtree.Table[any]).Compile()
+	if _, _, ok := c.LookupPrefix(mustPfx("::/0")); ok {
+		t.Errorf("LookupPrefix() of empty table, got %v, want false", ok)
+	}
+	if _, _, ok := c.Lookup(netip.Addr{}); ok {
+		t.Errorf("Lookup() of invalid ip, got %v, want false", ok)
+	}
+}
+
+func TestCompileLookup(t *testing.T) {
+	t.Parallel()
+
+	rtbl := new(cidrtree.Table[any])
+	for _, route := range routes {
+		rtbl.Insert(route.cidr, route.cidr)
+	}
+	rtbl.Insert(mustPfx("0.0.0.0/0"), "default")
+	rtbl.Insert(mustPfx("255.255.255.255/32"), "broadcast")
+
+	c := rtbl.Compile()
+	if c.Len() != len(routes)+2 {
+		t.Errorf("Len(), got %d, want %d", c.Len(), len(routes)+2)
+	}
+
+	var probes []netip.Addr
+	for _, s := range []string{"0.0.0.0", "9.255.255.255", "10.0.0.0", "10.0.1.255", "10.0.2.0", "255.255.255.255", "::", "::2", "2001:db8::1", "ffff::"} {
+		probes = append(probes, mustAddr(s))
+	}
+	for _, route := range routes {
+		addr := route.cidr.Addr()
+		probes = append(probes, addr, addr.Prev(), addr.Next())
+	}
+
+	for _, ip := range probes {
+		wantLPM, wantVal, wantOK := rtbl.Lookup(ip)
+		gotLPM, gotVal, gotOK := c.Lookup(ip)
+		if gotLPM != wantLPM || gotVal != wantVal || gotOK != wantOK {
+			t.Errorf("Lookup(%v), got (%v, %v, %v), want (%v, %v, %v)", ip, gotLPM, gotVal, gotOK, wantLPM, wantVal, wantOK)
+		}
+	}
+}
+
+func TestCompileFullTable(t *testing.T) {
+	t.Parallel()
+
+	rtbl := new(cidrtree.Table[any])
+	for _, cidr := range shuffleFullTable(100_000) {
+		rtbl.Insert(cidr, nil)
+	}
+	c := rtbl.Compile()
+
+	for _, cidr := range shuffleFullTable(10_000) {
+		for _, ip := range []netip.Addr{cidr.Addr(), cidr.Addr().Prev(), cidr.Addr().Next()} {
+			wantLPM, _, wantOK := rtbl.Lookup(ip)
+			gotLPM, _, gotOK := c.Lookup(ip)
+			if gotLPM != wantLPM || gotOK != wantOK {
+				t.Fatalf("Lookup(%v), got (%v, %v), want (%v, %v)", ip, gotLPM, gotOK, wantLPM, wantOK)
+			}
+		}
+
+		for _, bits := range []int{0, cidr.Bits() / 2, cidr.Bits(), cidr.Bits() + 1} {
+			pfx, err := cidr.Addr().Prefix(bits)
+			if err != nil {
+				continue
+			}
+			wantLPM, _, wantOK := rtbl.LookupPrefix(pfx)
+			gotLPM, _, gotOK := c.LookupPrefix(pfx)
+			if gotLPM != wantLPM || gotOK != wantOK {
+				t.Fatalf("LookupPrefix(%v), got (%v, %v), want (%v, %v)", pfx, gotLPM, gotOK, wantLPM, wantOK)
+			}
+		}
+	}
+}
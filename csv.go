@@ -0,0 +1,138 @@
+package cidrtree
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"strings"
+
+	"github.com/gaissmai/extnetip"
+)
+
+// column names in the CSV header for the network, or the start and end of an IP range.
+var (
+	csvNetworkColumns = []string{"network", "cidr", "prefix"}
+	csvStartColumns   = []string{"network_start_ip", "start_ip", "ip_start", "start", "first"}
+	csvEndColumns     = []string{"network_last_ip", "end_ip", "ip_end", "end", "last"}
+)
+
+// ReadCSV returns a table from CSV data like the MaxMind GeoIP2/GeoLite2 databases.
+//
+// The first record is the header, it must name the network column (network, cidr or prefix),
+// or the start and end columns of IP ranges (network_start_ip/network_last_ip, start_ip/end_ip,
+// ip_start/ip_end, start/end, first/last), the names are case insensitive.
+// IP ranges are split into CIDRs, all get the same value.
+//
+// The value for every record is returned by the value callback, header and record are
+// passed unchanged, the record must not be retained. For duplicate networks the last one wins.
+func ReadCSV[V any](r io.Reader, value func(header, record []string) (V, error)) (*Table[V], error) {
+	cr := csv.NewReader(r)
+	cr.ReuseRecord = true
+	cr.FieldsPerRecord = -1
+
+	header, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		return new(Table[V]), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cidrtree: csv header: %w", err)
+	}
+	header = append([]string(nil), header...) // not reused
+
+	network := csvColumn(header, csvNetworkColumns)
+	start := csvColumn(header, csvStartColumns)
+	end := csvColumn(header, csvEndColumns)
+
+	if network < 0 && (start < 0 || end < 0) {
+		return nil, fmt.Errorf("cidrtree: csv header: missing network or start and end column: %q", header)
+	}
+
+	var entries []Entry[V]
+	var pfxs []netip.Prefix
+
+	for {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("cidrtree: csv: %w", err)
+		}
+		line, _ := cr.FieldPos(0)
+
+		pfxs = pfxs[:0]
+		if network >= 0 {
+			pfxs, err = csvNetwork(pfxs, record, network)
+		} else {
+			pfxs, err = csvRange(pfxs, record, start, end)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("cidrtree: csv line %d: %w", line, err)
+		}
+
+		val, err := value(header, record)
+		if err != nil {
+			return nil, fmt.Errorf("cidrtree: csv line %d: %w", line, err)
+		}
+
+		for _, pfx := range pfxs {
+			entries = append(entries, Entry[V]{Prefix: pfx, Value: val})
+		}
+	}
+
+	return new(Table[V]).InsertManyImmutable(entries), nil
+}
+
+// csvColumn returns the index of the first header column with one of the names, or -1.
+func csvColumn(header []string, names []string) int {
+	for _, name := range names {
+		for i, col := range header {
+			if strings.EqualFold(strings.TrimSpace(col), name) {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func csvField(record []string, i int) (string, error) {
+	if i >= len(record) {
+		return "", fmt.Errorf("missing column %d", i+1)
+	}
+	return strings.TrimSpace(record[i]), nil
+}
+
+func csvNetwork(pfxs []netip.Prefix, record []string, i int) ([]netip.Prefix, error) {
+	field, err := csvField(record, i)
+	if err != nil {
+		return nil, err
+	}
+
+	pfx, err := netip.ParsePrefix(field)
+	if err != nil {
+		return nil, err
+	}
+	return append(pfxs, pfx), nil
+}
+
+func csvRange(pfxs []netip.Prefix, record []string, i, j int) ([]netip.Prefix, error) {
+	var addrs [2]netip.Addr
+	for k, col := range []int{i, j} {
+		field, err := csvField(record, col)
+		if err != nil {
+			return nil, err
+		}
+		if addrs[k], err = netip.ParseAddr(field); err != nil {
+			return nil, err
+		}
+	}
+
+	first, last := addrs[0], addrs[1]
+	if first.Is4() != last.Is4() || last.Less(first) {
+		return nil, fmt.Errorf("invalid range %s-%s", first, last)
+	}
+
+	return extnetip.PrefixesAppend(pfxs, first, last), nil
+}
@@ -0,0 +1,88 @@
+package cidrtree_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/gaissmai/cidrtree"
+)
+
+// country code is the second column
+func csvCountry(_, record []string) (string, error) {
+	if len(record) < 2 || record[1] == "" {
+		return "", errors.New("missing country")
+	}
+	return record[1], nil
+}
+
+func TestReadCSVNetwork(t *testing.T) {
+	t.Parallel()
+
+	data := `network,country_iso_code
+10.0.0.0/8,DE
+10.0.1.0/24,AT
+2001:db8::/32,CH
+`
+	rtbl, err := cidrtree.ReadCSV(strings.NewReader(data), csvCountry)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `▼
+└─ 10.0.0.0/8 (DE)
+   └─ 10.0.1.0/24 (AT)
+▼
+└─ 2001:db8::/32 (CH)
+`
+	if got := rtbl.String(); got != want {
+		t.Errorf("ReadCSV, got:\n%v\nwant:\n%v", got, want)
+	}
+}
+
+func TestReadCSVRange(t *testing.T) {
+	t.Parallel()
+
+	data := `Start_IP,Country,End_IP
+10.0.0.0,DE,10.0.2.255
+192.168.0.1,AT,192.168.0.1
+`
+	rtbl, err := cidrtree.ReadCSV(strings.NewReader(data), csvCountry)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `▼
+├─ 10.0.0.0/23 (DE)
+├─ 10.0.2.0/24 (DE)
+└─ 192.168.0.1/32 (AT)
+`
+	if got := rtbl.String(); got != want {
+		t.Errorf("ReadCSV, got:\n%v\nwant:\n%v", got, want)
+	}
+}
+
+func TestReadCSVErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []string{
+		"foo,bar\n10.0.0.0/8,DE\n",
+		"network,country\n10.0.0.0/33,DE\n",
+		"network,country\n10.0.0.0/8,\n",
+		"network,country\n10.0.0.0/8,DE\n\"foo\n",
+		"start,country,end\n10.0.0.9,DE,10.0.0.1\n",
+		"start,country,end\n10.0.0.1,DE,::1\n",
+		"start,country,end\n10.0.0.1,DE\n",
+	}
+
+	for _, data := range tests {
+		if _, err := cidrtree.ReadCSV(strings.NewReader(data), csvCountry); err == nil {
+			t.Errorf("ReadCSV(%q), expected error", data)
+		}
+	}
+
+	rtbl, err := cidrtree.ReadCSV(strings.NewReader(""), csvCountry)
+	if err != nil || rtbl.String() != "" {
+		t.Errorf("ReadCSV of empty input, got (%v, %v), want empty table", rtbl, err)
+	}
+}
@@ -35,7 +35,7 @@ func (t Table[V]) fprintBST(w io.Writer) error {
 // fprintBST recursive helper.
 func (n *node[V]) fprintBST(w io.Writer, pad string) error {
 	// stringify this node
-	_, err := fmt.Fprintf(w, "%v [prio:%.4g] [subtree maxUpper: %v]\n", n.cidr, float64(n.prio)/math.MaxUint64, n.maxUpper.cidr)
+	_, err := fmt.Fprintf(w, "%v [prio:%.4g] [subtree maxLast: %v]\n", n.cidr, float64(n.prio)/math.MaxUint64, n.maxLast.addr(n.cidr.Addr()))
 	if err != nil {
 		return err
 	}
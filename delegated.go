@@ -0,0 +1,127 @@
+package cidrtree
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gaissmai/extnetip"
+)
+
+// Delegation is a record of the RIR delegated-extended statistics files.
+type Delegation struct {
+	Registry string    // afrinic, apnic, arin, iana, lacnic or ripencc
+	CC       string    // ISO 3166 2-letter country code, ZZ or empty if unknown
+	Date     time.Time // date of the allocation or assignment, zero if unknown
+	Status   string    // allocated, assigned, available or reserved
+	OpaqueID string    // opaque holder id, empty in the non-extended format
+}
+
+// ReadDelegated returns a table from the RIR delegated-extended statistics format,
+// e.g. delegated-ripencc-extended-latest.
+//
+//	registry|cc|type|start|value|date|status[|opaque-id]
+//
+// The version header, summary lines, comments and asn records are skipped.
+// The value of ipv4 records is the number of addresses, these ranges are split into CIDRs.
+// The value of ipv6 records is the prefix length.
+func ReadDelegated(r io.Reader) (*Table[Delegation], error) {
+	var entries []Entry[Delegation]
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		var err error
+		if entries, err = parseDelegated(entries, text); err != nil {
+			return nil, fmt.Errorf("cidrtree: delegated line %d: %w", line, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("cidrtree: delegated: %w", err)
+	}
+
+	return new(Table[Delegation]).InsertManyImmutable(entries), nil
+}
+
+// parseDelegated appends the entries of a single record line.
+func parseDelegated(entries []Entry[Delegation], text string) ([]Entry[Delegation], error) {
+	fields := strings.Split(text, "|")
+
+	// version header: version|registry|serial|records|startdate|enddate|UTCoffset
+	if _, err := strconv.ParseFloat(fields[0], 64); err == nil {
+		return entries, nil
+	}
+
+	if len(fields) < 7 {
+		// summary lines: registry|*|type|*|count|summary
+		if len(fields) == 6 && fields[5] == "summary" {
+			return entries, nil
+		}
+		return nil, fmt.Errorf("malformed record: %q", text)
+	}
+
+	typ := fields[2]
+	if typ != "ipv4" && typ != "ipv6" {
+		return entries, nil // asn
+	}
+
+	start, err := netip.ParseAddr(fields[3])
+	if err != nil {
+		return nil, err
+	}
+	if start.Is4() != (typ == "ipv4") {
+		return nil, fmt.Errorf("start %s isn't %s", start, typ)
+	}
+
+	value, err := strconv.ParseUint(fields[4], 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	d := Delegation{
+		Registry: fields[0],
+		CC:       fields[1],
+		Status:   fields[6],
+	}
+	if len(fields) > 7 {
+		d.OpaqueID = fields[7]
+	}
+
+	// some registries use 00000000 for unknown dates
+	if fields[5] != "" && fields[5] != "00000000" {
+		if d.Date, err = time.Parse("20060102", fields[5]); err != nil {
+			return nil, err
+		}
+	}
+
+	if typ == "ipv6" {
+		pfx, err := start.Prefix(int(value))
+		if err != nil || value > 128 {
+			return nil, fmt.Errorf("invalid prefix length %d", value)
+		}
+		return append(entries, Entry[Delegation]{Prefix: pfx, Value: d}), nil
+	}
+
+	// ipv4, number of addresses
+	a4 := start.As4()
+	first := uint64(binary.BigEndian.Uint32(a4[:]))
+	if value == 0 || first+value > 1<<32 {
+		return nil, fmt.Errorf("invalid number of addresses %d", value)
+	}
+	binary.BigEndian.PutUint32(a4[:], uint32(first+value-1))
+	last := netip.AddrFrom4(a4)
+
+	for _, pfx := range extnetip.Prefixes(start, last) {
+		entries = append(entries, Entry[Delegation]{Prefix: pfx, Value: d})
+	}
+	return entries, nil
+}
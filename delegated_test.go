@@ -0,0 +1,77 @@
+package cidrtree_test
+
+import (
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gaissmai/cidrtree"
+)
+
+const delegatedData = `# comment
+2.3|ripencc|1700000000|4|19830705|20231113|+0100
+ripencc|*|ipv4|*|2|summary
+ripencc|*|ipv6|*|1|summary
+ripencc|*|asn|*|1|summary
+ripencc|DE|ipv4|10.0.0.0|768|20100705|allocated|abc-123
+ripencc|AT|ipv4|192.168.0.0|256|00000000|assigned|def-456
+ripencc|CH|ipv6|2001:db8::|32|20200101|allocated|ghi-789
+ripencc|FR|asn|64512|1|20200101|allocated|jkl-000
+`
+
+func TestReadDelegated(t *testing.T) {
+	t.Parallel()
+
+	rtbl, err := cidrtree.ReadDelegated(strings.NewReader(delegatedData))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	rtbl.Walk(func(pfx netip.Prefix, d cidrtree.Delegation) bool {
+		got = append(got, pfx.String()+" "+d.CC+" "+d.Status)
+		return true
+	})
+
+	want := []string{
+		"10.0.0.0/23 DE allocated",
+		"10.0.2.0/24 DE allocated",
+		"192.168.0.0/24 AT assigned",
+		"2001:db8::/32 CH allocated",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("ReadDelegated, got:\n%v\nwant:\n%v", got, want)
+	}
+
+	_, d, _ := rtbl.Lookup(mustAddr("10.0.2.1"))
+	wantDate := time.Date(2010, 7, 5, 0, 0, 0, 0, time.UTC)
+	if d.Registry != "ripencc" || d.OpaqueID != "abc-123" || !d.Date.Equal(wantDate) {
+		t.Errorf("Lookup(%v), got %+v", "10.0.2.1", d)
+	}
+
+	_, d, _ = rtbl.Lookup(mustAddr("192.168.0.1"))
+	if !d.Date.IsZero() {
+		t.Errorf("Lookup(%v), got date %v, want zero", "192.168.0.1", d.Date)
+	}
+}
+
+func TestReadDelegatedErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []string{
+		"ripencc|DE|ipv4\n",
+		"ripencc|DE|ipv4|10.0.0.x|256|20100705|allocated\n",
+		"ripencc|DE|ipv4|10.0.0.0|0|20100705|allocated\n",
+		"ripencc|DE|ipv4|255.255.255.0|257|20100705|allocated\n",
+		"ripencc|DE|ipv4|2001:db8::|256|20100705|allocated\n",
+		"ripencc|DE|ipv6|2001:db8::|129|20100705|allocated\n",
+		"ripencc|DE|ipv6|2001:db8::|32|2010-07-05|allocated\n",
+	}
+
+	for _, data := range tests {
+		if _, err := cidrtree.ReadDelegated(strings.NewReader(data)); err == nil {
+			t.Errorf("ReadDelegated(%q), expected error", data)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package cidrtree
+
+import (
+	"net/netip"
+
+	"github.com/gaissmai/extnetip"
+)
+
+// Bucket is a sub-prefix of the scope of [Table.Density] with the number of entries
+// within the bucket and the covered fraction of the bucket addresses.
+type Bucket struct {
+	Prefix   netip.Prefix
+	Entries  int
+	Coverage float64
+}
+
+// maxBucketBits, 2^16 buckets are enough for a /16 heatmap of the IPv4 address space.
+const maxBucketBits = 16
+
+// Density splits scope into the sub-prefixes with bits, e.g. the /8 or /16 of 0.0.0.0/0,
+// and returns them in ascending order with the number of entries within each bucket
+// and the covered fraction of the bucket, see [Table.Coverage]. The entries less specific
+// than the buckets count only for the coverage. The data is suitable for heatmaps and
+// Hilbert curve visualizations of the table.
+//
+// At most 2^16 buckets are returned, nil is returned for invalid bits or too many buckets.
+func (t Table[V]) Density(scope netip.Prefix, bits int) []Bucket {
+	scope = scope.Masked() // always canonicalize!
+	if !scope.IsValid() || bits < scope.Bits() || bits > scope.Addr().BitLen() || bits-scope.Bits() > maxBucketBits {
+		return nil
+	}
+
+	n := t.root6
+	if scope.Addr().Is4() {
+		n = t.root4
+	}
+
+	buckets := make([]Bucket, 0, 1<<(bits-scope.Bits()))
+	for addr := scope.Addr(); addr.IsValid() && scope.Contains(addr); {
+		pfx := netip.PrefixFrom(addr, bits)
+		_, last := extnetip.Range(pfx)
+
+		entries := 0
+		n.walkRange(pfx, netip.PrefixFrom(last, last.BitLen()), func(*node[V]) bool {
+			entries++
+			return true
+		})
+
+		_, fraction := t.Coverage(pfx)
+		buckets = append(buckets, Bucket{Prefix: pfx, Entries: entries, Coverage: fraction})
+
+		// the next bucket, invalid after the last address
+		addr = last.Next()
+	}
+
+	return buckets
+}
+
+// SubtreeStats are the statistics of a top-level entry and all entries covered by it,
+// the depth is relative to the top-level entry with depth 0.
+type SubtreeStats struct {
+	Prefix   netip.Prefix
+	Entries  int
+	MaxDepth int
+	AvgDepth float64
+}
+
+// TopLevelStats returns the statistics per top-level entry in ascending order, the entries
+// not covered by any other entry, see [Table.Fprint]. The subtrees exploding the table
+// are those with many entries or a deep nesting.
+func (t Table[V]) TopLevelStats() []SubtreeStats {
+	var stats []SubtreeStats
+	var depthSum int
+
+	// the previous subtree is complete
+	flush := func() {
+		if len(stats) > 0 {
+			last := &stats[len(stats)-1]
+			last.AvgDepth = float64(depthSum) / float64(last.Entries)
+		}
+		depthSum = 0
+	}
+
+	for _, root := range []*node[V]{t.root4, t.root6} {
+		root.walkNested(func(n *node[V], parents []*node[V]) bool {
+			if len(parents) == 0 {
+				flush()
+				stats = append(stats, SubtreeStats{Prefix: n.cidr})
+			}
+
+			last := &stats[len(stats)-1]
+			last.Entries++
+			last.MaxDepth = max(last.MaxDepth, len(parents))
+			depthSum += len(parents)
+			return true
+		})
+	}
+	flush()
+
+	return stats
+}
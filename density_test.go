@@ -0,0 +1,93 @@
+package cidrtree_test
+
+import (
+	"testing"
+
+	"github.com/gaissmai/cidrtree"
+)
+
+func TestDensity(t *testing.T) {
+	t.Parallel()
+
+	rtbl := new(cidrtree.Table[any])
+	for _, route := range routes {
+		rtbl.Insert(route.cidr, route.nextHop)
+	}
+
+	buckets := rtbl.Density(mustPfx("0.0.0.0/0"), 8)
+	if len(buckets) != 256 {
+		t.Fatalf("Density(0.0.0.0/0, 8), got %d buckets, want %d", len(buckets), 256)
+	}
+
+	want := map[int]cidrtree.Bucket{
+		0:   {Prefix: mustPfx("0.0.0.0/8")},
+		10:  {Prefix: mustPfx("10.0.0.0/8"), Entries: 3, Coverage: 1},
+		127: {Prefix: mustPfx("127.0.0.0/8"), Entries: 2, Coverage: 1},
+		169: {Prefix: mustPfx("169.0.0.0/8"), Entries: 1, Coverage: 1.0 / 256},
+		172: {Prefix: mustPfx("172.0.0.0/8"), Entries: 1, Coverage: 1.0 / 16},
+		192: {Prefix: mustPfx("192.0.0.0/8"), Entries: 2, Coverage: 1.0 / 256},
+		255: {Prefix: mustPfx("255.0.0.0/8")},
+	}
+
+	for i, b := range want {
+		if buckets[i] != b {
+			t.Errorf("Density(0.0.0.0/0, 8)[%d], got %v, want %v", i, buckets[i], b)
+		}
+	}
+
+	// the less specific entry 10.0.0.0/8 counts only for the coverage
+	buckets = rtbl.Density(mustPfx("10.0.0.0/8"), 24)
+	if len(buckets) != 1<<16 {
+		t.Fatalf("Density(10.0.0.0/8, 24), got %d buckets, want %d", len(buckets), 1<<16)
+	}
+	if b := buckets[1]; b.Prefix != mustPfx("10.0.1.0/24") || b.Entries != 1 || b.Coverage != 1 {
+		t.Errorf("Density(10.0.0.0/8, 24)[1], got %v", b)
+	}
+	if b := buckets[2]; b.Entries != 0 || b.Coverage != 1 {
+		t.Errorf("Density(10.0.0.0/8, 24)[2], got %v", b)
+	}
+
+	for _, bits := range []int{-1, 7, 25, 33} {
+		if buckets := rtbl.Density(mustPfx("10.0.0.0/8"), bits); buckets != nil {
+			t.Errorf("Density(10.0.0.0/8, %d), got %d buckets, want nil", bits, len(buckets))
+		}
+	}
+
+	if got := len(rtbl.Density(mustPfx("2001:db8::/32"), 48)); got != 1<<16 {
+		t.Errorf("Density(2001:db8::/32, 48), got %d buckets, want %d", got, 1<<16)
+	}
+}
+
+func TestTopLevelStats(t *testing.T) {
+	t.Parallel()
+
+	rtbl := new(cidrtree.Table[any])
+	for _, route := range routes {
+		rtbl.Insert(route.cidr, route.nextHop)
+	}
+	rtbl.Insert(mustPfx("10.0.1.128/25"), nil)
+
+	want := []cidrtree.SubtreeStats{
+		{Prefix: mustPfx("10.0.0.0/8"), Entries: 4, MaxDepth: 2, AvgDepth: 1},
+		{Prefix: mustPfx("127.0.0.0/8"), Entries: 2, MaxDepth: 1, AvgDepth: 0.5},
+		{Prefix: mustPfx("169.254.0.0/16"), Entries: 1},
+		{Prefix: mustPfx("172.16.0.0/12"), Entries: 1},
+		{Prefix: mustPfx("192.168.0.0/16"), Entries: 2, MaxDepth: 1, AvgDepth: 0.5},
+		{Prefix: mustPfx("::/0"), Entries: 7, MaxDepth: 2, AvgDepth: 1},
+	}
+
+	got := rtbl.TopLevelStats()
+	if len(got) != len(want) {
+		t.Fatalf("TopLevelStats, got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("TopLevelStats[%d], got %v, want %v", i, got[i], want[i])
+		}
+	}
+
+	var zeroTable cidrtree.Table[any]
+	if got := zeroTable.TopLevelStats(); got != nil {
+		t.Errorf("TopLevelStats of zero value, got %v, want nil", got)
+	}
+}
@@ -108,3 +108,26 @@ func ExampleTable_Walk() {
 	// 2001:db8::/32 (2001:db8::1)
 	// fe80::/10 (::1%lo)
 }
+
+func ExampleTable_LookupFunc() {
+	rtbl := new(cidrtree.Table[netip.Addr])
+	for _, item := range input {
+		rtbl.Insert(item.cidr, item.nextHop)
+	}
+
+	// next hop health, e.g. from BFD sessions
+	down := map[netip.Addr]bool{mustAddr("10.0.0.0"): true}
+	healthy := func(_ netip.Prefix, nextHop netip.Addr) bool { return !down[nextHop] }
+
+	ip := mustAddr("10.0.1.17")
+	lpm, value, ok := rtbl.LookupFunc(ip, healthy)
+	fmt.Printf("LookupFunc: %-10v lpm: %-11v value: %v, ok: %v\n", ip, lpm, value, ok)
+
+	down[mustAddr("9.9.9.9")] = true
+	lpm, value, ok = rtbl.LookupFunc(ip, healthy)
+	fmt.Printf("LookupFunc: %-10v lpm: %-11v value: %v, ok: %v\n", ip, lpm, value, ok)
+
+	// Output:
+	// LookupFunc: 10.0.1.17  lpm: 10.0.0.0/8  value: 9.9.9.9, ok: true
+	// LookupFunc: 10.0.1.17  lpm: invalid Prefix value: invalid IP, ok: false
+}
@@ -0,0 +1,141 @@
+package cidrtree
+
+import (
+	"fmt"
+	"io"
+	"net/netip"
+	"strings"
+
+	"github.com/gaissmai/extnetip"
+)
+
+// FprintIpset writes the table as input for `ipset restore` to w, the sets of type hash:net
+// are created if they don't exist. set4 and set6 are the set names for the IPv4 and IPv6 prefixes,
+// an empty name skips the IP version.
+//
+// Packet filter sets are membership tests, the values are ignored. The nested and adjacent
+// prefixes are merged, the output is the minimal list of CIDRs covering the same addresses.
+//
+//	create allow4 hash:net family inet -exist
+//	add allow4 10.0.0.0/8 -exist
+func (t Table[V]) FprintIpset(w io.Writer, set4, set6 string) error {
+	for _, fam := range []struct {
+		set    string
+		family string
+		root   *node[V]
+	}{
+		{set4, "inet", t.root4},
+		{set6, "inet6", t.root6},
+	} {
+		if fam.set == "" {
+			continue
+		}
+
+		if _, err := fmt.Fprintf(w, "create %s hash:net family %s -exist\n", fam.set, fam.family); err != nil {
+			return err
+		}
+		for _, pfx := range fam.root.covering() {
+			if _, err := fmt.Fprintf(w, "add %s %v -exist\n", fam.set, pfx); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// FprintNftables writes the table as nftables set elements for `nft -f` to w, table is the
+// nftables family and table name, e.g. "inet filter". set4 and set6 are the set names for the
+// IPv4 and IPv6 prefixes, an empty name skips the IP version. The sets must be declared
+// with `flags interval`, IP versions without prefixes are skipped.
+//
+// Packet filter sets are membership tests, the values are ignored. The nested and adjacent
+// prefixes are merged, nftables rejects overlapping interval elements.
+//
+//	add element inet filter allow4 { 10.0.0.0/8, 192.168.0.0/16 }
+func (t Table[V]) FprintNftables(w io.Writer, table, set4, set6 string) error {
+	for _, fam := range []struct {
+		set  string
+		root *node[V]
+	}{
+		{set4, t.root4},
+		{set6, t.root6},
+	} {
+		if fam.set == "" {
+			continue
+		}
+
+		pfxs := fam.root.covering()
+		if len(pfxs) == 0 {
+			continue
+		}
+
+		elems := make([]string, 0, len(pfxs))
+		for _, pfx := range pfxs {
+			elems = append(elems, pfx.String())
+		}
+
+		if _, err := fmt.Fprintf(w, "add element %s %s { %s }\n", table, fam.set, strings.Join(elems, ", ")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Summarize returns a new table with the minimal set of CIDRs covering exactly the same addresses
+// as the union of all entries, e.g. for compact ACLs and announcements. Nested, adjacent and
+// overlapping entries are merged. The value of a summary CIDR is folded with merge over the values
+// of all entries within it, in ascending order. If merge is nil, the values are the zero value of V.
+func (t Table[V]) Summarize(merge func(acc, value V) V) *Table[V] {
+	s := new(Table[V])
+
+	for _, root := range []*node[V]{t.root4, t.root6} {
+		for _, pfx := range root.covering() {
+			var value V
+			if merge != nil {
+				_, last := extnetip.Range(pfx)
+				first := true
+
+				root.walkRange(pfx, netip.PrefixFrom(last, last.BitLen()), func(m *node[V]) bool {
+					if first {
+						value, first = m.value, false
+					} else {
+						value = merge(value, m.value)
+					}
+					return true
+				})
+			}
+			s.Insert(pfx, value)
+		}
+	}
+	return s
+}
+
+// covering returns the minimal list of CIDRs in ascending order, covering
+// the same addresses as the union of all prefixes in the treap.
+func (n *node[V]) covering() []netip.Prefix {
+	var pfxs []netip.Prefix
+	var first, last netip.Addr
+
+	n.walkNodes(func(m *node[V]) bool {
+		mFirst, mLast := extnetip.Range(m.cidr)
+
+		switch {
+		case !first.IsValid():
+			first, last = mFirst, mLast
+		case !last.Less(mFirst):
+			// nested, CIDRs are nested or disjunct
+		case last.Next() == mFirst:
+			// adjacent, merge
+			last = mLast
+		default:
+			pfxs = extnetip.PrefixesAppend(pfxs, first, last)
+			first, last = mFirst, mLast
+		}
+		return true
+	})
+
+	if first.IsValid() {
+		pfxs = extnetip.PrefixesAppend(pfxs, first, last)
+	}
+	return pfxs
+}
@@ -0,0 +1,122 @@
+package cidrtree_test
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/gaissmai/cidrtree"
+)
+
+func filterTable() *cidrtree.Table[any] {
+	rtbl := new(cidrtree.Table[any])
+	for _, s := range []string{
+		"10.0.0.0/8",
+		"10.1.0.0/16", // nested
+		"192.168.0.0/24",
+		"192.168.1.0/24", // adjacent
+		"203.0.113.0/24",
+		"2001:db8::/32",
+	} {
+		rtbl.Insert(mustPfx(s), nil)
+	}
+	return rtbl
+}
+
+func TestFprintIpset(t *testing.T) {
+	t.Parallel()
+
+	want := `create allow4 hash:net family inet -exist
+add allow4 10.0.0.0/8 -exist
+add allow4 192.168.0.0/23 -exist
+add allow4 203.0.113.0/24 -exist
+create allow6 hash:net family inet6 -exist
+add allow6 2001:db8::/32 -exist
+`
+
+	w := new(strings.Builder)
+	if err := filterTable().FprintIpset(w, "allow4", "allow6"); err != nil {
+		t.Fatal(err)
+	}
+	if w.String() != want {
+		t.Errorf("FprintIpset\nwant:\n%sgot:\n%s", want, w.String())
+	}
+
+	w.Reset()
+	if err := filterTable().FprintIpset(w, "", "allow6"); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(w.String(), "allow4") {
+		t.Errorf("FprintIpset without set4, got:\n%s", w.String())
+	}
+}
+
+func TestFprintNftables(t *testing.T) {
+	t.Parallel()
+
+	want := `add element inet filter allow4 { 10.0.0.0/8, 192.168.0.0/23, 203.0.113.0/24 }
+add element inet filter allow6 { 2001:db8::/32 }
+`
+
+	w := new(strings.Builder)
+	if err := filterTable().FprintNftables(w, "inet filter", "allow4", "allow6"); err != nil {
+		t.Fatal(err)
+	}
+	if w.String() != want {
+		t.Errorf("FprintNftables\nwant:\n%sgot:\n%s", want, w.String())
+	}
+
+	// empty sets are skipped
+	w.Reset()
+	if err := new(cidrtree.Table[any]).FprintNftables(w, "inet filter", "allow4", "allow6"); err != nil {
+		t.Fatal(err)
+	}
+	if w.String() != "" {
+		t.Errorf("FprintNftables of empty table, got:\n%s", w.String())
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	t.Parallel()
+
+	rtbl := new(cidrtree.Table[int])
+	for i, s := range []string{
+		"10.0.0.0/8",
+		"10.1.0.0/16", // nested
+		"192.168.0.0/24",
+		"192.168.1.0/24", // adjacent, aligned
+		"192.168.2.0/24", // adjacent, not aligned
+		"2001:db8::/33",
+		"2001:db8:8000::/33",
+	} {
+		rtbl.Insert(mustPfx(s), i+1)
+	}
+
+	sum := func(acc, value int) int { return acc + value }
+
+	want := "10.0.0.0/8 3\n192.168.0.0/23 7\n192.168.2.0/24 5\n2001:db8::/32 13\n"
+
+	w := new(strings.Builder)
+	rtbl.Summarize(sum).Walk(func(pfx netip.Prefix, value int) bool {
+		fmt.Fprintf(w, "%v %d\n", pfx, value)
+		return true
+	})
+
+	if w.String() != want {
+		t.Errorf("Summarize\nwant:\n%sgot:\n%s", want, w.String())
+	}
+
+	// values discarded
+	rtbl.Summarize(nil).Walk(func(pfx netip.Prefix, value int) bool {
+		if value != 0 {
+			t.Errorf("Summarize(nil), %v got value %d, want 0", pfx, value)
+		}
+		return true
+	})
+
+	var zeroTable cidrtree.Table[int]
+	if got := zeroTable.Summarize(sum).Size(); got != 0 {
+		t.Errorf("Summarize of zero value, got size %d, want 0", got)
+	}
+}
@@ -0,0 +1,84 @@
+package cidrtree
+
+import (
+	"net/netip"
+)
+
+// Flow is the key of a flow for the consistent next hop selection, see [Table.LookupFlow].
+type Flow struct {
+	Src     netip.Addr
+	Dst     netip.Addr
+	SrcPort uint16
+	DstPort uint16
+	Proto   uint8
+}
+
+// LookupFlow returns the longest-prefix-match for the destination of the flow and selects
+// one of the next hops of the matched entry, the next hops of a value are returned by nextHops.
+//
+// The selection is consistent, rendezvous hashing (highest random weight) of the flow key
+// and the next hop. The same flow is always pinned to the same next hop, as long as the next hop
+// is in the set. If a next hop is removed, only the flows of this next hop are moved,
+// if a next hop is added, only the flows now pinned to the new next hop are moved.
+//
+// If there is no match or the matched entry has no next hops, ok is false.
+func (t Table[V]) LookupFlow(flow Flow, nextHops func(V) []netip.Addr) (lpm netip.Prefix, hop netip.Addr, ok bool) {
+	lpm, value, ok := t.Lookup(flow.Dst)
+	if !ok {
+		return
+	}
+
+	hops := nextHops(value)
+	if len(hops) == 0 {
+		return lpm, hop, false
+	}
+
+	key := flow.hash()
+
+	var maxWeight uint64
+	for i, h := range hops {
+		if w := hashAddr(key, h); i == 0 || w > maxWeight {
+			maxWeight = w
+			hop = h
+		}
+	}
+
+	return lpm, hop, true
+}
+
+// hash the flow key with FNV-1a.
+func (f Flow) hash() uint64 {
+	h := uint64(fnvOffset)
+	h = hashAddr(h, f.Src)
+	h = hashAddr(h, f.Dst)
+	h = fnvByte(h, byte(f.SrcPort>>8))
+	h = fnvByte(h, byte(f.SrcPort))
+	h = fnvByte(h, byte(f.DstPort>>8))
+	h = fnvByte(h, byte(f.DstPort))
+	h = fnvByte(h, f.Proto)
+	return h
+}
+
+const (
+	fnvOffset = 14695981039346656037
+	fnvPrime  = 1099511628211
+)
+
+func fnvByte(h uint64, b byte) uint64 {
+	return (h ^ uint64(b)) * fnvPrime
+}
+
+// hashAddr continues the FNV-1a hash h with the address a and mixes the bits,
+// the final avalanche is from splitmix64.
+func hashAddr(h uint64, a netip.Addr) uint64 {
+	for _, b := range a.As16() {
+		h = fnvByte(h, b)
+	}
+
+	h ^= h >> 30
+	h *= 0xbf58476d1ce4e5b9
+	h ^= h >> 27
+	h *= 0x94d049bb133111eb
+	h ^= h >> 31
+	return h
+}
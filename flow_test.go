@@ -0,0 +1,75 @@
+package cidrtree_test
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/gaissmai/cidrtree"
+)
+
+func TestLookupFlow(t *testing.T) {
+	t.Parallel()
+
+	hops := []netip.Addr{mustAddr("192.0.2.1"), mustAddr("192.0.2.2"), mustAddr("192.0.2.3"), mustAddr("192.0.2.4")}
+
+	rtbl := new(cidrtree.Table[[]netip.Addr])
+	rtbl.Insert(mustPfx("0.0.0.0/0"), hops)
+	rtbl.Insert(mustPfx("10.0.0.0/8"), nil)
+
+	nextHops := func(v []netip.Addr) []netip.Addr { return v }
+
+	if _, _, ok := rtbl.LookupFlow(cidrtree.Flow{Dst: mustAddr("10.0.0.1")}, nextHops); ok {
+		t.Errorf("LookupFlow, entry without next hops, got %v, want false", ok)
+	}
+
+	if _, _, ok := rtbl.LookupFlow(cidrtree.Flow{Dst: mustAddr("::1")}, nextHops); ok {
+		t.Errorf("LookupFlow, no match, got %v, want false", ok)
+	}
+
+	// selection of all flows with all next hops
+	pinned := make(map[cidrtree.Flow]netip.Addr)
+	count := make(map[netip.Addr]int)
+
+	for i := 0; i < 4_000; i++ {
+		f := cidrtree.Flow{
+			Src:     mustAddr("198.51.100.1"),
+			Dst:     netip.AddrFrom4([4]byte{203, 0, byte(i >> 8), byte(i)}),
+			SrcPort: uint16(i),
+			DstPort: 443,
+			Proto:   6,
+		}
+
+		lpm, hop, ok := rtbl.LookupFlow(f, nextHops)
+		if !ok || lpm != mustPfx("0.0.0.0/0") {
+			t.Fatalf("LookupFlow(%v) = %v, %v, %v", f, lpm, hop, ok)
+		}
+
+		if _, again, _ := rtbl.LookupFlow(f, nextHops); again != hop {
+			t.Fatalf("LookupFlow(%v) not consistent, %v != %v", f, again, hop)
+		}
+
+		pinned[f] = hop
+		count[hop]++
+	}
+
+	// roughly uniform
+	for _, h := range hops {
+		if count[h] < 800 || count[h] > 1200 {
+			t.Errorf("LookupFlow, next hop %v got %d of 4000 flows", h, count[h])
+		}
+	}
+
+	// remove one next hop, only the flows of this next hop are moved
+	removed := hops[1]
+	rtbl.Insert(mustPfx("0.0.0.0/0"), []netip.Addr{hops[0], hops[2], hops[3]})
+
+	for f, old := range pinned {
+		_, hop, _ := rtbl.LookupFlow(f, nextHops)
+		if old != removed && hop != old {
+			t.Fatalf("LookupFlow(%v) moved from %v to %v", f, old, hop)
+		}
+		if hop == removed {
+			t.Fatalf("LookupFlow(%v) still pinned to removed hop %v", f, hop)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package cidrtree
+
+import "net/netip"
+
+// LookupDiff is a probe address with different lookup results in two tables, see [ForwardingDiff].
+// The prefix of an entry is invalid if there is no match in the table.
+type LookupDiff[V any] struct {
+	Addr netip.Addr
+	A    Entry[V]
+	B    Entry[V]
+}
+
+// ForwardingDiff looks up all probe addresses in both tables and returns the addresses with
+// different results, in the order of the probes. The results differ if only one table has a match
+// or the values of the matches are not equal. The lpm prefixes may differ, e.g. for the validation
+// of an aggregated table against the original.
+//
+// The probes are generated by calling yield for each address, e.g. from a slice or a random sample,
+// the generator should stop if yield returns false.
+func ForwardingDiff[V any](a, b Table[V], probes func(yield func(ip netip.Addr) bool), equal func(x, y V) bool) []LookupDiff[V] {
+	var diffs []LookupDiff[V]
+
+	probes(func(ip netip.Addr) bool {
+		lpmA, valueA, okA := a.Lookup(ip)
+		lpmB, valueB, okB := b.Lookup(ip)
+
+		if okA != okB || (okA && !equal(valueA, valueB)) {
+			diffs = append(diffs, LookupDiff[V]{
+				Addr: ip,
+				A:    Entry[V]{Prefix: lpmA, Value: valueA},
+				B:    Entry[V]{Prefix: lpmB, Value: valueB},
+			})
+		}
+		return true
+	})
+
+	return diffs
+}
+
+// Equivalent reports whether both tables forward identically, that is the lookups of all
+// possible addresses have a match in both or in none of the tables and the values of the
+// matches are equal. This is decided exactly by comparing the partitions of the address space
+// induced by the longest-prefix-matches, not by sampling, e.g. to verify Minimize or Collapse.
+//
+// If the tables are not equivalent, the lowest address with different lookup results is returned.
+func Equivalent[V any](a, b Table[V], equal func(x, y V) bool) (ok bool, counterexample netip.Addr) {
+	families := []struct {
+		a, b *node[V]
+		zero netip.Addr
+	}{
+		{a.root4, b.root4, netip.IPv4Unspecified()},
+		{a.root6, b.root6, netip.IPv6Unspecified()},
+	}
+
+	for _, f := range families {
+		segsA := mergeSegments(f.a.segments(f.zero), equal)
+		segsB := mergeSegments(f.b.segments(f.zero), equal)
+
+		if ip, ok := diffSegments(segsA, segsB, equal); !ok {
+			return false, ip
+		}
+	}
+	return true, netip.Addr{}
+}
+
+// mergeSegments merges adjacent segments with equal lookup results, the partition is canonical.
+func mergeSegments[V any](segs []segment[V], equal func(x, y V) bool) []segment[V] {
+	merged := segs[:0:0]
+	for _, seg := range segs {
+		if len(merged) > 0 && sameResult(merged[len(merged)-1].node, seg.node, equal) {
+			continue
+		}
+		merged = append(merged, seg)
+	}
+	return merged
+}
+
+// diffSegments compares two canonical partitions, returns the lowest address with different results.
+func diffSegments[V any](a, b []segment[V], equal func(x, y V) bool) (netip.Addr, bool) {
+	for i := 0; i < len(a) || i < len(b); i++ {
+		// the previous segments are equal, the longer partition changes its result
+		if i == len(a) {
+			return b[i].start, false
+		}
+		if i == len(b) {
+			return a[i].start, false
+		}
+
+		// the earlier start changes the result, canonical partitions have no equal neighbors
+		if a[i].start != b[i].start {
+			if a[i].start.Less(b[i].start) {
+				return a[i].start, false
+			}
+			return b[i].start, false
+		}
+
+		if !sameResult(a[i].node, b[i].node, equal) {
+			return a[i].start, false
+		}
+	}
+	return netip.Addr{}, true
+}
+
+// sameResult reports whether both lpm nodes forward alike, nil is no match.
+func sameResult[V any](x, y *node[V], equal func(x, y V) bool) bool {
+	if x == nil || y == nil {
+		return x == y
+	}
+	return equal(x.value, y.value)
+}
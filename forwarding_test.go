@@ -0,0 +1,124 @@
+package cidrtree_test
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/gaissmai/cidrtree"
+)
+
+func TestForwardingDiff(t *testing.T) {
+	t.Parallel()
+
+	orig := new(cidrtree.Table[string])
+	orig.Insert(mustPfx("10.0.0.0/24"), "a")
+	orig.Insert(mustPfx("10.0.1.0/24"), "a")
+	orig.Insert(mustPfx("10.0.2.0/24"), "b")
+	orig.Insert(mustPfx("2001:db8::/32"), "c")
+
+	// aggregated, but 10.0.3.0/24 is captured and 2001:db8::/32 is lost
+	aggr := new(cidrtree.Table[string])
+	aggr.Insert(mustPfx("10.0.0.0/23"), "a")
+	aggr.Insert(mustPfx("10.0.2.0/23"), "b")
+
+	probes := []netip.Addr{
+		mustAddr("10.0.0.1"), mustAddr("10.0.1.1"), mustAddr("10.0.2.1"),
+		mustAddr("10.0.3.1"), mustAddr("2001:db8::1"), mustAddr("::1"),
+	}
+	gen := func(yield func(netip.Addr) bool) {
+		for _, ip := range probes {
+			if !yield(ip) {
+				return
+			}
+		}
+	}
+	equal := func(x, y string) bool { return x == y }
+
+	diffs := cidrtree.ForwardingDiff(*orig, *aggr, gen, equal)
+	if len(diffs) != 2 {
+		t.Fatalf("ForwardingDiff, got %v, want 2 diffs", diffs)
+	}
+
+	if d := diffs[0]; d.Addr != mustAddr("10.0.3.1") || d.A.Prefix.IsValid() || d.B.Prefix != mustPfx("10.0.2.0/23") {
+		t.Errorf("ForwardingDiff[0], got %v", d)
+	}
+	if d := diffs[1]; d.Addr != mustAddr("2001:db8::1") || d.A.Value != "c" || d.B.Prefix.IsValid() {
+		t.Errorf("ForwardingDiff[1], got %v", d)
+	}
+
+	if diffs := cidrtree.ForwardingDiff(*orig, *orig.Clone(), gen, equal); diffs != nil {
+		t.Errorf("ForwardingDiff of clone, got %v, want nil", diffs)
+	}
+}
+
+func TestEquivalent(t *testing.T) {
+	t.Parallel()
+
+	equal := func(x, y string) bool { return x == y }
+
+	orig := new(cidrtree.Table[string])
+	orig.Insert(mustPfx("10.0.0.0/8"), "a")
+	orig.Insert(mustPfx("10.0.0.0/24"), "a")
+	orig.Insert(mustPfx("10.0.1.0/24"), "b")
+	orig.Insert(mustPfx("2001:db8::/33"), "c")
+	orig.Insert(mustPfx("2001:db8:8000::/33"), "c")
+
+	// redundant more specifics removed and siblings collapsed
+	opt := new(cidrtree.Table[string])
+	opt.Insert(mustPfx("10.0.0.0/8"), "a")
+	opt.Insert(mustPfx("10.0.1.0/24"), "b")
+	opt.Insert(mustPfx("2001:db8::/32"), "c")
+
+	if ok, ip := cidrtree.Equivalent(*orig, *opt, equal); !ok {
+		t.Errorf("Equivalent, got false at %v, want true", ip)
+	}
+
+	var empty cidrtree.Table[string]
+	if ok, _ := cidrtree.Equivalent(empty, empty, equal); !ok {
+		t.Errorf("Equivalent of empty tables, got false, want true")
+	}
+
+	tcs := []struct {
+		change func(*cidrtree.Table[string])
+		want   netip.Addr
+	}{
+		{func(t *cidrtree.Table[string]) { t.Insert(mustPfx("10.0.2.128/25"), "b") }, mustAddr("10.0.2.128")},
+		{func(t *cidrtree.Table[string]) { t.Insert(mustPfx("10.0.1.0/24"), "x") }, mustAddr("10.0.1.0")},
+		{func(t *cidrtree.Table[string]) { t.Delete(mustPfx("10.0.0.0/8")) }, mustAddr("10.0.0.0")},
+		{func(t *cidrtree.Table[string]) { t.Insert(mustPfx("::/0"), "d") }, mustAddr("::")},
+		{func(t *cidrtree.Table[string]) { t.Insert(mustPfx("2001:db9::/32"), "c") }, mustAddr("2001:db9::")},
+		{func(t *cidrtree.Table[string]) { t.Insert(mustPfx("0.0.0.0/0"), "z") }, mustAddr("0.0.0.0")},
+	}
+
+	for _, tc := range tcs {
+		changed := opt.Clone()
+		tc.change(changed)
+
+		if ok, ip := cidrtree.Equivalent(*orig, *changed, equal); ok || ip != tc.want {
+			t.Errorf("Equivalent, got (%v, %v), want (false, %v)", ok, ip, tc.want)
+		}
+
+		// symmetric
+		if ok, ip := cidrtree.Equivalent(*changed, *orig, equal); ok || ip != tc.want {
+			t.Errorf("Equivalent swapped, got (%v, %v), want (false, %v)", ok, ip, tc.want)
+		}
+	}
+}
+
+func TestEquivalentMinimize(t *testing.T) {
+	t.Parallel()
+
+	equal := func(x, y int) bool { return x == y }
+
+	rtbl := new(cidrtree.Table[int])
+	for i, cidr := range shuffleFullTable(10_000) {
+		rtbl.Insert(cidr, i%3)
+	}
+
+	minimized := rtbl.Clone()
+	minimized.Minimize(equal)
+
+	if ok, ip := cidrtree.Equivalent(*rtbl, *minimized, equal); !ok {
+		t.Errorf("Equivalent after Minimize, got false at %v, want true", ip)
+	}
+}
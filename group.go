@@ -0,0 +1,104 @@
+package cidrtree
+
+import (
+	"net/netip"
+	"sync"
+	"sync/atomic"
+)
+
+// Group is a set of named tables with atomic multi-table transactions, e.g. the IPv4/IPv6 tables
+// of many VRFs. Readers observe either all or none of the changes of a transaction.
+// The zero value is ready to use.
+type Group[K comparable, V any] struct {
+	mu  sync.Mutex // serializes the transactions
+	ptr atomic.Pointer[GroupSnapshot[K, V]]
+}
+
+// GroupSnapshot is a consistent, read-only snapshot of all tables in a [Group].
+type GroupSnapshot[K comparable, V any] struct {
+	tables map[K]*Table[V]
+}
+
+// Table returns the frozen table with name, an empty table if name isn't in the group.
+func (s *GroupSnapshot[K, V]) Table(name K) *Table[V] {
+	if t := s.tables[name]; t != nil {
+		return t
+	}
+	return &Table[V]{frozen: true}
+}
+
+// Len returns the number of tables in the snapshot.
+func (s *GroupSnapshot[K, V]) Len() int {
+	return len(s.tables)
+}
+
+// Snapshot returns the current consistent snapshot of all tables.
+func (g *Group[K, V]) Snapshot() *GroupSnapshot[K, V] {
+	if s := g.ptr.Load(); s != nil {
+		return s
+	}
+	return &GroupSnapshot[K, V]{}
+}
+
+// Update runs fn in a transaction. If fn returns nil, all changes are committed atomically,
+// otherwise they are discarded and the error is returned. The transactions are serialized.
+func (g *Group[K, V]) Update(fn func(tx *Tx[K, V]) error) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	base := g.Snapshot()
+	tx := &Tx[K, V]{base: base, changed: make(map[K]*Table[V])}
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if len(tx.changed) == 0 {
+		return nil
+	}
+
+	next := &GroupSnapshot[K, V]{tables: make(map[K]*Table[V], len(base.tables)+len(tx.changed))}
+	for name, t := range base.tables {
+		next.tables[name] = t
+	}
+	for name, t := range tx.changed {
+		t.Freeze()
+		next.tables[name] = t
+	}
+
+	g.ptr.Store(next)
+	return nil
+}
+
+// Tx is a transaction on a [Group], see [Group.Update].
+// The changes are made with the immutable methods, they are invisible to readers until the commit.
+type Tx[K comparable, V any] struct {
+	base    *GroupSnapshot[K, V]
+	changed map[K]*Table[V]
+}
+
+// Table returns the table with name as changed so far in the transaction, the table must not be modified.
+func (tx *Tx[K, V]) Table(name K) *Table[V] {
+	if t := tx.changed[name]; t != nil {
+		return t
+	}
+	return tx.base.Table(name)
+}
+
+// Set replaces the table with name, t is frozen with the commit.
+func (tx *Tx[K, V]) Set(name K, t *Table[V]) {
+	tx.changed[name] = t
+}
+
+// Insert adds pfx with value to the table with name.
+func (tx *Tx[K, V]) Insert(name K, pfx netip.Prefix, value V) {
+	tx.changed[name] = tx.Table(name).InsertImmutable(pfx, value)
+}
+
+// Delete removes pfx from the table with name, returns true if pfx was in the table.
+func (tx *Tx[K, V]) Delete(name K, pfx netip.Prefix) bool {
+	t, ok := tx.Table(name).DeleteImmutable(pfx)
+	if ok {
+		tx.changed[name] = t
+	}
+	return ok
+}
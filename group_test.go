@@ -0,0 +1,100 @@
+package cidrtree_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/gaissmai/cidrtree"
+)
+
+func TestGroup(t *testing.T) {
+	t.Parallel()
+
+	var g cidrtree.Group[string, int]
+	if g.Snapshot().Len() != 0 || g.Snapshot().Table("red").Size() != 0 {
+		t.Fatalf("zero value isn't empty")
+	}
+
+	err := g.Update(func(tx *cidrtree.Tx[string, int]) error {
+		tx.Insert("red", mustPfx("10.0.0.0/8"), 1)
+		tx.Insert("red", mustPfx("::/0"), 2)
+		tx.Insert("blue", mustPfx("10.0.0.0/8"), 3)
+		if tx.Table("red").Size() != 2 {
+			t.Errorf("Tx.Table, changes are not visible in the transaction")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	snap := g.Snapshot()
+	if snap.Len() != 2 || snap.Table("red").Size() != 2 || snap.Table("blue").Size() != 1 {
+		t.Errorf("Update, got %d tables", snap.Len())
+	}
+	if !snap.Table("red").Frozen() {
+		t.Errorf("Update, committed table isn't frozen")
+	}
+
+	// rollback
+	errAbort := errors.New("abort")
+	err = g.Update(func(tx *cidrtree.Tx[string, int]) error {
+		tx.Delete("red", mustPfx("10.0.0.0/8"))
+		tx.Insert("green", mustPfx("10.0.0.0/8"), 4)
+		return errAbort
+	})
+	if !errors.Is(err, errAbort) {
+		t.Errorf("Update, got error %v, want %v", err, errAbort)
+	}
+	if g.Snapshot() != snap {
+		t.Errorf("Update with error, changes committed")
+	}
+
+	// the old snapshot is unchanged by later commits
+	_ = g.Update(func(tx *cidrtree.Tx[string, int]) error {
+		if !tx.Delete("red", mustPfx("10.0.0.0/8")) {
+			t.Errorf("Tx.Delete, got false, want true")
+		}
+		return nil
+	})
+	if snap.Table("red").Size() != 2 || g.Snapshot().Table("red").Size() != 1 {
+		t.Errorf("Update changed the old snapshot")
+	}
+}
+
+func TestGroupAtomicity(t *testing.T) {
+	t.Parallel()
+
+	var g cidrtree.Group[string, int]
+
+	// move a prefix between two tables, readers see it in exactly one table
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 1_000; i++ {
+			from, to := "a", "b"
+			if i%2 == 1 {
+				from, to = to, from
+			}
+			_ = g.Update(func(tx *cidrtree.Tx[string, int]) error {
+				tx.Delete(from, mustPfx("10.0.0.0/8"))
+				tx.Insert(to, mustPfx("10.0.0.0/8"), i)
+				return nil
+			})
+		}
+	}()
+
+	for i := 0; i < 1_000; i++ {
+		snap := g.Snapshot()
+		if snap.Len() == 0 {
+			continue
+		}
+		n := snap.Table("a").Size() + snap.Table("b").Size()
+		if n != 1 {
+			t.Fatalf("Snapshot isn't consistent, got %d entries, want 1", n)
+		}
+	}
+	wg.Wait()
+}
@@ -0,0 +1,90 @@
+package cidrtree
+
+import (
+	"fmt"
+	"html"
+	"io"
+)
+
+// FprintHTML writes the ordered CIDR tree as nested HTML lists to w, the CIDRs with subnets
+// are collapsible <details> elements. The values are rendered with render, they are HTML-escaped.
+// If render is nil, the values are rendered with the default format of the fmt package.
+//
+// The hierarchy is the same as for [Table.Fprint], the output is a HTML fragment,
+// one <ul> element for each IP version.
+func (t Table[V]) FprintHTML(w io.Writer, render func(V) string) error {
+	if render == nil {
+		render = func(v V) string { return fmt.Sprint(v) }
+	}
+
+	if err := t.root4.fprintHTML(w, render); err != nil {
+		return err
+	}
+	if err := t.root6.fprintHTML(w, render); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (n *node[V]) fprintHTML(w io.Writer, render func(V) string) error {
+	if n == nil {
+		return nil
+	}
+
+	// pcm = parent-child-mapping
+	var pcm parentChildsMap[V]
+
+	// init map
+	pcm.pcMap = make(map[*node[V]][]*node[V])
+
+	pcm = n.buildParentChildsMap(pcm)
+
+	if len(pcm.pcMap) == 0 {
+		return nil
+	}
+
+	// start recursion with root
+	var root *node[V]
+	return root.walkAndHTML(w, pcm, render)
+}
+
+func (n *node[V]) walkAndHTML(w io.Writer, pcm parentChildsMap[V], render func(V) string) error {
+	// dereference child-slice for clearer code
+	childs := pcm.pcMap[n]
+
+	if _, err := fmt.Fprint(w, "<ul>\n"); err != nil {
+		return err
+	}
+
+	for _, child := range childs {
+		text := html.EscapeString(fmt.Sprintf("%v (%v)", child.cidr, render(child.value)))
+
+		// leaf
+		if len(pcm.pcMap[child]) == 0 {
+			if _, err := fmt.Fprintf(w, "<li>%s</li>\n", text); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// collapsible subtree
+		if _, err := fmt.Fprintf(w, "<li><details><summary>%s</summary>\n", text); err != nil {
+			return err
+		}
+
+		// recdescent down
+		if err := child.walkAndHTML(w, pcm, render); err != nil {
+			return err
+		}
+
+		if _, err := fmt.Fprint(w, "</details></li>\n"); err != nil {
+			return err
+		}
+	}
+
+	if _, err := fmt.Fprint(w, "</ul>\n"); err != nil {
+		return err
+	}
+
+	return nil
+}
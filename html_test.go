@@ -0,0 +1,57 @@
+package cidrtree_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gaissmai/cidrtree"
+)
+
+const asHTMLStr = `<ul>
+<li><details><summary>10.0.0.0/8 (A&amp;B)</summary>
+<ul>
+<li>10.0.0.0/24 (&lt;C&gt;)</li>
+<li>10.0.1.0/24 (&lt;C&gt;)</li>
+</ul>
+</details></li>
+<li>127.0.0.1/32 (&lt;C&gt;)</li>
+</ul>
+<ul>
+<li>::1/128 (&lt;C&gt;)</li>
+</ul>
+`
+
+func TestFprintHTML(t *testing.T) {
+	t.Parallel()
+
+	rtbl := new(cidrtree.Table[string])
+	rtbl.Insert(mustPfx("10.0.0.0/8"), "A&B")
+	rtbl.Insert(mustPfx("10.0.0.0/24"), "<C>")
+	rtbl.Insert(mustPfx("10.0.1.0/24"), "<C>")
+	rtbl.Insert(mustPfx("127.0.0.1/32"), "<C>")
+	rtbl.Insert(mustPfx("::1/128"), "<C>")
+
+	w := new(strings.Builder)
+	if err := rtbl.FprintHTML(w, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if w.String() != asHTMLStr {
+		t.Errorf("FprintHTML\nwant:\n%sgot:\n%s", asHTMLStr, w.String())
+	}
+
+	w.Reset()
+	if err := rtbl.FprintHTML(w, strings.ToLower); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(w.String(), "10.0.0.0/8 (a&amp;b)") {
+		t.Errorf("FprintHTML with render, got:\n%s", w.String())
+	}
+
+	var zeroTable cidrtree.Table[any]
+	w.Reset()
+	if err := zeroTable.FprintHTML(w, nil); err != nil || w.String() != "" {
+		t.Errorf("FprintHTML of zero value, got %q, %v, want \"\"", w.String(), err)
+	}
+}
@@ -0,0 +1,46 @@
+package cidrtree
+
+import "net/netip"
+
+// Impact is the effect of a candidate change on the longest-prefix-match results of the table.
+// The addresses of Prefixes change their lpm from Before to After, e.g. for change reviews
+// of the routing policy. An invalid Before or After is no match.
+type Impact struct {
+	Prefixes []netip.Prefix // the affected address space as CIDRs in ascending order
+	Before   netip.Prefix
+	After    netip.Prefix
+}
+
+// InsertImpact returns the impact of inserting pfx, without changing the table. The addresses
+// of pfx not covered by more specific entries move from the covering entry to pfx.
+// If pfx is already in the table, Before is pfx itself, only the value would change.
+func (t Table[V]) InsertImpact(pfx netip.Prefix) Impact {
+	pfx = pfx.Masked() // always canonicalize!
+
+	impact := Impact{Prefixes: t.gaps(pfx), Before: t.parent(pfx), After: pfx}
+	if _, ok := t.get(pfx); ok {
+		impact.Before = pfx
+	}
+	return impact
+}
+
+// DeleteImpact returns the impact of deleting pfx, without changing the table. The addresses
+// of pfx not covered by more specific entries fall back to the covering entry of pfx.
+// Returns false if pfx isn't in the table.
+func (t Table[V]) DeleteImpact(pfx netip.Prefix) (Impact, bool) {
+	pfx = pfx.Masked() // always canonicalize!
+
+	if _, ok := t.get(pfx); !ok {
+		return Impact{}, false
+	}
+	return Impact{Prefixes: t.gaps(pfx), Before: pfx, After: t.parent(pfx)}, true
+}
+
+// parent returns the most specific entry strictly covering pfx, invalid if there is none.
+func (t Table[V]) parent(pfx netip.Prefix) netip.Prefix {
+	supernets := t.supernets(pfx)
+	if len(supernets) == 0 {
+		return netip.Prefix{}
+	}
+	return supernets[len(supernets)-1].cidr
+}
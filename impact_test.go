@@ -0,0 +1,99 @@
+package cidrtree_test
+
+import (
+	"net/netip"
+	"reflect"
+	"testing"
+
+	"github.com/gaissmai/cidrtree"
+)
+
+func TestInsertImpact(t *testing.T) {
+	t.Parallel()
+
+	rtbl := new(cidrtree.Table[any])
+	for _, route := range routes {
+		rtbl.Insert(route.cidr, route.nextHop)
+	}
+	before := rtbl.String()
+
+	tcs := []struct {
+		pfx  netip.Prefix
+		want cidrtree.Impact
+	}{
+		{
+			pfx: mustPfx("10.0.0.0/23"),
+			want: cidrtree.Impact{
+				Before: mustPfx("10.0.0.0/8"),
+				After:  mustPfx("10.0.0.0/23"),
+			},
+		},
+		{
+			pfx: mustPfx("10.0.0.0/22"),
+			want: cidrtree.Impact{
+				Prefixes: []netip.Prefix{mustPfx("10.0.2.0/23")},
+				Before:   mustPfx("10.0.0.0/8"),
+				After:    mustPfx("10.0.0.0/22"),
+			},
+		},
+		{
+			pfx: mustPfx("11.0.0.0/8"),
+			want: cidrtree.Impact{
+				Prefixes: []netip.Prefix{mustPfx("11.0.0.0/8")},
+				After:    mustPfx("11.0.0.0/8"),
+			},
+		},
+		{
+			// value change only
+			pfx: mustPfx("192.168.0.0/16"),
+			want: cidrtree.Impact{
+				Prefixes: []netip.Prefix{mustPfx("192.168.0.0/24"), mustPfx("192.168.2.0/23"), mustPfx("192.168.4.0/22"),
+					mustPfx("192.168.8.0/21"), mustPfx("192.168.16.0/20"), mustPfx("192.168.32.0/19"),
+					mustPfx("192.168.64.0/18"), mustPfx("192.168.128.0/17")},
+				Before: mustPfx("192.168.0.0/16"),
+				After:  mustPfx("192.168.0.0/16"),
+			},
+		},
+	}
+
+	for _, tc := range tcs {
+		if got := rtbl.InsertImpact(tc.pfx); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("InsertImpact(%v), got %v, want %v", tc.pfx, got, tc.want)
+		}
+	}
+
+	if rtbl.String() != before {
+		t.Errorf("InsertImpact changed the table")
+	}
+}
+
+func TestDeleteImpact(t *testing.T) {
+	t.Parallel()
+
+	rtbl := new(cidrtree.Table[any])
+	for _, route := range routes {
+		rtbl.Insert(route.cidr, route.nextHop)
+	}
+
+	if _, ok := rtbl.DeleteImpact(mustPfx("10.0.2.0/24")); ok {
+		t.Errorf("DeleteImpact(%v), got %v, want false", "10.0.2.0/24", ok)
+	}
+
+	want := cidrtree.Impact{
+		Prefixes: []netip.Prefix{mustPfx("10.0.1.0/24")},
+		Before:   mustPfx("10.0.1.0/24"),
+		After:    mustPfx("10.0.0.0/8"),
+	}
+	if got, _ := rtbl.DeleteImpact(mustPfx("10.0.1.0/24")); !reflect.DeepEqual(got, want) {
+		t.Errorf("DeleteImpact(%v), got %v, want %v", "10.0.1.0/24", got, want)
+	}
+
+	// no covering entry, the addresses are unrouted after the delete
+	want = cidrtree.Impact{
+		Prefixes: []netip.Prefix{mustPfx("172.16.0.0/12")},
+		Before:   mustPfx("172.16.0.0/12"),
+	}
+	if got, _ := rtbl.DeleteImpact(mustPfx("172.16.0.0/12")); !reflect.DeepEqual(got, want) {
+		t.Errorf("DeleteImpact(%v), got %v, want %v", "172.16.0.0/12", got, want)
+	}
+}
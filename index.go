@@ -0,0 +1,100 @@
+package cidrtree
+
+import (
+	"net/netip"
+	"slices"
+)
+
+// IndexedTable is a routing table with a secondary index from values to prefixes,
+// kept in sync by Insert and Delete. Reverse lookups by value are O(k) instead of O(n),
+// at the cost of memory and slower updates. The zero value is ready to use.
+//
+// Only the mutable API is supported, the index can't be shared between snapshots.
+type IndexedTable[V comparable] struct {
+	table Table[V]
+	index map[V]map[netip.Prefix]struct{}
+}
+
+// Insert adds pfx to the table with value of comparable type V and updates the index.
+// If pfx is already present in the table, its value is set to the new value.
+func (it *IndexedTable[V]) Insert(pfx netip.Prefix, value V) {
+	pfx = pfx.Masked() // always canonicalize!
+
+	if old, existed := it.table.Swap(pfx, value); existed {
+		it.unindex(pfx, old)
+	}
+
+	if it.index == nil {
+		it.index = make(map[V]map[netip.Prefix]struct{})
+	}
+	if it.index[value] == nil {
+		it.index[value] = make(map[netip.Prefix]struct{})
+	}
+	it.index[value][pfx] = struct{}{}
+}
+
+// Delete removes the prefix from table and index, returns true if it exists, false otherwise.
+func (it *IndexedTable[V]) Delete(pfx netip.Prefix) bool {
+	pfx = pfx.Masked() // always canonicalize!
+
+	lpm, value, ok := it.table.LookupPrefix(pfx)
+	if !ok || lpm != pfx {
+		return false
+	}
+
+	it.unindex(pfx, value)
+	return it.table.Delete(pfx)
+}
+
+// Lookup returns the longest-prefix-match for ip, see [Table.Lookup].
+func (it *IndexedTable[V]) Lookup(ip netip.Addr) (lpm netip.Prefix, value V, ok bool) {
+	return it.table.Lookup(ip)
+}
+
+// LookupPrefix returns the longest-prefix-match for pfx, see [Table.LookupPrefix].
+func (it *IndexedTable[V]) LookupPrefix(pfx netip.Prefix) (lpm netip.Prefix, value V, ok bool) {
+	return it.table.LookupPrefix(pfx)
+}
+
+// PrefixesWithValue returns all prefixes in ascending order with the given value, using the index.
+func (it *IndexedTable[V]) PrefixesWithValue(value V) []netip.Prefix {
+	set := it.index[value]
+	if len(set) == 0 {
+		return nil
+	}
+
+	pfxs := make([]netip.Prefix, 0, len(set))
+	for pfx := range set {
+		pfxs = append(pfxs, pfx)
+	}
+
+	// compare sorts IPv4 before IPv6
+	slices.SortFunc(pfxs, compare)
+	return pfxs
+}
+
+// DeleteValue removes all prefixes with the given value, e.g. all routes via a dead next hop.
+// Returns the number of deleted prefixes.
+func (it *IndexedTable[V]) DeleteValue(value V) int {
+	set := it.index[value]
+	for pfx := range set {
+		it.table.Delete(pfx)
+	}
+	delete(it.index, value)
+	return len(set)
+}
+
+// Table returns a clone of the underlying routing table for all other operations, O(n).
+// Changes of the clone don't affect the indexed table, the index stays in sync.
+func (it *IndexedTable[V]) Table() Table[V] {
+	return *it.table.Clone()
+}
+
+// unindex pfx with value.
+func (it *IndexedTable[V]) unindex(pfx netip.Prefix, value V) {
+	set := it.index[value]
+	delete(set, pfx)
+	if len(set) == 0 {
+		delete(it.index, value)
+	}
+}
@@ -0,0 +1,94 @@
+package cidrtree_test
+
+import (
+	"net/netip"
+	"reflect"
+	"testing"
+
+	"github.com/gaissmai/cidrtree"
+)
+
+func TestIndexedTable(t *testing.T) {
+	t.Parallel()
+
+	it := new(cidrtree.IndexedTable[netip.Addr])
+	for _, route := range routes {
+		it.Insert(route.cidr, route.nextHop)
+	}
+
+	if it.Table().String() != asTopoStr {
+		t.Errorf("Fprint()\nwant:\n%sgot:\n%s", asTopoStr, it.Table().String())
+	}
+
+	v4, v6 := mustAddr("203.0.113.0"), mustAddr("2001:db8::1")
+
+	if got := len(it.PrefixesWithValue(v4)); got != 9 {
+		t.Errorf("PrefixesWithValue(%v), got %d prefixes, want %d", v4, got, 9)
+	}
+
+	// overwrite the value of some prefixes
+	other := mustAddr("192.0.2.1")
+	it.Insert(mustPfx("10.0.0.0/8"), other)
+	it.Insert(mustPfx("::1/128"), other)
+	it.Insert(mustPfx("::1/128"), other)
+
+	want := []netip.Prefix{mustPfx("10.0.0.0/8"), mustPfx("::1/128")}
+	if got := it.PrefixesWithValue(other); !reflect.DeepEqual(got, want) {
+		t.Errorf("PrefixesWithValue(%v), got %v, want %v", other, got, want)
+	}
+
+	if got := len(it.PrefixesWithValue(v4)); got != 8 {
+		t.Errorf("PrefixesWithValue(%v), got %d prefixes, want %d", v4, got, 8)
+	}
+
+	if ok := it.Delete(mustPfx("10.0.0.0/9")); ok {
+		t.Errorf("Delete(%v), got %v, want false", "10.0.0.0/9", ok)
+	}
+
+	if ok := it.Delete(mustPfx("10.0.0.0/8")); !ok {
+		t.Errorf("Delete(%v), got %v, want true", "10.0.0.0/8", ok)
+	}
+
+	want = []netip.Prefix{mustPfx("::1/128")}
+	if got := it.PrefixesWithValue(other); !reflect.DeepEqual(got, want) {
+		t.Errorf("PrefixesWithValue(%v), got %v, want %v", other, got, want)
+	}
+
+	// withdraw all routes via v6 next hop
+	if n := it.DeleteValue(v6); n != 6 {
+		t.Errorf("DeleteValue(%v), got %d, want %d", v6, n, 6)
+	}
+
+	if lpm, _, _ := it.Lookup(mustAddr("2001:db8::1")); lpm.IsValid() {
+		t.Errorf("Lookup(%v) after DeleteValue, got %v, want invalid", "2001:db8::1", lpm)
+	}
+
+	if lpm, _, _ := it.LookupPrefix(mustPfx("::1/128")); lpm != mustPfx("::1/128") {
+		t.Errorf("LookupPrefix(%v), got %v, want %v", "::1/128", lpm, "::1/128")
+	}
+
+	if got := it.PrefixesWithValue(v6); got != nil {
+		t.Errorf("PrefixesWithValue(%v), got %v, want nil", v6, got)
+	}
+}
+
+func TestIndexedTableTableClone(t *testing.T) {
+	t.Parallel()
+
+	it := new(cidrtree.IndexedTable[netip.Addr])
+	for _, route := range routes {
+		it.Insert(route.cidr, route.nextHop)
+	}
+
+	// changes of the returned table don't affect the indexed table
+	rtbl := it.Table()
+	rtbl.Insert(mustPfx("10.0.0.0/8"), mustAddr("192.0.2.1"))
+	rtbl.Delete(mustPfx("::1/128"))
+
+	if got := it.Table().String(); got != asTopoStr {
+		t.Errorf("Table() after changing the returned table\nwant:\n%sgot:\n%s", asTopoStr, got)
+	}
+	if got := it.PrefixesWithValue(mustAddr("192.0.2.1")); got != nil {
+		t.Errorf("PrefixesWithValue(%v), got %v, want nil", "192.0.2.1", got)
+	}
+}
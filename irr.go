@@ -0,0 +1,144 @@
+package cidrtree
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/netip"
+	"strings"
+	"unicode"
+)
+
+// RouteObject is the origin of a route or route6 object in an IRR database.
+type RouteObject struct {
+	Origin uint32   // origin AS
+	Source string   // registry, e.g. RIPE or RADB
+	MntBy  []string // maintainers
+}
+
+// ReadIRR returns a table from an IRR database dump in RPSL, e.g. ripe.db.route.gz
+// or radb.db.gz after decompression. The value of a prefix are all its route objects,
+// many origins for the same prefix are common.
+//
+//	route:   192.0.2.0/24
+//	origin:  AS64500
+//	mnt-by:  EXAMPLE-MNT
+//	source:  RIPE
+//
+// Objects are separated by blank lines, all other object classes and comments are skipped.
+// The attribute values may be continued on lines beginning with white space or '+'.
+func ReadIRR(r io.Reader) (*Table[[]RouteObject], error) {
+	t := new(Table[[]RouteObject])
+
+	var obj irrObject
+	flush := func() error {
+		defer func() { obj = irrObject{} }()
+
+		pfx, ro, ok, err := obj.routeObject()
+		if err != nil {
+			return fmt.Errorf("cidrtree: irr object at line %d: %w", obj.line, err)
+		}
+		if !ok {
+			return nil
+		}
+
+		old, _ := t.get(pfx)
+		t.Insert(pfx, append(old, ro))
+		return nil
+	}
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+
+		switch {
+		case strings.TrimSpace(text) == "":
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			continue
+		case strings.HasPrefix(text, "%") || strings.HasPrefix(text, "#"):
+			continue
+		case text[0] == ' ' || text[0] == '\t' || text[0] == '+':
+			// continuation of the last attribute
+			if len(obj.attrs) == 0 {
+				return nil, fmt.Errorf("cidrtree: irr line %d: continuation without attribute", line)
+			}
+			last := &obj.attrs[len(obj.attrs)-1]
+			last.value = strings.TrimSpace(last.value + " " + stripComment(text[1:]))
+			continue
+		}
+
+		key, value, found := strings.Cut(text, ":")
+		if !found {
+			return nil, fmt.Errorf("cidrtree: irr line %d: malformed attribute: %q", line, text)
+		}
+
+		if len(obj.attrs) == 0 {
+			obj.line = line
+		}
+		obj.attrs = append(obj.attrs, irrAttr{strings.ToLower(strings.TrimSpace(key)), stripComment(value)})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("cidrtree: irr: %w", err)
+	}
+
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// irrObject is a parsed RPSL object, the attributes in input order.
+type irrObject struct {
+	line  int // first line of the object
+	attrs []irrAttr
+}
+
+type irrAttr struct {
+	key, value string
+}
+
+// routeObject returns the prefix and origin of a route or route6 object,
+// ok is false for all other object classes.
+func (o irrObject) routeObject() (pfx netip.Prefix, ro RouteObject, ok bool, err error) {
+	if len(o.attrs) == 0 || o.attrs[0].key != "route" && o.attrs[0].key != "route6" {
+		return
+	}
+
+	if pfx, err = netip.ParsePrefix(o.attrs[0].value); err != nil {
+		return
+	}
+	pfx = pfx.Masked() // always canonicalize!
+
+	hasOrigin := false
+	for _, attr := range o.attrs[1:] {
+		switch attr.key {
+		case "origin":
+			if ro.Origin, err = parseASN(attr.value); err != nil {
+				return
+			}
+			hasOrigin = true
+		case "source":
+			ro.Source = strings.ToUpper(attr.value)
+		case "mnt-by":
+			ro.MntBy = append(ro.MntBy, strings.FieldsFunc(attr.value, func(r rune) bool {
+				return r == ',' || unicode.IsSpace(r)
+			})...)
+		}
+	}
+
+	if !hasOrigin {
+		err = fmt.Errorf("%s %s without origin", o.attrs[0].key, pfx)
+		return
+	}
+	return pfx, ro, true, nil
+}
+
+// stripComment removes the trailing # comment and the white space of an attribute value.
+func stripComment(value string) string {
+	if i := strings.IndexByte(value, '#'); i >= 0 {
+		value = value[:i]
+	}
+	return strings.TrimSpace(value)
+}
@@ -0,0 +1,87 @@
+package cidrtree_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gaissmai/cidrtree"
+)
+
+const irrDump = `% The objects are in RPSL format.
+% Note: this output has been filtered.
+
+route:          192.0.2.0/24
+descr:          Example network
+origin:         AS64500
+mnt-by:         EXAMPLE-MNT, OTHER-MNT
+source:         ripe # primary
+
+route:          192.0.2.0/24
+origin:         AS64501
+mnt-by:         BACKUP-MNT
++               THIRD-MNT
+source:         RADB
+
+aut-num:        AS64500
+as-name:        EXAMPLE
+
+route6:         2001:db8::/32
+origin:         as64502
+source:         RIPE
+`
+
+func TestReadIRR(t *testing.T) {
+	t.Parallel()
+
+	rtbl, err := cidrtree.ReadIRR(strings.NewReader(irrDump))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n := rtbl.Size(); n != 2 {
+		t.Fatalf("Size, got %v, want %v", n, 2)
+	}
+
+	_, ros, _ := rtbl.LookupPrefix(mustPfx("192.0.2.0/24"))
+	if len(ros) != 2 {
+		t.Fatalf("route objects, got %v, want 2", ros)
+	}
+	if ro := ros[0]; ro.Origin != 64500 || ro.Source != "RIPE" || strings.Join(ro.MntBy, " ") != "EXAMPLE-MNT OTHER-MNT" {
+		t.Errorf("route object, got %+v", ro)
+	}
+	if ro := ros[1]; ro.Origin != 64501 || ro.Source != "RADB" || len(ro.MntBy) != 2 || strings.Join(ro.MntBy, " ") != "BACKUP-MNT THIRD-MNT" {
+		t.Errorf("route object, got %+v", ro)
+	}
+
+	if _, ros, ok := rtbl.Lookup(mustAddr("2001:db8::1")); !ok || ros[0].Origin != 64502 {
+		t.Errorf("route6 object, got %+v, %v", ros, ok)
+	}
+
+	for _, bad := range []string{
+		"route: 192.0.2.0/24\nsource: RIPE\n",
+		"route: 192.0.2.0/33\norigin: AS1\n",
+		"route: 192.0.2.0/24\norigin: ASX\n",
+		"  continuation\n",
+		"route 192.0.2.0/24\n",
+	} {
+		if _, err := cidrtree.ReadIRR(strings.NewReader(bad)); err == nil {
+			t.Errorf("ReadIRR(%q), expected error", bad)
+		}
+	}
+}
+
+func TestReadIRRErrorLine(t *testing.T) {
+	t.Parallel()
+
+	db := "route: 192.0.2.0/24\norigin: AS1\n\n% comment\nroute: 198.51.100.0/24\norigin: ASX\n\n"
+	_, err := cidrtree.ReadIRR(strings.NewReader(db))
+	if err == nil || !strings.Contains(err.Error(), "line 5") {
+		t.Errorf("ReadIRR, got error %v, want object at line 5", err)
+	}
+
+	// without the trailing blank line
+	_, err = cidrtree.ReadIRR(strings.NewReader(strings.TrimSpace(db)))
+	if err == nil || !strings.Contains(err.Error(), "line 5") {
+		t.Errorf("ReadIRR at EOF, got error %v, want object at line 5", err)
+	}
+}
@@ -0,0 +1,208 @@
+package cidrtree
+
+import (
+	"net/netip"
+
+	"github.com/gaissmai/extnetip"
+)
+
+// Iterator is a pull iterator over a snapshot of the table in ascending order.
+//
+// The iterator captures the roots of the table at creation. Concurrent writers using the
+// immutable methods (or an [Atomic] table) never change the captured nodes, the iterator is
+// snapshot-stable and never sees partial updates. Mutable methods on the same table
+// are not allowed while iterating, they change the nodes in place.
+//
+//	it := rtbl.Iter()
+//	for it.Next() {
+//		fmt.Println(it.Prefix(), it.Value())
+//	}
+//
+// The adapters FilterByPrefix, FilterByValue and Limit restrict the remaining entries,
+// they can be chained, e.g. the first 100 entries under 10.0.0.0/8:
+//
+//	it := rtbl.Iter().FilterByPrefix(pfx).Limit(100)
+type Iterator[V any] struct {
+	roots [2]*node[V] // the pending roots, v4 and v6
+	stack []*node[V]  // the path of pending left ancestors
+	cur   *node[V]
+	seek  netip.Prefix // the entries before are skipped, see FilterByPrefix
+
+	filters []func(*node[V]) bool // all must keep the entry
+	stops   []func(*node[V]) bool // any stops the iteration, no more entries can pass
+	limit   int                   // remaining entries, if limited
+	limited bool
+}
+
+// Iter returns an iterator over the current snapshot of the table.
+func (t Table[V]) Iter() *Iterator[V] {
+	return &Iterator[V]{roots: [2]*node[V]{t.root4, t.root6}}
+}
+
+// Iter4 returns an iterator over the IPv4 entries of the current snapshot, see [Table.Iter].
+func (t Table[V]) Iter4() *Iterator[V] {
+	return &Iterator[V]{roots: [2]*node[V]{t.root4, nil}}
+}
+
+// Iter6 returns an iterator over the IPv6 entries of the current snapshot, see [Table.Iter].
+func (t Table[V]) Iter6() *Iterator[V] {
+	return &Iterator[V]{roots: [2]*node[V]{t.root6, nil}}
+}
+
+// FilterByPrefix restricts the iterator to the entries equal to or covered by pfx.
+// The iterator seeks to the first entry of pfx in O(log n), the iteration ends after
+// the last subnet of pfx.
+func (it *Iterator[V]) FilterByPrefix(pfx netip.Prefix) *Iterator[V] {
+	pfx = pfx.Masked() // always canonicalize!
+	_, last := extnetip.Range(pfx)
+
+	// pfx sorts before all its subnets
+	if !it.seek.IsValid() || compare(pfx, it.seek) > 0 {
+		it.seek = pfx
+	}
+
+	it.filters = append(it.filters, func(n *node[V]) bool {
+		return n.cidr.Bits() >= pfx.Bits() && pfx.Contains(n.cidr.Addr())
+	})
+
+	// ascending order, IPv4 before IPv6
+	it.stops = append(it.stops, func(n *node[V]) bool {
+		if n.cidr.Addr().Is4() != pfx.Addr().Is4() {
+			return pfx.Addr().Is4()
+		}
+		return n.cidr.Addr().Compare(last) > 0
+	})
+	return it
+}
+
+// FilterByValue restricts the iterator to the entries with values passing keep.
+func (it *Iterator[V]) FilterByValue(keep func(V) bool) *Iterator[V] {
+	it.filters = append(it.filters, func(n *node[V]) bool {
+		return keep(n.value)
+	})
+	return it
+}
+
+// Limit restricts the iterator to at most n more entries.
+func (it *Iterator[V]) Limit(n int) *Iterator[V] {
+	if !it.limited || n < it.limit {
+		it.limit = n
+	}
+	it.limited = true
+	return it
+}
+
+// Chunk returns the next entries, at most size, e.g. for paginated exports.
+// At the end, nil is returned.
+func (it *Iterator[V]) Chunk(size int) []Entry[V] {
+	var chunk []Entry[V]
+	for len(chunk) < size && it.Next() {
+		chunk = append(chunk, Entry[V]{Prefix: it.cur.cidr, Value: it.cur.value})
+	}
+	return chunk
+}
+
+// Next advances the iterator to the next entry, it returns false at the end.
+func (it *Iterator[V]) Next() bool {
+	if it.limited && it.limit <= 0 {
+		it.cur = nil
+		return false
+	}
+
+	for it.advance() {
+		if it.stop(it.cur) {
+			// drop the pending nodes, the end is reached
+			it.roots = [2]*node[V]{}
+			it.stack = nil
+			it.cur = nil
+			return false
+		}
+
+		if it.keep(it.cur) {
+			if it.limited {
+				it.limit--
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// keep reports whether n passes all filters.
+func (it *Iterator[V]) keep(n *node[V]) bool {
+	for _, f := range it.filters {
+		if !f(n) {
+			return false
+		}
+	}
+	return true
+}
+
+// stop reports whether the iteration ends at n.
+func (it *Iterator[V]) stop(n *node[V]) bool {
+	for _, f := range it.stops {
+		if f(n) {
+			return true
+		}
+	}
+	return false
+}
+
+// advance to the next node in ascending order, without the adapters.
+func (it *Iterator[V]) advance() bool {
+	if it.cur != nil {
+		it.pushLeft(it.cur.right)
+	}
+
+	for {
+		for len(it.stack) == 0 {
+			if it.roots[0] == nil && it.roots[1] == nil {
+				it.cur = nil
+				return false
+			}
+
+			root := it.roots[0]
+			it.roots[0], it.roots[1] = it.roots[1], nil
+			it.pushLeft(root)
+		}
+
+		it.cur = it.stack[len(it.stack)-1]
+		it.stack = it.stack[:len(it.stack)-1]
+
+		if !it.seek.IsValid() || compare(it.cur.cidr, it.seek) >= 0 {
+			return true
+		}
+
+		// pushed before the seek, skip cur, the left subtree is already done
+		it.pushLeft(it.cur.right)
+	}
+}
+
+// Prefix returns the prefix of the current entry.
+func (it *Iterator[V]) Prefix() netip.Prefix {
+	if it.cur == nil {
+		return netip.Prefix{}
+	}
+	return it.cur.cidr
+}
+
+// Value returns the value of the current entry.
+func (it *Iterator[V]) Value() (value V) {
+	if it.cur == nil {
+		return
+	}
+	return it.cur.value
+}
+
+// pushLeft pushes n and all its left descendants, the nodes before the seek are skipped.
+func (it *Iterator[V]) pushLeft(n *node[V]) {
+	for n != nil {
+		if it.seek.IsValid() && compare(n.cidr, it.seek) < 0 {
+			// n and its left subtree are before the seek
+			n = n.right
+			continue
+		}
+		it.stack = append(it.stack, n)
+		n = n.left
+	}
+}
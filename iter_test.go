@@ -0,0 +1,253 @@
+package cidrtree_test
+
+import (
+	"fmt"
+	"net/netip"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gaissmai/cidrtree"
+)
+
+func TestIter(t *testing.T) {
+	t.Parallel()
+
+	if new(cidrtree.Table[any]).Iter().Next() {
+		t.Errorf("Next() of empty table, got true, want false")
+	}
+
+	rtbl := new(cidrtree.Table[any])
+	for _, route := range routes {
+		rtbl.Insert(route.cidr, route.nextHop)
+	}
+
+	w := new(strings.Builder)
+	it := rtbl.Iter()
+	for it.Next() {
+		fmt.Fprintf(w, "%v (%v)\n", it.Prefix(), it.Value())
+	}
+
+	if w.String() != asStr {
+		t.Errorf("Iter, expected:\n%sgot:\n%s", asStr, w.String())
+	}
+
+	if it.Next() || it.Prefix().IsValid() || it.Value() != nil {
+		t.Errorf("exhausted iterator, got (%v, %v)", it.Prefix(), it.Value())
+	}
+}
+
+func TestIterWalk46(t *testing.T) {
+	t.Parallel()
+
+	rtbl := new(cidrtree.Table[any])
+	for _, route := range routes {
+		rtbl.Insert(route.cidr, route.nextHop)
+	}
+
+	// asStr is split at the first IPv6 entry
+	i := strings.Index(asStr, "::/0")
+	want4, want6 := asStr[:i], asStr[i:]
+
+	w4, w6 := new(strings.Builder), new(strings.Builder)
+	for it := rtbl.Iter4(); it.Next(); {
+		fmt.Fprintf(w4, "%v (%v)\n", it.Prefix(), it.Value())
+	}
+	for it := rtbl.Iter6(); it.Next(); {
+		fmt.Fprintf(w6, "%v (%v)\n", it.Prefix(), it.Value())
+	}
+
+	if w4.String() != want4 || w6.String() != want6 {
+		t.Errorf("Iter4, Iter6, expected:\n%s%sgot:\n%s%s", want4, want6, w4.String(), w6.String())
+	}
+
+	w4.Reset()
+	w6.Reset()
+	rtbl.Walk4(func(pfx netip.Prefix, value any) bool {
+		fmt.Fprintf(w4, "%v (%v)\n", pfx, value)
+		return true
+	})
+	rtbl.Walk6(func(pfx netip.Prefix, value any) bool {
+		fmt.Fprintf(w6, "%v (%v)\n", pfx, value)
+		return true
+	})
+
+	if w4.String() != want4 || w6.String() != want6 {
+		t.Errorf("Walk4, Walk6, expected:\n%s%sgot:\n%s%s", want4, want6, w4.String(), w6.String())
+	}
+
+	// abort
+	var count int
+	rtbl.Walk6(func(netip.Prefix, any) bool {
+		count++
+		return false
+	})
+	if count != 1 {
+		t.Errorf("Walk6 aborted, got %d calls, want 1", count)
+	}
+}
+
+func TestIterAdapters(t *testing.T) {
+	t.Parallel()
+
+	rtbl := new(cidrtree.Table[any])
+	for _, route := range routes {
+		rtbl.Insert(route.cidr, route.nextHop)
+	}
+
+	collect := func(it *cidrtree.Iterator[any]) []string {
+		var got []string
+		for it.Next() {
+			got = append(got, it.Prefix().String())
+		}
+		return got
+	}
+
+	tcs := []struct {
+		name string
+		it   *cidrtree.Iterator[any]
+		want []string
+	}{
+		{
+			name: "FilterByPrefix",
+			it:   rtbl.Iter().FilterByPrefix(mustPfx("10.0.0.0/8")),
+			want: []string{"10.0.0.0/8", "10.0.0.0/24", "10.0.1.0/24"},
+		},
+		{
+			name: "FilterByPrefix IPv6",
+			it:   rtbl.Iter().FilterByPrefix(mustPfx("2000::/3")),
+			want: []string{"2000::/3", "2001:db8::/32"},
+		},
+		{
+			name: "FilterByPrefix Limit",
+			it:   rtbl.Iter().FilterByPrefix(mustPfx("10.0.0.0/8")).Limit(2),
+			want: []string{"10.0.0.0/8", "10.0.0.0/24"},
+		},
+		{
+			name: "FilterByValue",
+			it: rtbl.Iter().FilterByValue(func(v any) bool {
+				return v.(netip.Addr).Is6()
+			}).Limit(3),
+			want: []string{"::/0", "::1/128", "2000::/3"},
+		},
+		{
+			name: "Limit 0",
+			it:   rtbl.Iter().Limit(0),
+			want: nil,
+		},
+		{
+			name: "FilterByPrefix no match",
+			it:   rtbl.Iter().FilterByPrefix(mustPfx("11.0.0.0/8")),
+			want: nil,
+		},
+	}
+
+	for _, tc := range tcs {
+		if got := collect(tc.it); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s, got %v, want %v", tc.name, got, tc.want)
+		}
+	}
+
+	// paginated export of the IPv4 entries
+	var pages [][]cidrtree.Entry[any]
+	it := rtbl.Iter4()
+	for chunk := it.Chunk(4); chunk != nil; chunk = it.Chunk(4) {
+		pages = append(pages, chunk)
+	}
+
+	if len(pages) != 3 || len(pages[0]) != 4 || len(pages[2]) != 1 {
+		t.Errorf("Chunk(4), got %v", pages)
+	}
+	if pages[2][0].Prefix != mustPfx("192.168.1.0/24") {
+		t.Errorf("Chunk(4), last entry, got %v, want %v", pages[2][0].Prefix, "192.168.1.0/24")
+	}
+}
+
+func TestIterFilterByPrefixSeek(t *testing.T) {
+	t.Parallel()
+
+	rtbl := new(cidrtree.Table[any])
+	for _, cidr := range shuffleFullTable(10_000) {
+		rtbl.Insert(cidr, nil)
+	}
+
+	// brute force with Walk
+	covered := func(pfx netip.Prefix, from int) []netip.Prefix {
+		var want []netip.Prefix
+		var i int
+		rtbl.Walk(func(p netip.Prefix, _ any) bool {
+			if i >= from && p.Bits() >= pfx.Bits() && pfx.Contains(p.Addr()) {
+				want = append(want, p)
+			}
+			i++
+			return true
+		})
+		return want
+	}
+
+	for _, probe := range shuffleFullTable(100) {
+		pfx, _ := probe.Addr().Prefix(max(0, probe.Bits()-8))
+
+		var got []netip.Prefix
+		for it := rtbl.Iter().FilterByPrefix(pfx); it.Next(); {
+			got = append(got, it.Prefix())
+		}
+		if want := covered(pfx, 0); !slices.Equal(got, want) {
+			t.Fatalf("FilterByPrefix(%v), got %d entries, want %d", pfx, len(got), len(want))
+		}
+
+		// seek while iterating, the already iterated entries are not repeated
+		it := rtbl.Iter()
+		for i := 0; i < 5_000; i++ {
+			it.Next()
+		}
+		got = got[:0]
+		for it.FilterByPrefix(pfx); it.Next(); {
+			got = append(got, it.Prefix())
+		}
+		if want := covered(pfx, 5_000); !slices.Equal(got, want) {
+			t.Fatalf("FilterByPrefix(%v) after 5000 entries, got %d entries, want %d", pfx, len(got), len(want))
+		}
+	}
+}
+
+func TestIterSnapshot(t *testing.T) {
+	t.Parallel()
+
+	var at cidrtree.Atomic[int]
+	cidrs := shuffleFullTable(10_000)
+	for i, cidr := range cidrs[:5_000] {
+		at.Insert(cidr, i)
+	}
+	snap := at.Load()
+	it := snap.Iter()
+
+	// concurrent writer
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i, cidr := range cidrs[5_000:] {
+			at.Insert(cidr, i)
+			at.Delete(cidrs[i])
+		}
+	}()
+
+	var got []netip.Prefix
+	for it.Next() {
+		got = append(got, it.Prefix())
+	}
+	wg.Wait()
+
+	var want []netip.Prefix
+	snap.Walk(func(pfx netip.Prefix, _ int) bool {
+		want = append(want, pfx)
+		return true
+	})
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Iter under concurrent writes, got %d entries, want %d", len(got), len(want))
+	}
+}
@@ -0,0 +1,141 @@
+package cidrtree
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/netip"
+)
+
+// jsonNode is the JSON representation of a CIDR with value and subnets,
+// the nesting is the CIDR containment as for [Table.Fprint].
+type jsonNode[V any] struct {
+	Cidr     netip.Prefix  `json:"cidr"`
+	Value    V             `json:"value"`
+	Children []jsonNode[V] `json:"children,omitempty"`
+}
+
+// MarshalJSON implements the [json.Marshaler] interface.
+// The table is encoded as a JSON array of the top-level CIDRs, every CIDR with its value
+// and the children array of the covered CIDRs, mirroring the hierarchy of [Table.Fprint].
+//
+//	[{"cidr":"10.0.0.0/8","value":"A","children":[{"cidr":"10.0.1.0/24","value":"B"}]}]
+func (t Table[V]) MarshalJSON() ([]byte, error) {
+	roots := make([]jsonNode[V], 0)
+	roots = append(roots, t.root4.jsonNodes()...)
+	roots = append(roots, t.root6.jsonNodes()...)
+
+	return json.Marshal(roots)
+}
+
+// jsonNodes returns the nested JSON nodes of the treap.
+func (n *node[V]) jsonNodes() []jsonNode[V] {
+	if n == nil {
+		return nil
+	}
+
+	// pcm = parent-child-mapping
+	var pcm parentChildsMap[V]
+
+	// init map
+	pcm.pcMap = make(map[*node[V]][]*node[V])
+
+	pcm = n.buildParentChildsMap(pcm)
+
+	// start recursion with root
+	var root *node[V]
+	return root.walkAndJSON(pcm)
+}
+
+func (n *node[V]) walkAndJSON(pcm parentChildsMap[V]) []jsonNode[V] {
+	var nodes []jsonNode[V]
+	for _, child := range pcm.pcMap[n] {
+		nodes = append(nodes, jsonNode[V]{
+			Cidr:     child.cidr,
+			Value:    child.value,
+			Children: child.walkAndJSON(pcm),
+		})
+	}
+	return nodes
+}
+
+// jsonVersion is the current version of the versioned JSON snapshot format.
+//
+//	0: bare array of the top-level CIDRs, the format of MarshalJSON
+//	1: {"version":1,"table":[...]}, the array of version 0 with a version header
+const jsonVersion = 1
+
+// jsonSnapshot is the versioned JSON snapshot format, see [FileStore].
+type jsonSnapshot struct {
+	Version int             `json:"version"`
+	Table   json.RawMessage `json:"table"`
+}
+
+// marshalSnapshot returns the table in the current versioned JSON snapshot format.
+func (t Table[V]) marshalSnapshot() ([]byte, error) {
+	data, err := t.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(jsonSnapshot{Version: jsonVersion, Table: data})
+}
+
+// UnmarshalJSON implements the [json.Unmarshaler] interface.
+// It accepts the nested JSON form of [Table.MarshalJSON], the table is replaced by the decoded CIDRs.
+// The containment is validated, every child CIDR must be strictly covered by its parent CIDR.
+//
+// The versioned snapshots of [FileStore] are also accepted, all versions written
+// by older versions of this package are migrated.
+func (t *Table[V]) UnmarshalJSON(data []byte) error {
+	t.mustNotBeFrozen()
+
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '{' {
+		var snap jsonSnapshot
+		if err := json.Unmarshal(data, &snap); err != nil {
+			return err
+		}
+
+		switch snap.Version {
+		case 1:
+			data = snap.Table // same nodes as version 0
+		default:
+			return fmt.Errorf("cidrtree: unsupported JSON snapshot version %d", snap.Version)
+		}
+	}
+
+	var roots []jsonNode[V]
+	if err := json.Unmarshal(data, &roots); err != nil {
+		return err
+	}
+
+	var nt Table[V]
+	if err := nt.insertJSON(roots, netip.Prefix{}); err != nil {
+		return err
+	}
+
+	*t = nt
+	return nil
+}
+
+// insertJSON inserts the nested JSON nodes rec-descent, parent is invalid for the top-level nodes.
+func (t *Table[V]) insertJSON(nodes []jsonNode[V], parent netip.Prefix) error {
+	for _, jn := range nodes {
+		if !jn.Cidr.IsValid() {
+			return fmt.Errorf("cidrtree: invalid CIDR in JSON input")
+		}
+		pfx := jn.Cidr.Masked()
+
+		if parent.IsValid() && !(parent.Bits() < pfx.Bits() && parent.Contains(pfx.Addr())) {
+			return fmt.Errorf("cidrtree: CIDR %v is not covered by parent %v", jn.Cidr, parent)
+		}
+
+		t.Insert(pfx, jn.Value)
+
+		// rec-descent
+		if err := t.insertJSON(jn.Children, pfx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package cidrtree_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/gaissmai/cidrtree"
+)
+
+const asJSONStr = `[{"cidr":"10.0.0.0/8","value":1,"children":[{"cidr":"10.0.0.0/24","value":2},{"cidr":"10.0.1.0/24","value":3}]},` +
+	`{"cidr":"127.0.0.1/32","value":4},` +
+	`{"cidr":"::/0","value":5,"children":[{"cidr":"::1/128","value":6}]}]`
+
+func TestMarshalJSON(t *testing.T) {
+	t.Parallel()
+
+	rtbl := new(cidrtree.Table[int])
+	rtbl.Insert(mustPfx("10.0.0.0/8"), 1)
+	rtbl.Insert(mustPfx("10.0.0.0/24"), 2)
+	rtbl.Insert(mustPfx("10.0.1.0/24"), 3)
+	rtbl.Insert(mustPfx("127.0.0.1/32"), 4)
+	rtbl.Insert(mustPfx("::/0"), 5)
+	rtbl.Insert(mustPfx("::1/128"), 6)
+
+	buf, err := json.Marshal(rtbl)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(buf) != asJSONStr {
+		t.Errorf("MarshalJSON\nwant:\n%s\ngot:\n%s", asJSONStr, buf)
+	}
+
+	var zeroTable cidrtree.Table[any]
+	buf, err = json.Marshal(zeroTable)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(buf) != "[]" {
+		t.Errorf("MarshalJSON of zero value, got %s, want []", buf)
+	}
+}
+
+func TestUnmarshalJSON(t *testing.T) {
+	t.Parallel()
+
+	rtbl := new(cidrtree.Table[int])
+	if err := json.Unmarshal([]byte(asJSONStr), rtbl); err != nil {
+		t.Fatal(err)
+	}
+
+	buf, err := json.Marshal(rtbl)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(buf) != asJSONStr {
+		t.Errorf("UnmarshalJSON, round trip\nwant:\n%s\ngot:\n%s", asJSONStr, buf)
+	}
+
+	// round trip the big table
+	rtbl2 := new(cidrtree.Table[any])
+	for _, cidr := range shuffleFullTable(10_000) {
+		rtbl2.Insert(cidr, cidr.Bits())
+	}
+
+	buf, err = json.Marshal(rtbl2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rtbl3 := new(cidrtree.Table[any])
+	if err := json.Unmarshal(buf, rtbl3); err != nil {
+		t.Fatal(err)
+	}
+
+	buf3, _ := json.Marshal(rtbl3)
+	if string(buf) != string(buf3) {
+		t.Error("UnmarshalJSON, round trip of big table differs")
+	}
+
+	bad := []string{
+		`[{"cidr":"10.0.0.0/24","value":1,"children":[{"cidr":"10.0.0.0/8","value":2}]}]`,
+		`[{"cidr":"10.0.0.0/24","value":1,"children":[{"cidr":"10.0.0.0/24","value":2}]}]`,
+		`[{"cidr":"10.0.0.0/8","value":1,"children":[{"cidr":"11.0.0.0/24","value":2}]}]`,
+		`[{"cidr":"10.0.0.0/8","value":1,"children":[{"cidr":"::/0","value":2}]}]`,
+		`[{"value":1}]`,
+		`[{"cidr":"10.0.0.0/33"}]`,
+		`{}`,
+	}
+
+	for _, s := range bad {
+		rtbl := new(cidrtree.Table[int])
+		if err := json.Unmarshal([]byte(s), rtbl); err == nil {
+			t.Errorf("UnmarshalJSON(%s), expected error", s)
+		}
+	}
+}
+
+func TestUnmarshalJSONVersion(t *testing.T) {
+	t.Parallel()
+
+	legacy := new(cidrtree.Table[int])
+	if err := json.Unmarshal([]byte(asJSONStr), legacy); err != nil {
+		t.Fatal(err)
+	}
+
+	versioned := new(cidrtree.Table[int])
+	if err := json.Unmarshal([]byte(` {"version":1,"table":`+asJSONStr+`}`), versioned); err != nil {
+		t.Fatal(err)
+	}
+
+	if versioned.String() != legacy.String() {
+		t.Errorf("UnmarshalJSON of version 1, got:\n%v\nwant:\n%v", versioned, legacy)
+	}
+
+	for _, data := range []string{`{"version":2,"table":[]}`, `{"version":0}`, `{"version":1,"table":{}}`} {
+		if err := json.Unmarshal([]byte(data), new(cidrtree.Table[int])); err == nil {
+			t.Errorf("UnmarshalJSON(%s), expected error", data)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package cidrtree
+
+import "net/netip"
+
+// LazyTable is a routing table with lazy deletion, e.g. for BGP churn storms with many withdrawals.
+//
+// Delete marks the entry with a tombstone instead of restructuring the treap with split and join.
+// Lookups skip the dead entries, the next-less-specific match is taken instead. Compact removes the
+// dead entries, either explicitly or automatically if the tombstones exceed CompactRatio of the entries.
+// The zero value is ready to use, compaction is then only explicit.
+type LazyTable[V any] struct {
+	table Table[V]
+	dead  map[netip.Prefix]struct{}
+
+	// CompactRatio of tombstones to entries, Delete compacts automatically above it, 0 disables.
+	CompactRatio float64
+}
+
+// Insert adds pfx with value, a tombstone for pfx is removed, see [Table.Insert].
+func (l *LazyTable[V]) Insert(pfx netip.Prefix, value V) {
+	pfx = pfx.Masked() // always canonicalize!
+
+	delete(l.dead, pfx)
+	l.table.Insert(pfx, value)
+}
+
+// Delete marks pfx as dead, returns false if pfx isn't in the table or is already dead.
+func (l *LazyTable[V]) Delete(pfx netip.Prefix) bool {
+	pfx = pfx.Masked() // always canonicalize!
+
+	if _, ok := l.table.get(pfx); !ok || l.isDead(pfx) {
+		return false
+	}
+
+	if l.dead == nil {
+		l.dead = make(map[netip.Prefix]struct{})
+	}
+	l.dead[pfx] = struct{}{}
+
+	if l.CompactRatio > 0 && float64(len(l.dead)) > l.CompactRatio*float64(l.table.Size()) {
+		l.Compact()
+	}
+	return true
+}
+
+// Compact removes all dead entries from the treap, returns the number of removed entries.
+func (l *LazyTable[V]) Compact() int {
+	n := len(l.dead)
+	for pfx := range l.dead {
+		l.table.Delete(pfx)
+	}
+	l.dead = nil
+	return n
+}
+
+// Lookup returns the longest-prefix-match for ip, skipping the dead entries, see [Table.Lookup].
+func (l *LazyTable[V]) Lookup(ip netip.Addr) (lpm netip.Prefix, value V, ok bool) {
+	if len(l.dead) == 0 {
+		return l.table.Lookup(ip)
+	}
+	return l.table.LookupFunc(ip, l.alive)
+}
+
+// LookupPrefix returns the longest-prefix-match for pfx, skipping the dead entries, see [Table.LookupPrefix].
+func (l *LazyTable[V]) LookupPrefix(pfx netip.Prefix) (lpm netip.Prefix, value V, ok bool) {
+	if len(l.dead) == 0 {
+		return l.table.LookupPrefix(pfx)
+	}
+
+	pfx = pfx.Masked() // always canonicalize!
+	if value, ok := l.table.get(pfx); ok && !l.isDead(pfx) {
+		return pfx, value, true
+	}
+
+	// from most to least specific
+	covering := l.table.supernets(pfx)
+	for i := len(covering) - 1; i >= 0; i-- {
+		if n := covering[i]; !l.isDead(n.cidr) {
+			return n.cidr, n.value, true
+		}
+	}
+	return
+}
+
+// Walk iterates the live entries in ascending order, see [Table.Walk].
+func (l *LazyTable[V]) Walk(cb func(pfx netip.Prefix, value V) bool) {
+	l.table.Walk(func(pfx netip.Prefix, value V) bool {
+		if l.isDead(pfx) {
+			return true
+		}
+		return cb(pfx, value)
+	})
+}
+
+// Size returns the number of live entries.
+func (l *LazyTable[V]) Size() int {
+	return l.table.Size() - len(l.dead)
+}
+
+// Dead returns the number of tombstones.
+func (l *LazyTable[V]) Dead() int {
+	return len(l.dead)
+}
+
+func (l *LazyTable[V]) isDead(pfx netip.Prefix) bool {
+	_, ok := l.dead[pfx]
+	return ok
+}
+
+func (l *LazyTable[V]) alive(pfx netip.Prefix, _ V) bool {
+	return !l.isDead(pfx)
+}
@@ -0,0 +1,96 @@
+package cidrtree_test
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/gaissmai/cidrtree"
+)
+
+func TestLazyTable(t *testing.T) {
+	t.Parallel()
+
+	var lazy cidrtree.LazyTable[int]
+	rtbl := new(cidrtree.Table[int])
+
+	cidrs := shuffleFullTable(10_000)
+	cidrs = append(cidrs, mustPfx("0.0.0.0/0"), mustPfx("::/0"))
+	for i, cidr := range cidrs {
+		lazy.Insert(cidr, i)
+		rtbl.Insert(cidr, i)
+	}
+
+	for _, cidr := range cidrs[:3_000] {
+		if lazy.Delete(cidr) != rtbl.Delete(cidr) {
+			t.Fatalf("Delete(%v) differs", cidr)
+		}
+	}
+	if lazy.Delete(cidrs[0]) {
+		t.Errorf("Delete of dead entry, got true, want false")
+	}
+
+	// revive some
+	for i, cidr := range cidrs[:100] {
+		lazy.Insert(cidr, i)
+		rtbl.Insert(cidr, i)
+	}
+
+	check := func(stage string) {
+		t.Helper()
+		if lazy.Size() != rtbl.Size() {
+			t.Fatalf("%s: Size(), got %d, want %d", stage, lazy.Size(), rtbl.Size())
+		}
+
+		for _, cidr := range cidrs {
+			gotLPM, gotVal, gotOK := lazy.Lookup(cidr.Addr())
+			wantLPM, wantVal, wantOK := rtbl.Lookup(cidr.Addr())
+			if gotLPM != wantLPM || gotVal != wantVal || gotOK != wantOK {
+				t.Fatalf("%s: Lookup(%v), got (%v, %v, %v), want (%v, %v, %v)", stage, cidr.Addr(), gotLPM, gotVal, gotOK, wantLPM, wantVal, wantOK)
+			}
+
+			gotLPM, _, gotOK = lazy.LookupPrefix(cidr)
+			wantLPM, _, wantOK = rtbl.LookupPrefix(cidr)
+			if gotLPM != wantLPM || gotOK != wantOK {
+				t.Fatalf("%s: LookupPrefix(%v), got (%v, %v), want (%v, %v)", stage, cidr, gotLPM, gotOK, wantLPM, wantOK)
+			}
+		}
+
+		var n int
+		lazy.Walk(func(netip.Prefix, int) bool {
+			n++
+			return true
+		})
+		if n != rtbl.Size() {
+			t.Fatalf("%s: Walk, got %d entries, want %d", stage, n, rtbl.Size())
+		}
+	}
+
+	check("lazy")
+
+	dead := lazy.Dead()
+	if n := lazy.Compact(); n != dead || lazy.Dead() != 0 {
+		t.Errorf("Compact(), got %d, want %d, tombstones left: %d", n, dead, lazy.Dead())
+	}
+	check("compacted")
+}
+
+func TestLazyTableCompactRatio(t *testing.T) {
+	t.Parallel()
+
+	lazy := cidrtree.LazyTable[int]{CompactRatio: 0.25}
+	for i := 0; i < 8; i++ {
+		lazy.Insert(netip.PrefixFrom(netip.AddrFrom4([4]byte{10, byte(i), 0, 0}), 16), i)
+	}
+
+	lazy.Delete(mustPfx("10.0.0.0/16"))
+	lazy.Delete(mustPfx("10.1.0.0/16"))
+	if lazy.Dead() != 2 {
+		t.Errorf("Dead(), got %d, want %d", lazy.Dead(), 2)
+	}
+
+	// 3 > 0.25 * 8, compacted
+	lazy.Delete(mustPfx("10.2.0.0/16"))
+	if lazy.Dead() != 0 || lazy.Size() != 5 {
+		t.Errorf("auto Compact, got (dead %d, size %d), want (0, 5)", lazy.Dead(), lazy.Size())
+	}
+}
@@ -0,0 +1,79 @@
+package cidrtree
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// FprintMarkdown writes the ordered CIDR tree as nested markdown list to w, e.g. for route plans
+// in wikis and design docs. The values are rendered with render, the markdown syntax characters
+// are escaped. If render is nil, the values are rendered with the default format of the fmt package.
+//
+// The hierarchy is the same as for [Table.Fprint], one list for each IP version.
+func (t Table[V]) FprintMarkdown(w io.Writer, render func(V) string) error {
+	if render == nil {
+		render = func(v V) string { return fmt.Sprint(v) }
+	}
+
+	if err := t.root4.fprintMarkdown(w, render); err != nil {
+		return err
+	}
+	if t.root4 != nil && t.root6 != nil {
+		// a blank line separates the lists
+		if _, err := fmt.Fprint(w, "\n"); err != nil {
+			return err
+		}
+	}
+	if err := t.root6.fprintMarkdown(w, render); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (n *node[V]) fprintMarkdown(w io.Writer, render func(V) string) error {
+	if n == nil {
+		return nil
+	}
+
+	// pcm = parent-child-mapping
+	var pcm parentChildsMap[V]
+
+	// init map
+	pcm.pcMap = make(map[*node[V]][]*node[V])
+
+	pcm = n.buildParentChildsMap(pcm)
+
+	if len(pcm.pcMap) == 0 {
+		return nil
+	}
+
+	// start recursion with root and empty indentation
+	var root *node[V]
+	return root.walkAndMarkdown(w, pcm, render, "")
+}
+
+func (n *node[V]) walkAndMarkdown(w io.Writer, pcm parentChildsMap[V], render func(V) string, indent string) error {
+	for _, child := range pcm.pcMap[n] {
+		if _, err := fmt.Fprintf(w, "%s- `%v` %s\n", indent, child.cidr, escapeMarkdown(render(child.value))); err != nil {
+			return err
+		}
+
+		// recdescent down
+		if err := child.walkAndMarkdown(w, pcm, render, indent+"  "); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// markdownEscaper escapes the markdown syntax characters in inline text.
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", `*`, `\*`, `_`, `\_`, `[`, `\[`, `]`, `\]`,
+	`<`, `\<`, `>`, `\>`, `|`, `\|`, `#`, `\#`,
+)
+
+func escapeMarkdown(s string) string {
+	return markdownEscaper.Replace(s)
+}
@@ -0,0 +1,50 @@
+package cidrtree_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gaissmai/cidrtree"
+)
+
+const asMarkdownStr = "- `10.0.0.0/8` core\\_net\n" +
+	"  - `10.0.0.0/24` \\*mgmt\\*\n" +
+	"  - `10.0.1.0/24` lab\n" +
+	"- `127.0.0.1/32` lo\n" +
+	"\n" +
+	"- `::1/128` lo\n"
+
+func TestFprintMarkdown(t *testing.T) {
+	t.Parallel()
+
+	rtbl := new(cidrtree.Table[string])
+	rtbl.Insert(mustPfx("10.0.0.0/8"), "core_net")
+	rtbl.Insert(mustPfx("10.0.0.0/24"), "*mgmt*")
+	rtbl.Insert(mustPfx("10.0.1.0/24"), "lab")
+	rtbl.Insert(mustPfx("127.0.0.1/32"), "lo")
+	rtbl.Insert(mustPfx("::1/128"), "lo")
+
+	w := new(strings.Builder)
+	if err := rtbl.FprintMarkdown(w, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if w.String() != asMarkdownStr {
+		t.Errorf("FprintMarkdown\nwant:\n%sgot:\n%s", asMarkdownStr, w.String())
+	}
+
+	w.Reset()
+	if err := rtbl.FprintMarkdown(w, strings.ToUpper); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(w.String(), "  - `10.0.1.0/24` LAB\n") {
+		t.Errorf("FprintMarkdown with render, got:\n%s", w.String())
+	}
+
+	var zeroTable cidrtree.Table[any]
+	w.Reset()
+	if err := zeroTable.FprintMarkdown(w, nil); err != nil || w.String() != "" {
+		t.Errorf("FprintMarkdown of zero value, got %q, %v, want \"\"", w.String(), err)
+	}
+}
@@ -0,0 +1,101 @@
+package cidrtree
+
+import "net/netip"
+
+// SGTable is a multicast policy table with (S,G) and (*,G) entries, a table of group prefixes
+// with a table of source prefixes per group, e.g. for RPF and policy lookups of multicast flows.
+// The zero value is ready to use, only the mutable API is supported.
+type SGTable[V any] struct {
+	groups Table[*sgEntry[V]]
+}
+
+// sgEntry holds the (S,G) entries of a group prefix and the optional (*,G) entry.
+type sgEntry[V any] struct {
+	sources Table[V]
+	star    V
+	hasStar bool
+}
+
+// Insert adds the entry (source, group) with value, an invalid source is the (*,G) entry for all sources.
+// The group must be a multicast prefix and of the same IP version as a valid source,
+// returns false otherwise.
+func (st *SGTable[V]) Insert(source, group netip.Prefix, value V) bool {
+	group = group.Masked() // always canonicalize!
+	if !group.IsValid() || !group.Addr().IsMulticast() {
+		return false
+	}
+	if source.IsValid() && source.Addr().Is4() != group.Addr().Is4() {
+		return false
+	}
+
+	e, ok := st.groups.get(group)
+	if !ok {
+		e = new(sgEntry[V])
+		st.groups.Insert(group, e)
+	}
+
+	if !source.IsValid() {
+		e.star, e.hasStar = value, true
+		return true
+	}
+	e.sources.Insert(source, value)
+	return true
+}
+
+// Delete removes the entry (source, group), an invalid source is the (*,G) entry.
+// Returns false if the entry isn't in the table.
+func (st *SGTable[V]) Delete(source, group netip.Prefix) bool {
+	group = group.Masked() // always canonicalize!
+
+	e, ok := st.groups.get(group)
+	if !ok {
+		return false
+	}
+
+	if !source.IsValid() {
+		if !e.hasStar {
+			return false
+		}
+		var zero V
+		e.star, e.hasStar = zero, false
+	} else if !e.sources.Delete(source) {
+		return false
+	}
+
+	if !e.hasStar && e.sources.IsEmpty() {
+		st.groups.Delete(group)
+	}
+	return true
+}
+
+// Lookup classifies the multicast flow (src, group). The precedence is:
+//
+//   - the most specific group prefix wins,
+//   - within this group prefix the most specific (S,G) source prefix,
+//   - then the (*,G) entry of the group prefix with an invalid source prefix,
+//   - without any match the next-less-specific group prefix is tried.
+//
+// If group isn't a multicast address or src and group are of different IP versions, ok is false.
+func (st *SGTable[V]) Lookup(src, group netip.Addr) (sourcePfx, groupPfx netip.Prefix, value V, ok bool) {
+	if !group.IsMulticast() || src.Is4() != group.Is4() {
+		return
+	}
+
+	groupPfx, _, ok = st.groups.LookupFunc(group, func(_ netip.Prefix, e *sgEntry[V]) bool {
+		if lpm, v, found := e.sources.Lookup(src); found {
+			sourcePfx, value = lpm, v
+			return true
+		}
+		if e.hasStar {
+			sourcePfx, value = netip.Prefix{}, e.star
+			return true
+		}
+		return false
+	})
+
+	if !ok {
+		var zero V
+		return netip.Prefix{}, netip.Prefix{}, zero, false
+	}
+	return sourcePfx, groupPfx, value, true
+}
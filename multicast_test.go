@@ -0,0 +1,80 @@
+package cidrtree_test
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/gaissmai/cidrtree"
+)
+
+func TestSGTable(t *testing.T) {
+	t.Parallel()
+
+	var st cidrtree.SGTable[string]
+
+	var star netip.Prefix // (*,G)
+	st.Insert(star, mustPfx("224.0.0.0/4"), "default")
+	st.Insert(mustPfx("10.0.0.0/8"), mustPfx("232.0.0.0/8"), "ssm-10")
+	st.Insert(mustPfx("10.1.0.0/16"), mustPfx("232.0.0.0/8"), "ssm-10.1")
+	st.Insert(star, mustPfx("239.1.0.0/16"), "scoped")
+	st.Insert(mustPfx("2001:db8::/32"), mustPfx("ff3e::/16"), "ssm6")
+
+	if ok := st.Insert(star, mustPfx("10.0.0.0/8"), "unicast"); ok {
+		t.Errorf("Insert unicast group, got %v, want false", ok)
+	}
+	if ok := st.Insert(mustPfx("2001:db8::/32"), mustPfx("232.0.0.0/8"), "mixed"); ok {
+		t.Errorf("Insert mixed IP versions, got %v, want false", ok)
+	}
+
+	tcs := []struct {
+		src, group string
+		wantSrc    string
+		wantGroup  string
+		wantValue  string
+		wantOK     bool
+	}{
+		{"10.1.2.3", "232.1.1.1", "10.1.0.0/16", "232.0.0.0/8", "ssm-10.1", true},
+		{"10.2.2.3", "232.1.1.1", "10.0.0.0/8", "232.0.0.0/8", "ssm-10", true},
+		// no (S,G) and no (*,G) in 232/8, the less specific group
+		{"192.0.2.1", "232.1.1.1", "", "224.0.0.0/4", "default", true},
+		{"192.0.2.1", "239.1.2.3", "", "239.1.0.0/16", "scoped", true},
+		{"2001:db8::1", "ff3e::1234", "2001:db8::/32", "ff3e::/16", "ssm6", true},
+		{"2001:db9::1", "ff3e::1234", "", "", "", false},
+		{"10.1.2.3", "10.1.1.1", "", "", "", false},     // unicast group
+		{"2001:db8::1", "232.1.1.1", "", "", "", false}, // mixed IP versions
+	}
+
+	for _, tc := range tcs {
+		src, group, value, ok := st.Lookup(mustAddr(tc.src), mustAddr(tc.group))
+
+		wantSrc, wantGroup := netip.Prefix{}, netip.Prefix{}
+		if tc.wantSrc != "" {
+			wantSrc = mustPfx(tc.wantSrc)
+		}
+		if tc.wantGroup != "" {
+			wantGroup = mustPfx(tc.wantGroup)
+		}
+
+		if src != wantSrc || group != wantGroup || value != tc.wantValue || ok != tc.wantOK {
+			t.Errorf("Lookup(%v, %v), got (%v, %v, %q, %v), want (%v, %v, %q, %v)",
+				tc.src, tc.group, src, group, value, ok, wantSrc, wantGroup, tc.wantValue, tc.wantOK)
+		}
+	}
+
+	if !st.Delete(mustPfx("10.1.0.0/16"), mustPfx("232.0.0.0/8")) {
+		t.Errorf("Delete (S,G), got false, want true")
+	}
+	if st.Delete(star, mustPfx("232.0.0.0/8")) {
+		t.Errorf("Delete missing (*,G), got true, want false")
+	}
+	if !st.Delete(star, mustPfx("224.0.0.0/4")) {
+		t.Errorf("Delete (*,G), got false, want true")
+	}
+
+	if _, _, value, _ := st.Lookup(mustAddr("10.1.2.3"), mustAddr("232.1.1.1")); value != "ssm-10" {
+		t.Errorf("Lookup after Delete, got %q, want %q", value, "ssm-10")
+	}
+	if _, _, _, ok := st.Lookup(mustAddr("192.0.2.1"), mustAddr("232.1.1.1")); ok {
+		t.Errorf("Lookup after Delete (*,G), got %v, want false", ok)
+	}
+}
@@ -0,0 +1,61 @@
+package cidrtree
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+
+	"github.com/gaissmai/extnetip"
+)
+
+// OverlapError is returned by [Table.InsertNoOverlap], the prefix overlaps existing entries.
+type OverlapError struct {
+	Prefix    netip.Prefix
+	Conflicts []netip.Prefix // all overlapping entries in ascending order
+}
+
+// Error implements the error interface.
+func (e *OverlapError) Error() string {
+	conflicts := make([]string, 0, len(e.Conflicts))
+	for _, pfx := range e.Conflicts {
+		conflicts = append(conflicts, pfx.String())
+	}
+	return fmt.Sprintf("cidrtree: %v overlaps %s", e.Prefix, strings.Join(conflicts, ", "))
+}
+
+// InsertNoOverlap adds pfx to the table like Insert, but only if pfx doesn't overlap
+// with any existing entry, e.g. for address-plan management where overlaps are always mistakes.
+// An equal, covering or covered entry is a conflict, the returned [*OverlapError] lists all conflicts.
+func (t *Table[V]) InsertNoOverlap(pfx netip.Prefix, value V) error {
+	t.mustNotBeFrozen()
+	pfx = pfx.Masked() // always canonicalize!
+
+	if conflicts := t.overlaps(pfx); len(conflicts) > 0 {
+		return &OverlapError{Prefix: pfx, Conflicts: conflicts}
+	}
+
+	t.Insert(pfx, value)
+	return nil
+}
+
+// overlaps returns all entries equal to, covering or covered by the canonical pfx, in ascending order.
+func (t Table[V]) overlaps(pfx netip.Prefix) []netip.Prefix {
+	var conflicts []netip.Prefix
+	for _, n := range t.supernets(pfx) {
+		conflicts = append(conflicts, n.cidr)
+	}
+
+	root := t.root6
+	if pfx.Addr().Is4() {
+		root = t.root4
+	}
+
+	// pfx itself and all subnets
+	_, last := extnetip.Range(pfx)
+	root.walkRange(pfx, netip.PrefixFrom(last, last.BitLen()), func(n *node[V]) bool {
+		conflicts = append(conflicts, n.cidr)
+		return true
+	})
+
+	return conflicts
+}
@@ -0,0 +1,59 @@
+package cidrtree_test
+
+import (
+	"errors"
+	"net/netip"
+	"reflect"
+	"testing"
+
+	"github.com/gaissmai/cidrtree"
+)
+
+func TestInsertNoOverlap(t *testing.T) {
+	t.Parallel()
+
+	rtbl := new(cidrtree.Table[int])
+	for _, s := range []string{"10.0.0.0/16", "10.1.0.0/24", "10.1.2.0/24", "10.2.0.0/16", "::/1"} {
+		if err := rtbl.InsertNoOverlap(mustPfx(s), 0); err != nil {
+			t.Fatalf("InsertNoOverlap(%v), unexpected error: %v", s, err)
+		}
+	}
+
+	tests := []struct {
+		pfx  string
+		want []string
+	}{
+		{"10.0.0.0/16", []string{"10.0.0.0/16"}},
+		{"10.0.1.0/24", []string{"10.0.0.0/16"}},
+		{"10.0.0.0/8", []string{"10.0.0.0/16", "10.1.0.0/24", "10.1.2.0/24", "10.2.0.0/16"}},
+		{"10.1.0.0/22", []string{"10.1.0.0/24", "10.1.2.0/24"}},
+		{"::/0", []string{"::/1"}},
+	}
+
+	for _, tt := range tests {
+		err := rtbl.InsertNoOverlap(mustPfx(tt.pfx), 1)
+
+		var oe *cidrtree.OverlapError
+		if !errors.As(err, &oe) {
+			t.Errorf("InsertNoOverlap(%v), got %v, want OverlapError", tt.pfx, err)
+			continue
+		}
+
+		var want []netip.Prefix
+		for _, s := range tt.want {
+			want = append(want, mustPfx(s))
+		}
+		if !reflect.DeepEqual(oe.Conflicts, want) {
+			t.Errorf("InsertNoOverlap(%v), got conflicts %v, want %v", tt.pfx, oe.Conflicts, want)
+		}
+	}
+
+	err := rtbl.InsertNoOverlap(mustPfx("10.1.0.0/23"), 1)
+	if want := "cidrtree: 10.1.0.0/23 overlaps 10.1.0.0/24"; err == nil || err.Error() != want {
+		t.Errorf("InsertNoOverlap, got error %v, want %v", err, want)
+	}
+
+	if rtbl.Size() != 5 {
+		t.Errorf("InsertNoOverlap changed the table, got size %d, want %d", rtbl.Size(), 5)
+	}
+}
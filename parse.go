@@ -0,0 +1,48 @@
+package cidrtree
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+// InsertString parses the prefix and inserts it with value, see [Table.Insert].
+func (t *Table[V]) InsertString(pfx string, value V) error {
+	p, err := netip.ParsePrefix(pfx)
+	if err != nil {
+		return fmt.Errorf("cidrtree: %w", err)
+	}
+
+	t.Insert(p, value)
+	return nil
+}
+
+// LookupString parses s as ip or, if it contains a slash, as prefix and returns the
+// longest-prefix-match, see [Table.Lookup] and [Table.LookupPrefix].
+func (t Table[V]) LookupString(s string) (lpm netip.Prefix, value V, ok bool, err error) {
+	if strings.Contains(s, "/") {
+		pfx, err := netip.ParsePrefix(s)
+		if err != nil {
+			return lpm, value, false, fmt.Errorf("cidrtree: %w", err)
+		}
+		lpm, value, ok = t.LookupPrefix(pfx)
+		return lpm, value, ok, nil
+	}
+
+	ip, err := netip.ParseAddr(s)
+	if err != nil {
+		return lpm, value, false, fmt.Errorf("cidrtree: %w", err)
+	}
+	lpm, value, ok = t.Lookup(ip)
+	return lpm, value, ok, nil
+}
+
+// DeleteString parses the prefix and deletes it, see [Table.Delete].
+func (t *Table[V]) DeleteString(pfx string) (bool, error) {
+	p, err := netip.ParsePrefix(pfx)
+	if err != nil {
+		return false, fmt.Errorf("cidrtree: %w", err)
+	}
+
+	return t.Delete(p), nil
+}
@@ -0,0 +1,65 @@
+package cidrtree_test
+
+import (
+	"testing"
+
+	"github.com/gaissmai/cidrtree"
+)
+
+func TestStringAPI(t *testing.T) {
+	t.Parallel()
+
+	rtbl := new(cidrtree.Table[int])
+
+	for i, s := range []string{"10.0.0.0/8", "10.0.1.0/24", "::/0"} {
+		if err := rtbl.InsertString(s, i); err != nil {
+			t.Errorf("InsertString(%q), unexpected error: %v", s, err)
+		}
+	}
+
+	for _, s := range []string{"", "10.0.0.0", "10.0.0.0/33", "foo/8"} {
+		if err := rtbl.InsertString(s, 0); err == nil {
+			t.Errorf("InsertString(%q), expected error", s)
+		}
+	}
+
+	tests := []struct {
+		s       string
+		wantLPM string
+		wantVal int
+		ok      bool
+	}{
+		{"10.0.1.1", "10.0.1.0/24", 1, true},
+		{"10.0.2.1", "10.0.0.0/8", 0, true},
+		{"10.0.0.0/23", "10.0.0.0/8", 0, true},
+		{"2001:db8::1", "::/0", 2, true},
+		{"11.0.0.1", "", 0, false},
+	}
+
+	for _, tt := range tests {
+		lpm, val, ok, err := rtbl.LookupString(tt.s)
+		if err != nil {
+			t.Errorf("LookupString(%q), unexpected error: %v", tt.s, err)
+			continue
+		}
+		if ok != tt.ok || val != tt.wantVal || ok && lpm.String() != tt.wantLPM {
+			t.Errorf("LookupString(%q), got (%v, %v, %v), want (%v, %v, %v)", tt.s, lpm, val, ok, tt.wantLPM, tt.wantVal, tt.ok)
+		}
+	}
+
+	for _, s := range []string{"", "10.0.0", "10.0.0.0/33"} {
+		if _, _, _, err := rtbl.LookupString(s); err == nil {
+			t.Errorf("LookupString(%q), expected error", s)
+		}
+	}
+
+	if ok, err := rtbl.DeleteString("10.0.1.0/24"); !ok || err != nil {
+		t.Errorf("DeleteString(%q), got (%v, %v), want (true, nil)", "10.0.1.0/24", ok, err)
+	}
+	if ok, err := rtbl.DeleteString("10.0.1.0/24"); ok || err != nil {
+		t.Errorf("DeleteString(%q), got (%v, %v), want (false, nil)", "10.0.1.0/24", ok, err)
+	}
+	if _, err := rtbl.DeleteString("10.0.1.0"); err == nil {
+		t.Errorf("DeleteString(%q), expected error", "10.0.1.0")
+	}
+}
@@ -0,0 +1,47 @@
+package cidrtree
+
+import "net/netip"
+
+// PolicyRouter is a policy-based router with two lookup stages, the source address selects
+// a policy in Rules, the destination is looked up in the routing table of the policy.
+// This is the pattern of source routing rules with per-policy tables, e.g. ip rule and ip route.
+type PolicyRouter[K comparable, V any] struct {
+	// Rules maps the source prefixes to the policies.
+	Rules Table[K]
+
+	// Tables are the routing tables of the policies.
+	Tables map[K]*Table[V]
+
+	// Main is the routing table for the packets without a policy route, nil is no fallback.
+	Main *Table[V]
+}
+
+// Lookup returns the route for a packet from src to dst. The most specific source rule with
+// a route for dst in its policy table wins, a policy table without a route for dst passes on
+// to the next-less-specific source rule. Without a policy route, dst is looked up in Main and
+// policy is the zero value of K.
+func (pr *PolicyRouter[K, V]) Lookup(src, dst netip.Addr) (policy K, lpm netip.Prefix, value V, ok bool) {
+	_, policy, ok = pr.Rules.LookupFunc(src, func(_ netip.Prefix, p K) bool {
+		t := pr.Tables[p]
+		if t == nil {
+			return false
+		}
+
+		var found bool
+		lpm, value, found = t.Lookup(dst)
+		return found
+	})
+
+	if ok {
+		return policy, lpm, value, true
+	}
+
+	var zero K
+	if pr.Main == nil {
+		var zeroValue V
+		return zero, netip.Prefix{}, zeroValue, false
+	}
+
+	lpm, value, ok = pr.Main.Lookup(dst)
+	return zero, lpm, value, ok
+}
@@ -0,0 +1,60 @@
+package cidrtree_test
+
+import (
+	"testing"
+
+	"github.com/gaissmai/cidrtree"
+)
+
+func TestPolicyRouter(t *testing.T) {
+	t.Parallel()
+
+	guest := new(cidrtree.Table[string])
+	guest.Insert(mustPfx("0.0.0.0/0"), "isp2")
+
+	voip := new(cidrtree.Table[string])
+	voip.Insert(mustPfx("198.51.100.0/24"), "sip-trunk")
+
+	main := new(cidrtree.Table[string])
+	main.Insert(mustPfx("0.0.0.0/0"), "isp1")
+	main.Insert(mustPfx("10.0.0.0/8"), "core")
+
+	pr := cidrtree.PolicyRouter[string, string]{
+		Tables: map[string]*cidrtree.Table[string]{"guest": guest, "voip": voip},
+		Main:   main,
+	}
+	pr.Rules.Insert(mustPfx("10.99.0.0/16"), "guest")
+	pr.Rules.Insert(mustPfx("10.20.0.0/16"), "guest")
+	pr.Rules.Insert(mustPfx("10.20.30.0/24"), "voip")
+	pr.Rules.Insert(mustPfx("10.50.0.0/16"), "unknown")
+
+	tcs := []struct {
+		src, dst   string
+		wantPolicy string
+		wantValue  string
+		wantOK     bool
+	}{
+		{"10.99.1.1", "8.8.8.8", "guest", "isp2", true},
+		{"10.20.30.1", "198.51.100.7", "voip", "sip-trunk", true},
+		// no route in voip, the less specific rule
+		{"10.20.30.1", "8.8.8.8", "guest", "isp2", true},
+		// no rule, main table
+		{"10.1.1.1", "10.2.2.2", "", "core", true},
+		// policy without table, main table
+		{"10.50.1.1", "8.8.8.8", "", "isp1", true},
+		{"10.1.1.1", "2001:db8::1", "", "", false},
+	}
+
+	for _, tc := range tcs {
+		policy, _, value, ok := pr.Lookup(mustAddr(tc.src), mustAddr(tc.dst))
+		if policy != tc.wantPolicy || value != tc.wantValue || ok != tc.wantOK {
+			t.Errorf("Lookup(%v, %v), got (%q, %q, %v), want (%q, %q, %v)",
+				tc.src, tc.dst, policy, value, ok, tc.wantPolicy, tc.wantValue, tc.wantOK)
+		}
+	}
+
+	pr.Main = nil
+	if _, _, _, ok := pr.Lookup(mustAddr("10.1.1.1"), mustAddr("10.2.2.2")); ok {
+		t.Errorf("Lookup without Main, got %v, want false", ok)
+	}
+}
@@ -0,0 +1,75 @@
+package cidrtree
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/netip"
+	"slices"
+	"strings"
+)
+
+// ReadPFTable returns a table from an OpenBSD pf table file, e.g. the file of
+//
+//	table <bogons> persist file "/etc/bogons"
+//
+// The entries are addresses or CIDRs separated by white space, # starts a comment.
+// A negated entry !CIDR is excluded from the covering entries with [Table.Exclude],
+// the lookups of the table match the addresses pf matches. Entries covered by a negation
+// stay, the longest match wins as in pf, an entry equal to a negation is removed.
+// Host names and interface names are not supported.
+func ReadPFTable(r io.Reader) (*Table[struct{}], error) {
+	var entries []Entry[struct{}]
+	var negations []netip.Prefix
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text, _, _ := strings.Cut(scanner.Text(), "#")
+
+		for _, field := range strings.Fields(text) {
+			negated := strings.HasPrefix(field, "!")
+
+			pfx, err := parsePFEntry(strings.TrimPrefix(field, "!"))
+			if err != nil {
+				return nil, fmt.Errorf("cidrtree: pf table line %d: %w", line, err)
+			}
+
+			if negated {
+				negations = append(negations, pfx)
+				continue
+			}
+			entries = append(entries, Entry[struct{}]{Prefix: pfx})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("cidrtree: pf table: %w", err)
+	}
+
+	t := new(Table[struct{}]).InsertManyImmutable(entries)
+
+	// from least to most specific, a hole punched into a hole would split the nested entries
+	slices.SortStableFunc(negations, func(a, b netip.Prefix) int { return a.Bits() - b.Bits() })
+	for _, pfx := range negations {
+		t.Delete(pfx)
+		t.Exclude(pfx)
+	}
+
+	return t, nil
+}
+
+// parsePFEntry parses an address or CIDR, addresses are host routes.
+func parsePFEntry(s string) (netip.Prefix, error) {
+	if strings.Contains(s, "/") {
+		pfx, err := netip.ParsePrefix(s)
+		if err != nil {
+			return pfx, err
+		}
+		return pfx.Masked(), nil
+	}
+
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("unsupported entry %q", s)
+	}
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
@@ -0,0 +1,58 @@
+package cidrtree_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gaissmai/cidrtree"
+)
+
+func TestReadPFTable(t *testing.T) {
+	t.Parallel()
+
+	in := `# trusted networks
+10.0.0.0/8 !10.1.0.0/16
+10.1.1.0/24        # nested in the negation, pf matches it
+!10.1.1.128/25
+192.0.2.1
+!192.0.2.1
+2001:db8::/32
+!2001:db8:dead::/48
+`
+
+	rtbl, err := cidrtree.ReadPFTable(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tcs := []struct {
+		ip   string
+		want bool
+	}{
+		{"10.0.0.1", true},
+		{"10.255.255.255", true},
+		{"10.1.0.1", false},
+		{"10.1.1.1", true},
+		{"10.1.1.129", false},
+		{"192.0.2.1", false},
+		{"2001:db8::1", true},
+		{"2001:db8:dead::1", false},
+		{"2001:db9::1", false},
+	}
+
+	for _, tc := range tcs {
+		if got := rtbl.Contains(mustAddr(tc.ip)); got != tc.want {
+			t.Errorf("Contains(%s), got %v, want %v", tc.ip, got, tc.want)
+		}
+	}
+}
+
+func TestReadPFTableError(t *testing.T) {
+	t.Parallel()
+
+	for _, s := range []string{"example.com", "10.0.0.0/33", "!self", "em0:network"} {
+		if _, err := cidrtree.ReadPFTable(strings.NewReader(s)); err == nil {
+			t.Errorf("ReadPFTable(%q), expected error", s)
+		}
+	}
+}
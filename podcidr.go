@@ -0,0 +1,139 @@
+package cidrtree
+
+import (
+	"fmt"
+	"net/netip"
+	"sync"
+)
+
+// PodCIDRAllocator manages the per-node pod CIDRs out of the cluster CIDRs, as the node IPAM
+// controller of Kubernetes. Every node gets one pod CIDR of each cluster CIDR, for dual-stack
+// clusters one cluster CIDR per IP version. The allocator is safe for concurrent use.
+type PodCIDRAllocator struct {
+	mu       sync.Mutex
+	clusters []netip.Prefix
+	bits     []int
+	table    Table[string] // pod CIDR -> node
+	nodes    map[string][]netip.Prefix
+}
+
+// NewPodCIDRAllocator returns an allocator for the cluster CIDRs, the pod CIDRs of the nodes have
+// the mask size maskSize4 for IPv4 and maskSize6 for IPv6 cluster CIDRs, e.g. 24 and 64.
+func NewPodCIDRAllocator(clusterCIDRs []netip.Prefix, maskSize4, maskSize6 int) (*PodCIDRAllocator, error) {
+	a := &PodCIDRAllocator{nodes: make(map[string][]netip.Prefix)}
+
+	for _, cidr := range clusterCIDRs {
+		cidr = cidr.Masked() // always canonicalize!
+		if !cidr.IsValid() {
+			return nil, fmt.Errorf("cidrtree: invalid cluster CIDR")
+		}
+
+		bits := maskSize6
+		if cidr.Addr().Is4() {
+			bits = maskSize4
+		}
+		if bits < cidr.Bits() || bits > cidr.Addr().BitLen() {
+			return nil, fmt.Errorf("cidrtree: mask size %d invalid for cluster CIDR %v", bits, cidr)
+		}
+
+		a.clusters = append(a.clusters, cidr)
+		a.bits = append(a.bits, bits)
+	}
+
+	return a, nil
+}
+
+// Allocate returns the pod CIDRs of node, one of each cluster CIDR, in the order of the cluster CIDRs.
+// Already allocated pod CIDRs are returned unchanged, the allocation is idempotent.
+// An error is returned if a cluster CIDR is exhausted, then nothing is allocated.
+func (a *PodCIDRAllocator) Allocate(node string) ([]netip.Prefix, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if pfxs, ok := a.nodes[node]; ok {
+		return append([]netip.Prefix(nil), pfxs...), nil
+	}
+
+	pfxs := make([]netip.Prefix, 0, len(a.clusters))
+	for i, cluster := range a.clusters {
+		pfx, ok := a.table.NextFree(cluster, a.bits[i])
+		if !ok {
+			return nil, fmt.Errorf("cidrtree: cluster CIDR %v exhausted", cluster)
+		}
+		pfxs = append(pfxs, pfx)
+	}
+
+	for _, pfx := range pfxs {
+		a.table.Insert(pfx, node)
+	}
+	a.nodes[node] = pfxs
+
+	return append([]netip.Prefix(nil), pfxs...), nil
+}
+
+// Occupy marks pfx as allocated to node, e.g. to restore the pod CIDRs of the node objects after a restart.
+// An error is returned if pfx isn't a pod CIDR of a cluster CIDR or overlaps the pod CIDR of another node.
+func (a *PodCIDRAllocator) Occupy(node string, pfx netip.Prefix) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	pfx = pfx.Masked() // always canonicalize!
+
+	valid := false
+	for i, cluster := range a.clusters {
+		if pfx.Bits() == a.bits[i] && cluster.Contains(pfx.Addr()) {
+			valid = true
+		}
+	}
+	if !valid {
+		return fmt.Errorf("cidrtree: %v is not a pod CIDR of the cluster CIDRs", pfx)
+	}
+
+	if owner, ok := a.table.get(pfx); ok && owner == node {
+		return nil
+	}
+
+	// equal, covering or covered pod CIDRs, e.g. of overlapping cluster CIDRs
+	if conflicts := a.table.overlaps(pfx); len(conflicts) > 0 {
+		owner, _ := a.table.get(conflicts[0])
+		return fmt.Errorf("cidrtree: pod CIDR %v overlaps %v allocated to %s", pfx, conflicts[0], owner)
+	}
+
+	a.table.Insert(pfx, node)
+	a.nodes[node] = append(a.nodes[node], pfx)
+	return nil
+}
+
+// Release frees the pod CIDRs of node, returns false if node has no pod CIDRs.
+func (a *PodCIDRAllocator) Release(node string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	pfxs, ok := a.nodes[node]
+	if !ok {
+		return false
+	}
+
+	for _, pfx := range pfxs {
+		a.table.Delete(pfx)
+	}
+	delete(a.nodes, node)
+	return true
+}
+
+// PodCIDRs returns the pod CIDRs of node.
+func (a *PodCIDRAllocator) PodCIDRs(node string) []netip.Prefix {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return append([]netip.Prefix(nil), a.nodes[node]...)
+}
+
+// Owner returns the node with the pod CIDR containing ip, e.g. for the pod address of a flow.
+func (a *PodCIDRAllocator) Owner(ip netip.Addr) (node string, ok bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	_, node, ok = a.table.Lookup(ip)
+	return
+}
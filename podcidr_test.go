@@ -0,0 +1,126 @@
+package cidrtree_test
+
+import (
+	"fmt"
+	"net/netip"
+	"reflect"
+	"testing"
+
+	"github.com/gaissmai/cidrtree"
+)
+
+func TestPodCIDRAllocator(t *testing.T) {
+	t.Parallel()
+
+	a, err := cidrtree.NewPodCIDRAllocator([]netip.Prefix{mustPfx("10.244.0.0/22"), mustPfx("fd00:10:244::/56")}, 24, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// restored after a restart
+	if err := a.Occupy("node-1", mustPfx("10.244.1.0/24")); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := a.Allocate("node-0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []netip.Prefix{mustPfx("10.244.0.0/24"), mustPfx("fd00:10:244::/64")}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Allocate(node-0), got %v, want %v", got, want)
+	}
+
+	// idempotent
+	if again, _ := a.Allocate("node-0"); !reflect.DeepEqual(again, want) {
+		t.Errorf("Allocate(node-0) again, got %v, want %v", again, want)
+	}
+
+	for i := 2; i <= 3; i++ {
+		if _, err := a.Allocate(fmt.Sprintf("node-%d", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := a.Allocate("node-4"); err == nil {
+		t.Errorf("Allocate(node-4), expected exhausted error")
+	}
+
+	if node, ok := a.Owner(mustAddr("10.244.2.17")); !ok || node != "node-2" {
+		t.Errorf("Owner(10.244.2.17), got (%q, %v), want (%q, %v)", node, ok, "node-2", true)
+	}
+
+	if err := a.Occupy("node-9", mustPfx("10.244.2.0/24")); err == nil {
+		t.Errorf("Occupy of allocated pod CIDR, expected error")
+	}
+	if err := a.Occupy("node-9", mustPfx("10.245.0.0/24")); err == nil {
+		t.Errorf("Occupy outside the cluster CIDRs, expected error")
+	}
+
+	if !a.Release("node-2") {
+		t.Errorf("Release(node-2), got false, want true")
+	}
+	if a.Release("node-2") {
+		t.Errorf("Release(node-2) again, got true, want false")
+	}
+	if _, ok := a.Owner(mustAddr("10.244.2.17")); ok {
+		t.Errorf("Owner(10.244.2.17) after Release, got true, want false")
+	}
+
+	if got, _ := a.Allocate("node-4"); got[0] != mustPfx("10.244.2.0/24") {
+		t.Errorf("Allocate(node-4) after Release, got %v, want %v", got[0], "10.244.2.0/24")
+	}
+	if got := a.PodCIDRs("node-1"); !reflect.DeepEqual(got, []netip.Prefix{mustPfx("10.244.1.0/24")}) {
+		t.Errorf("PodCIDRs(node-1), got %v", got)
+	}
+}
+
+func TestNewPodCIDRAllocatorError(t *testing.T) {
+	t.Parallel()
+
+	if _, err := cidrtree.NewPodCIDRAllocator([]netip.Prefix{mustPfx("10.244.0.0/16")}, 8, 64); err == nil {
+		t.Errorf("NewPodCIDRAllocator with mask size 8 for a /16, expected error")
+	}
+	if _, err := cidrtree.NewPodCIDRAllocator([]netip.Prefix{{}}, 24, 64); err == nil {
+		t.Errorf("NewPodCIDRAllocator with invalid cluster CIDR, expected error")
+	}
+}
+
+func TestPodCIDRAllocatorWholeCluster(t *testing.T) {
+	t.Parallel()
+
+	// the mask size equals the cluster CIDR, one node only
+	a, _ := cidrtree.NewPodCIDRAllocator([]netip.Prefix{mustPfx("10.244.0.0/24")}, 24, 64)
+
+	if got, err := a.Allocate("node-0"); err != nil || got[0] != mustPfx("10.244.0.0/24") {
+		t.Fatalf("Allocate(node-0), got (%v, %v)", got, err)
+	}
+	if got, err := a.Allocate("node-1"); err == nil {
+		t.Errorf("Allocate(node-1) of the allocated cluster CIDR, got %v, expected error", got)
+	}
+	if err := a.Occupy("node-1", mustPfx("10.244.0.0/24")); err == nil {
+		t.Errorf("Occupy of the allocated cluster CIDR, expected error")
+	}
+}
+
+func TestPodCIDRAllocatorOccupyOverlap(t *testing.T) {
+	t.Parallel()
+
+	// overlapping cluster CIDRs, the /24 pod CIDRs of both clusters collide
+	a, _ := cidrtree.NewPodCIDRAllocator([]netip.Prefix{mustPfx("10.244.0.0/16"), mustPfx("10.244.1.0/24")}, 24, 64)
+
+	if err := a.Occupy("node-0", mustPfx("10.244.1.0/24")); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Occupy("node-0", mustPfx("10.244.1.0/24")); err != nil {
+		t.Errorf("Occupy of own pod CIDR, got %v, want nil", err)
+	}
+	if err := a.Occupy("node-1", mustPfx("10.244.1.0/24")); err == nil {
+		t.Errorf("Occupy of overlapping pod CIDR, expected error")
+	}
+
+	// the second cluster CIDR is exhausted by the pod CIDR of the first
+	if got, err := a.Allocate("node-1"); err == nil {
+		t.Errorf("Allocate(node-1), got %v, expected exhausted error", got)
+	}
+}
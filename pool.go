@@ -0,0 +1,195 @@
+package cidrtree
+
+import (
+	"container/heap"
+	"fmt"
+	"net/netip"
+	"sync"
+	"time"
+)
+
+// Lease is an address or sub-prefix of a pool, leased to a client until the expiry.
+type Lease struct {
+	Prefix  netip.Prefix
+	Pool    netip.Prefix
+	Client  string
+	Expires time.Time
+}
+
+// Pools manages DHCP-style address pools. The pools are disjunct prefixes, the clients lease
+// host addresses or sub-prefixes out of the pools with an expiry. Expired leases are reclaimed
+// before the next lease, the lowest free address is handed out first. Pools is safe for concurrent use.
+//
+// The zero value is ready to use.
+type Pools struct {
+	// Now returns the current time, nil is [time.Now].
+	Now func() time.Time
+
+	mu      sync.Mutex
+	pools   Table[struct{}]
+	leases  Table[Lease]
+	clients map[poolClient]netip.Prefix
+	expiry  leaseHeap
+}
+
+type poolClient struct {
+	pool   netip.Prefix
+	client string
+}
+
+// AddPool adds the pool pfx, an [*OverlapError] is returned if pfx overlaps another pool.
+func (p *Pools) AddPool(pfx netip.Prefix) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.pools.InsertNoOverlap(pfx, struct{}{})
+}
+
+// Lease leases the lowest free sub-prefix with the given bits of pool to client for ttl,
+// e.g. bits 32 for an IPv4 host address. An active lease of client in pool with the same bits
+// is renewed instead, as for a DHCP request of a known client.
+func (p *Pools) Lease(pool netip.Prefix, bits int, client string, ttl time.Duration) (Lease, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	pool = pool.Masked() // always canonicalize!
+	if _, ok := p.pools.get(pool); !ok {
+		return Lease{}, fmt.Errorf("cidrtree: pool %v not found", pool)
+	}
+	if bits < pool.Bits() || bits > pool.Addr().BitLen() {
+		return Lease{}, fmt.Errorf("cidrtree: invalid bits %d for pool %v", bits, pool)
+	}
+
+	now := p.now()
+	p.expire(now)
+
+	key := poolClient{pool: pool, client: client}
+	if pfx, ok := p.clients[key]; ok && pfx.Bits() == bits {
+		return p.renew(pfx, now.Add(ttl)), nil
+	}
+
+	pfx, ok := p.leases.NextFree(pool, bits)
+	if !ok {
+		return Lease{}, fmt.Errorf("cidrtree: pool %v exhausted for /%d", pool, bits)
+	}
+
+	// a lease with other bits is replaced
+	if old, ok := p.clients[key]; ok {
+		p.leases.Delete(old)
+	}
+
+	if p.clients == nil {
+		p.clients = make(map[poolClient]netip.Prefix)
+	}
+	p.clients[key] = pfx
+
+	lease := Lease{Prefix: pfx, Pool: pool, Client: client, Expires: now.Add(ttl)}
+	p.leases.Insert(pfx, lease)
+	heap.Push(&p.expiry, lease)
+
+	return lease, nil
+}
+
+// Renew extends the lease of pfx for ttl from now.
+func (p *Pools) Renew(pfx netip.Prefix, ttl time.Duration) (Lease, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now()
+	p.expire(now)
+
+	pfx = pfx.Masked() // always canonicalize!
+	if _, ok := p.leases.get(pfx); !ok {
+		return Lease{}, fmt.Errorf("cidrtree: no active lease for %v", pfx)
+	}
+	return p.renew(pfx, now.Add(ttl)), nil
+}
+
+// Release ends the lease of pfx, returns false if there is no active lease.
+func (p *Pools) Release(pfx netip.Prefix) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.expire(p.now())
+	return p.release(pfx.Masked())
+}
+
+// Lookup returns the active lease containing ip.
+func (p *Pools) Lookup(ip netip.Addr) (Lease, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.expire(p.now())
+
+	_, lease, ok := p.leases.Lookup(ip)
+	return lease, ok
+}
+
+// Occupancy returns the number of active leases in pool and the leased fraction of the pool addresses.
+func (p *Pools) Occupancy(pool netip.Prefix) (leases int, fraction float64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.expire(p.now())
+
+	// the leases are disjunct, all are direct children of the pool
+	pool = pool.Masked() // always canonicalize!
+	leases = len(p.leases.Children(pool))
+
+	_, fraction = p.leases.Coverage(pool)
+	return leases, fraction
+}
+
+func (p *Pools) now() time.Time {
+	if p.Now == nil {
+		return time.Now()
+	}
+	return p.Now()
+}
+
+func (p *Pools) renew(pfx netip.Prefix, expires time.Time) Lease {
+	lease, _ := p.leases.get(pfx)
+	lease.Expires = expires
+
+	p.leases.Insert(pfx, lease)
+	heap.Push(&p.expiry, lease)
+	return lease
+}
+
+func (p *Pools) release(pfx netip.Prefix) bool {
+	lease, ok := p.leases.get(pfx)
+	if !ok {
+		return false
+	}
+
+	p.leases.Delete(pfx)
+	delete(p.clients, poolClient{pool: lease.Pool, client: lease.Client})
+	return true
+}
+
+// expire releases all leases expired at now. The heap may hold outdated copies of renewed
+// or released leases, they are skipped.
+func (p *Pools) expire(now time.Time) {
+	for len(p.expiry) > 0 && !p.expiry[0].Expires.After(now) {
+		old := heap.Pop(&p.expiry).(Lease)
+
+		if lease, ok := p.leases.get(old.Prefix); ok && lease == old {
+			p.release(old.Prefix)
+		}
+	}
+}
+
+// leaseHeap is a min-heap of the leases by expiry.
+type leaseHeap []Lease
+
+func (h leaseHeap) Len() int           { return len(h) }
+func (h leaseHeap) Less(i, j int) bool { return h[i].Expires.Before(h[j].Expires) }
+func (h leaseHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *leaseHeap) Push(x any)        { *h = append(*h, x.(Lease)) }
+
+func (h *leaseHeap) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
@@ -0,0 +1,123 @@
+package cidrtree_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gaissmai/cidrtree"
+)
+
+func TestPools(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	p := cidrtree.Pools{Now: func() time.Time { return now }}
+
+	pool := mustPfx("192.168.0.0/30")
+	if err := p.AddPool(pool); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.AddPool(mustPfx("192.168.0.0/24")); err == nil {
+		t.Errorf("AddPool of overlapping pool, expected error")
+	}
+
+	a, err := p.Lease(pool, 32, "a", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Prefix != mustPfx("192.168.0.0/32") || a.Expires != now.Add(time.Hour) {
+		t.Errorf("Lease(a), got %+v", a)
+	}
+
+	b, _ := p.Lease(pool, 32, "b", time.Hour)
+	c, _ := p.Lease(pool, 32, "c", time.Hour)
+	d, _ := p.Lease(pool, 32, "d", time.Hour)
+	if d.Prefix != mustPfx("192.168.0.3/32") {
+		t.Errorf("Lease(d), got %v, want %v", d.Prefix, "192.168.0.3/32")
+	}
+
+	if _, err := p.Lease(pool, 32, "e", time.Hour); err == nil {
+		t.Errorf("Lease(e), expected exhausted error")
+	}
+
+	// known client renews
+	now = now.Add(30 * time.Minute)
+	if again, _ := p.Lease(pool, 32, "a", time.Hour); again.Prefix != a.Prefix || again.Expires != now.Add(time.Hour) {
+		t.Errorf("Lease(a) again, got %+v", again)
+	}
+
+	if n, fraction := p.Occupancy(pool); n != 4 || fraction != 1 {
+		t.Errorf("Occupancy, got (%d, %v), want (4, 1)", n, fraction)
+	}
+
+	// b, c and d expire, a was renewed
+	now = now.Add(45 * time.Minute)
+	if n, fraction := p.Occupancy(pool); n != 1 || fraction != 0.25 {
+		t.Errorf("Occupancy after expiry, got (%d, %v), want (1, 0.25)", n, fraction)
+	}
+	if _, ok := p.Lookup(b.Prefix.Addr()); ok {
+		t.Errorf("Lookup(%v) after expiry, got true, want false", b.Prefix)
+	}
+	if lease, ok := p.Lookup(a.Prefix.Addr()); !ok || lease.Client != "a" {
+		t.Errorf("Lookup(%v), got (%+v, %v)", a.Prefix, lease, ok)
+	}
+
+	if e, _ := p.Lease(pool, 32, "e", time.Hour); e.Prefix != b.Prefix {
+		t.Errorf("Lease(e) after expiry, got %v, want %v", e.Prefix, b.Prefix)
+	}
+
+	if _, err := p.Renew(c.Prefix, time.Hour); err == nil {
+		t.Errorf("Renew of expired lease, expected error")
+	}
+	if !p.Release(a.Prefix) || p.Release(a.Prefix) {
+		t.Errorf("Release(%v), want true once", a.Prefix)
+	}
+}
+
+func TestPoolsSubPrefix(t *testing.T) {
+	t.Parallel()
+
+	var p cidrtree.Pools
+	pool := mustPfx("2001:db8::/48")
+	_ = p.AddPool(pool)
+
+	// delegated prefixes
+	l1, _ := p.Lease(pool, 56, "cpe-1", time.Hour)
+	l2, _ := p.Lease(pool, 56, "cpe-2", time.Hour)
+	if l1.Prefix != mustPfx("2001:db8::/56") || l2.Prefix != mustPfx("2001:db8:0:100::/56") {
+		t.Errorf("Lease /56, got %v and %v", l1.Prefix, l2.Prefix)
+	}
+
+	if _, err := p.Lease(mustPfx("2001:db9::/48"), 56, "cpe-3", time.Hour); err == nil {
+		t.Errorf("Lease of unknown pool, expected error")
+	}
+	if _, err := p.Lease(pool, 40, "cpe-3", time.Hour); err == nil {
+		t.Errorf("Lease with invalid bits, expected error")
+	}
+}
+
+func TestPoolsLeaseWholePool(t *testing.T) {
+	t.Parallel()
+
+	var p cidrtree.Pools
+	pool := mustPfx("192.0.2.0/28")
+	_ = p.AddPool(pool)
+
+	l1, err := p.Lease(pool, 28, "a", time.Hour)
+	if err != nil || l1.Prefix != pool {
+		t.Fatalf("Lease whole pool, got (%v, %v), want %v", l1.Prefix, err, pool)
+	}
+
+	// the pool is leased, neither the whole pool nor a part of it is free
+	if l2, err := p.Lease(pool, 28, "b", time.Hour); err == nil {
+		t.Errorf("Lease whole pool twice, got %v, expected error", l2.Prefix)
+	}
+	if l2, err := p.Lease(pool, 32, "b", time.Hour); err == nil {
+		t.Errorf("Lease host of leased pool, got %v, expected error", l2.Prefix)
+	}
+
+	// the same client renews
+	if l, err := p.Lease(pool, 28, "a", 2*time.Hour); err != nil || l.Prefix != pool {
+		t.Errorf("Lease renew, got (%v, %v), want %v", l.Prefix, err, pool)
+	}
+}
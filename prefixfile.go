@@ -0,0 +1,78 @@
+package cidrtree
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"strings"
+)
+
+// LoadPrefixFile reads the prefixes from the file, one prefix per line, see [ReadPrefixes].
+// Gzip compressed files are detected and decompressed.
+func LoadPrefixFile(path string) ([]netip.Prefix, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("cidrtree: %w", err)
+	}
+	defer file.Close()
+
+	br := bufio.NewReader(file)
+
+	var r io.Reader = br
+	if magic, _ := br.Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		rgz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("cidrtree: %s: %w", path, err)
+		}
+		defer rgz.Close()
+		r = rgz
+	}
+
+	pfxs, err := ReadPrefixes(r)
+	if err != nil {
+		return nil, fmt.Errorf("cidrtree: %s: %w", path, err)
+	}
+	return pfxs, nil
+}
+
+// ReadPrefixes reads the prefixes, one prefix per line, in the given order.
+// Leading and trailing white space is trimmed, empty lines and lines starting with # are skipped.
+func ReadPrefixes(r io.Reader) ([]netip.Prefix, error) {
+	var pfxs []netip.Prefix
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		pfx, err := netip.ParsePrefix(text)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		pfxs = append(pfxs, pfx)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return pfxs, nil
+}
+
+// LoadTable returns a new table with all prefixes of the file, see [LoadPrefixFile], all with the same value.
+func LoadTable[V any](path string, value V) (*Table[V], error) {
+	pfxs, err := LoadPrefixFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]Entry[V], 0, len(pfxs))
+	for _, pfx := range pfxs {
+		entries = append(entries, Entry[V]{Prefix: pfx, Value: value})
+	}
+	return new(Table[V]).InsertManyImmutable(entries), nil
+}
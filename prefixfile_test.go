@@ -0,0 +1,46 @@
+package cidrtree_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gaissmai/cidrtree"
+)
+
+func TestLoadPrefixFile(t *testing.T) {
+	t.Parallel()
+
+	// gzipped
+	pfxs, err := cidrtree.LoadPrefixFile(prefixFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pfxs) != len(fullTable) {
+		t.Errorf("LoadPrefixFile(%v), got %d prefixes, want %d", prefixFile, len(pfxs), len(fullTable))
+	}
+
+	// plain
+	path := filepath.Join(t.TempDir(), "prefixes.txt")
+	if err := os.WriteFile(path, []byte("# comment\n10.0.0.0/8\n\n  ::/0  \n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	rtbl, err := cidrtree.LoadTable(path, "x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "▼\n└─ 10.0.0.0/8 (x)\n▼\n└─ ::/0 (x)\n"; rtbl.String() != want {
+		t.Errorf("LoadTable(%v), got:\n%v\nwant:\n%v", path, rtbl, want)
+	}
+
+	if _, err := cidrtree.LoadPrefixFile(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Errorf("LoadPrefixFile of missing file, expected error")
+	}
+
+	_, err = cidrtree.ReadPrefixes(strings.NewReader("10.0.0.0/8\nfoo\n"))
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("ReadPrefixes, got error %v, want error in line 2", err)
+	}
+}
@@ -0,0 +1,448 @@
+package cidrtree
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// PrefixListRule is a rule of a router prefix-list, e.g. the Cisco IOS syntax
+//
+//	ip prefix-list NAME seq 10 permit 10.0.0.0/8 ge 16 le 24
+//
+// The rule matches all prefixes covered by Prefix with a length in the range of GE and LE.
+// Without GE and LE only Prefix itself matches, with GE only the range is GE up to the
+// address bit length and with LE only the range is the length of Prefix up to LE.
+type PrefixListRule struct {
+	Seq    int
+	Deny   bool
+	Prefix netip.Prefix
+	GE     int // 0 if unset
+	LE     int // 0 if unset
+}
+
+// lengths returns the range of matching prefix lengths.
+func (r PrefixListRule) lengths() (lo, hi int) {
+	switch {
+	case r.GE == 0 && r.LE == 0:
+		return r.Prefix.Bits(), r.Prefix.Bits()
+	case r.LE == 0:
+		return r.GE, r.Prefix.Addr().BitLen()
+	case r.GE == 0:
+		return r.Prefix.Bits(), r.LE
+	default:
+		return r.GE, r.LE
+	}
+}
+
+// Matches reports whether pfx is matched by the rule, the action isn't considered.
+func (r PrefixListRule) Matches(pfx netip.Prefix) bool {
+	pfx = pfx.Masked() // always canonicalize!
+	if !pfx.IsValid() || pfx.Addr().Is4() != r.Prefix.Addr().Is4() {
+		return false
+	}
+
+	lo, hi := r.lengths()
+	return r.Prefix.Bits() <= pfx.Bits() && r.Prefix.Contains(pfx.Addr()) && lo <= pfx.Bits() && pfx.Bits() <= hi
+}
+
+// validate the rule with the constraints of Cisco IOS: len < ge <= le <= address bit length.
+func (r PrefixListRule) validate() error {
+	if !r.Prefix.IsValid() {
+		return fmt.Errorf("invalid prefix %v", r.Prefix)
+	}
+
+	bits, maxBits := r.Prefix.Bits(), r.Prefix.Addr().BitLen()
+	if r.GE != 0 && (r.GE <= bits || r.GE > maxBits) {
+		return fmt.Errorf("invalid ge %d for %v", r.GE, r.Prefix)
+	}
+	if r.LE != 0 && (r.LE <= bits || r.LE > maxBits || r.LE < r.GE) {
+		return fmt.Errorf("invalid le %d for %v", r.LE, r.Prefix)
+	}
+	return nil
+}
+
+// PrefixList is a named, ordered list of prefix-list rules, the rules are stored in a table
+// keyed by the rule prefix. A match needs only the rules of the covering prefixes.
+type PrefixList struct {
+	name  string
+	table Table[[]PrefixListRule]
+	seqs  map[int]netip.Prefix
+}
+
+// NewPrefixList returns an empty prefix-list.
+func NewPrefixList(name string) *PrefixList {
+	return &PrefixList{name: name, seqs: make(map[int]netip.Prefix)}
+}
+
+// Name returns the name of the prefix-list.
+func (p *PrefixList) Name() string {
+	return p.name
+}
+
+// Add adds the rule to the prefix-list, a rule with the same sequence number is replaced.
+// If the sequence number is 0, it is set to the highest sequence number plus 5, as in Cisco IOS.
+func (p *PrefixList) Add(rule PrefixListRule) error {
+	rule.Prefix = rule.Prefix.Masked() // always canonicalize!
+	if err := rule.validate(); err != nil {
+		return fmt.Errorf("cidrtree: prefix-list %s: %w", p.name, err)
+	}
+
+	if rule.Seq == 0 {
+		for seq := range p.seqs {
+			rule.Seq = max(rule.Seq, seq)
+		}
+		rule.Seq += 5
+	}
+
+	// replace the rule with the same seq
+	if old, ok := p.seqs[rule.Seq]; ok {
+		rules, _ := p.table.get(old)
+		rules = slices.DeleteFunc(slices.Clone(rules), func(r PrefixListRule) bool { return r.Seq == rule.Seq })
+		if len(rules) == 0 {
+			p.table.Delete(old)
+		} else {
+			p.table.Insert(old, rules)
+		}
+	}
+
+	rules, _ := p.table.get(rule.Prefix)
+	rules = append(slices.Clone(rules), rule)
+	p.table.Insert(rule.Prefix, rules)
+	p.seqs[rule.Seq] = rule.Prefix
+
+	return nil
+}
+
+// Rules returns the rules in ascending order of the sequence numbers.
+func (p *PrefixList) Rules() []PrefixListRule {
+	var all []PrefixListRule
+	p.table.Walk(func(_ netip.Prefix, rules []PrefixListRule) bool {
+		all = append(all, rules...)
+		return true
+	})
+
+	slices.SortFunc(all, func(a, b PrefixListRule) int { return a.Seq - b.Seq })
+	return all
+}
+
+// Match returns the matching rule with the lowest sequence number for pfx.
+func (p *PrefixList) Match(pfx netip.Prefix) (rule PrefixListRule, ok bool) {
+	pfx = pfx.Masked() // always canonicalize!
+	if !pfx.IsValid() {
+		return
+	}
+
+	// only rules of pfx and its supernets can match
+	var candidates [][]PrefixListRule
+	if rules, found := p.table.get(pfx); found {
+		candidates = append(candidates, rules)
+	}
+	for _, n := range p.table.supernets(pfx) {
+		candidates = append(candidates, n.value)
+	}
+
+	for _, rules := range candidates {
+		for _, r := range rules {
+			if (!ok || r.Seq < rule.Seq) && r.Matches(pfx) {
+				rule, ok = r, true
+			}
+		}
+	}
+	return
+}
+
+// Permits reports whether pfx is permitted, prefixes without a matching rule are denied.
+func (p *PrefixList) Permits(pfx netip.Prefix) bool {
+	rule, ok := p.Match(pfx)
+	return ok && !rule.Deny
+}
+
+// ReadCiscoPrefixLists parses the prefix-lists in Cisco IOS syntax, the lists are returned
+// in the order of their first line. All other lines of a configuration are skipped.
+//
+//	ip prefix-list NAME [seq N] {permit|deny} PREFIX [ge N] [le N]
+//	ipv6 prefix-list NAME [seq N] {permit|deny} PREFIX [ge N] [le N]
+func ReadCiscoPrefixLists(r io.Reader) ([]*PrefixList, error) {
+	var lists []*PrefixList
+	byName := make(map[string]*PrefixList)
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 || (fields[0] != "ip" && fields[0] != "ipv6") || fields[1] != "prefix-list" {
+			continue
+		}
+
+		name := fields[2]
+		if len(fields) > 3 && fields[3] == "description" {
+			continue
+		}
+
+		rule, err := parseCiscoRule(fields[3:])
+		if err != nil {
+			return nil, fmt.Errorf("cidrtree: prefix-list line %d: %w", line, err)
+		}
+		if rule.Prefix.Addr().Is4() != (fields[0] == "ip") {
+			return nil, fmt.Errorf("cidrtree: prefix-list line %d: wrong IP version for %v", line, rule.Prefix)
+		}
+
+		p := byName[name]
+		if p == nil {
+			p = NewPrefixList(name)
+			byName[name] = p
+			lists = append(lists, p)
+		}
+
+		if err := p.Add(rule); err != nil {
+			return nil, err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("cidrtree: prefix-list: %w", err)
+	}
+
+	return lists, nil
+}
+
+// parseCiscoRule parses [seq N] {permit|deny} PREFIX [ge N] [le N].
+func parseCiscoRule(fields []string) (rule PrefixListRule, err error) {
+	next := func() string {
+		if len(fields) == 0 {
+			return ""
+		}
+		s := fields[0]
+		fields = fields[1:]
+		return s
+	}
+	number := func() (int, error) {
+		s := next()
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid number %q", s)
+		}
+		return n, nil
+	}
+
+	action := next()
+	if action == "seq" {
+		if rule.Seq, err = number(); err != nil {
+			return
+		}
+		action = next()
+	}
+
+	switch action {
+	case "permit":
+	case "deny":
+		rule.Deny = true
+	default:
+		return rule, fmt.Errorf("invalid action %q", action)
+	}
+
+	if rule.Prefix, err = netip.ParsePrefix(next()); err != nil {
+		return
+	}
+
+	for len(fields) > 0 {
+		switch kw := next(); kw {
+		case "ge":
+			rule.GE, err = number()
+		case "le":
+			rule.LE, err = number()
+		default:
+			err = fmt.Errorf("unexpected %q", kw)
+		}
+		if err != nil {
+			return
+		}
+	}
+
+	return rule, nil
+}
+
+// FprintCisco writes the prefix-list in Cisco IOS syntax to w.
+func (p *PrefixList) FprintCisco(w io.Writer) error {
+	for _, r := range p.Rules() {
+		ip := "ipv6"
+		if r.Prefix.Addr().Is4() {
+			ip = "ip"
+		}
+
+		action := "permit"
+		if r.Deny {
+			action = "deny"
+		}
+
+		var b strings.Builder
+		fmt.Fprintf(&b, "%s prefix-list %s seq %d %s %v", ip, p.name, r.Seq, action, r.Prefix)
+		if r.GE != 0 {
+			fmt.Fprintf(&b, " ge %d", r.GE)
+		}
+		if r.LE != 0 {
+			fmt.Fprintf(&b, " le %d", r.LE)
+		}
+
+		if _, err := fmt.Fprintln(w, b.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadJuniperPrefixLists parses route-filter-lists and prefix-lists in Junos set syntax,
+// the lists are returned in the order of their first line. All other lines are skipped.
+// The sequence numbers are given in steps of 5 in the order of the lines.
+//
+//	set policy-options route-filter-list NAME PREFIX {exact|orlonger|longer|upto /N|prefix-length-range /N-/M}
+//	set policy-options prefix-list NAME PREFIX
+//
+// Junos lists have no actions, all rules permit.
+func ReadJuniperPrefixLists(r io.Reader) ([]*PrefixList, error) {
+	var lists []*PrefixList
+	byName := make(map[string]*PrefixList)
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 5 || fields[0] != "set" || fields[1] != "policy-options" {
+			continue
+		}
+
+		var rule PrefixListRule
+		var err error
+
+		switch fields[2] {
+		case "prefix-list":
+			if len(fields) != 5 {
+				err = fmt.Errorf("unexpected %q", fields[5])
+				break
+			}
+			rule.Prefix, err = netip.ParsePrefix(fields[4])
+		case "route-filter-list":
+			rule, err = parseJuniperRule(fields[4:])
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("cidrtree: prefix-list line %d: %w", line, err)
+		}
+
+		name := fields[3]
+		p := byName[name]
+		if p == nil {
+			p = NewPrefixList(name)
+			byName[name] = p
+			lists = append(lists, p)
+		}
+
+		if err := p.Add(rule); err != nil {
+			return nil, err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("cidrtree: prefix-list: %w", err)
+	}
+
+	return lists, nil
+}
+
+// parseJuniperRule parses PREFIX MATCH-TYPE and maps the match type to ge and le.
+func parseJuniperRule(fields []string) (rule PrefixListRule, err error) {
+	if len(fields) < 2 {
+		return rule, fmt.Errorf("missing match type")
+	}
+	if rule.Prefix, err = netip.ParsePrefix(fields[0]); err != nil {
+		return
+	}
+
+	bits, maxBits := rule.Prefix.Bits(), rule.Prefix.Addr().BitLen()
+	length := func(s string) (int, error) {
+		n, err := strconv.Atoi(strings.TrimPrefix(s, "/"))
+		if err != nil || !strings.HasPrefix(s, "/") {
+			return 0, fmt.Errorf("invalid prefix length %q", s)
+		}
+		return n, nil
+	}
+
+	switch typ := fields[1]; {
+	case typ == "exact" && len(fields) == 2:
+	case typ == "orlonger" && len(fields) == 2:
+		if bits < maxBits {
+			rule.LE = maxBits
+		}
+	case typ == "longer" && len(fields) == 2:
+		if bits == maxBits {
+			return rule, fmt.Errorf("longer for host prefix %v", rule.Prefix)
+		}
+		rule.GE = bits + 1
+	case typ == "upto" && len(fields) == 3:
+		if rule.LE, err = length(fields[2]); err != nil {
+			return
+		}
+		if rule.LE == bits {
+			rule.LE = 0 // exact
+		}
+	case typ == "prefix-length-range" && len(fields) == 3:
+		lo, hi, found := strings.Cut(fields[2], "-")
+		if !found {
+			return rule, fmt.Errorf("invalid prefix-length-range %q", fields[2])
+		}
+		if rule.GE, err = length(lo); err != nil {
+			return
+		}
+		if rule.LE, err = length(hi); err != nil {
+			return
+		}
+		if rule.GE == bits {
+			rule.GE = 0 // same as le only
+		}
+		if rule.GE == 0 && rule.LE == bits {
+			rule.LE = 0 // exact
+		}
+	default:
+		return rule, fmt.Errorf("invalid match type %q", strings.Join(fields[1:], " "))
+	}
+
+	return rule, nil
+}
+
+// FprintJuniper writes the prefix-list as Junos route-filter-list in set syntax to w.
+// Junos route-filter-lists have no actions, an error is returned for deny rules
+// and nothing is written.
+func (p *PrefixList) FprintJuniper(w io.Writer) error {
+	rules := p.Rules()
+	for _, r := range rules {
+		if r.Deny {
+			return fmt.Errorf("cidrtree: prefix-list %s: deny rule seq %d not supported by route-filter-list", p.name, r.Seq)
+		}
+	}
+
+	for _, r := range rules {
+		bits, maxBits := r.Prefix.Bits(), r.Prefix.Addr().BitLen()
+		lo, hi := r.lengths()
+
+		var match string
+		switch {
+		case lo == bits && hi == bits:
+			match = "exact"
+		case lo == bits && hi == maxBits:
+			match = "orlonger"
+		case lo == bits+1 && hi == maxBits:
+			match = "longer"
+		case lo == bits:
+			match = fmt.Sprintf("upto /%d", hi)
+		default:
+			match = fmt.Sprintf("prefix-length-range /%d-/%d", lo, hi)
+		}
+
+		if _, err := fmt.Fprintf(w, "set policy-options route-filter-list %s %v %s\n", p.name, r.Prefix, match); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,194 @@
+package cidrtree_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gaissmai/cidrtree"
+)
+
+const ciscoPrefixLists = `!
+hostname edge1
+ip prefix-list CUSTOMER description customer routes
+ip prefix-list CUSTOMER seq 5 deny 10.1.0.0/16 le 32
+ip prefix-list CUSTOMER seq 10 permit 10.0.0.0/8 ge 16 le 24
+ip prefix-list CUSTOMER seq 15 permit 192.0.2.0/24
+ipv6 prefix-list CUSTOMER6 seq 5 permit 2001:db8::/32 ge 48
+ip prefix-list DEFAULT permit 0.0.0.0/0
+!
+`
+
+func TestReadCiscoPrefixLists(t *testing.T) {
+	t.Parallel()
+
+	lists, err := cidrtree.ReadCiscoPrefixLists(strings.NewReader(ciscoPrefixLists))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lists) != 3 {
+		t.Fatalf("ReadCiscoPrefixLists, got %d lists, want %d", len(lists), 3)
+	}
+
+	cust, cust6, dflt := lists[0], lists[1], lists[2]
+	if cust.Name() != "CUSTOMER" || cust6.Name() != "CUSTOMER6" || dflt.Name() != "DEFAULT" {
+		t.Fatalf("ReadCiscoPrefixLists, got names %s, %s, %s", cust.Name(), cust6.Name(), dflt.Name())
+	}
+
+	tcs := []struct {
+		list *cidrtree.PrefixList
+		pfx  string
+		want bool
+	}{
+		{cust, "10.0.0.0/8", false},
+		{cust, "10.2.0.0/16", true},
+		{cust, "10.2.3.0/24", true},
+		{cust, "10.2.3.0/25", false},
+		{cust, "10.1.2.0/24", false}, // seq 5 deny wins
+		{cust, "192.0.2.0/24", true},
+		{cust, "192.0.2.0/25", false},
+		{cust, "2001:db8::/48", false},
+		{cust6, "2001:db8:1::/48", true},
+		{cust6, "2001:db8:1::/64", true},
+		{cust6, "2001:db8::/32", false},
+		{dflt, "0.0.0.0/0", true},
+		{dflt, "10.0.0.0/8", false},
+	}
+
+	for _, tc := range tcs {
+		if got := tc.list.Permits(mustPfx(tc.pfx)); got != tc.want {
+			t.Errorf("%s.Permits(%s), got %v, want %v", tc.list.Name(), tc.pfx, got, tc.want)
+		}
+	}
+
+	// auto seq
+	if rules := dflt.Rules(); len(rules) != 1 || rules[0].Seq != 5 {
+		t.Errorf("auto seq, got %v", rules)
+	}
+}
+
+func TestReadCiscoPrefixListsError(t *testing.T) {
+	t.Parallel()
+
+	for _, s := range []string{
+		"ip prefix-list X seq 5 allow 10.0.0.0/8",
+		"ip prefix-list X seq 5 permit 10.0.0.0/8 ge 8",
+		"ip prefix-list X seq 5 permit 10.0.0.0/8 ge 24 le 16",
+		"ip prefix-list X seq 5 permit 10.0.0.0/8 le 33",
+		"ip prefix-list X permit 2001:db8::/32",
+		"ip prefix-list X permit 10.0.0.0/8 foo",
+	} {
+		if _, err := cidrtree.ReadCiscoPrefixLists(strings.NewReader(s)); err == nil {
+			t.Errorf("ReadCiscoPrefixLists(%q), expected error", s)
+		}
+	}
+}
+
+func TestFprintCisco(t *testing.T) {
+	t.Parallel()
+
+	lists, err := cidrtree.ReadCiscoPrefixLists(strings.NewReader(ciscoPrefixLists))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `ip prefix-list CUSTOMER seq 5 deny 10.1.0.0/16 le 32
+ip prefix-list CUSTOMER seq 10 permit 10.0.0.0/8 ge 16 le 24
+ip prefix-list CUSTOMER seq 15 permit 192.0.2.0/24
+`
+	w := new(strings.Builder)
+	if err := lists[0].FprintCisco(w); err != nil {
+		t.Fatal(err)
+	}
+	if w.String() != want {
+		t.Errorf("FprintCisco\nwant:\n%sgot:\n%s", want, w.String())
+	}
+
+	// replace by seq
+	if err := lists[0].Add(cidrtree.PrefixListRule{Seq: 10, Prefix: mustPfx("172.16.0.0/12")}); err != nil {
+		t.Fatal(err)
+	}
+	if lists[0].Permits(mustPfx("10.2.0.0/16")) || !lists[0].Permits(mustPfx("172.16.0.0/12")) {
+		t.Errorf("Add with existing seq, rule not replaced: %v", lists[0].Rules())
+	}
+}
+
+func TestJuniperPrefixLists(t *testing.T) {
+	t.Parallel()
+
+	in := `set policy-options route-filter-list RFL 10.0.0.0/8 exact
+set policy-options route-filter-list RFL 172.16.0.0/12 orlonger
+set policy-options route-filter-list RFL 192.168.0.0/16 longer
+set policy-options route-filter-list RFL 100.64.0.0/10 upto /24
+set policy-options route-filter-list RFL 2001:db8::/32 prefix-length-range /40-/48
+set policy-options prefix-list PL 198.51.100.0/24
+set interfaces ge-0/0/0 unit 0 family inet address 192.0.2.1/24
+`
+
+	lists, err := cidrtree.ReadJuniperPrefixLists(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lists) != 2 {
+		t.Fatalf("ReadJuniperPrefixLists, got %d lists, want %d", len(lists), 2)
+	}
+
+	rfl := lists[0]
+	tcs := []struct {
+		pfx  string
+		want bool
+	}{
+		{"10.0.0.0/8", true},
+		{"10.0.0.0/9", false},
+		{"172.16.0.0/12", true},
+		{"172.16.1.1/32", true},
+		{"192.168.0.0/16", false},
+		{"192.168.1.0/24", true},
+		{"100.64.0.0/10", true},
+		{"100.64.1.0/24", true},
+		{"100.64.1.0/25", false},
+		{"2001:db8::/32", false},
+		{"2001:db8:ff00::/40", true},
+		{"2001:db8:ff00::/56", false},
+	}
+	for _, tc := range tcs {
+		if got := rfl.Permits(mustPfx(tc.pfx)); got != tc.want {
+			t.Errorf("%s.Permits(%s), got %v, want %v", rfl.Name(), tc.pfx, got, tc.want)
+		}
+	}
+
+	// round trip
+	w := new(strings.Builder)
+	if err := rfl.FprintJuniper(w); err != nil {
+		t.Fatal(err)
+	}
+	if want := strings.Join(strings.Split(in, "\n")[:5], "\n") + "\n"; w.String() != want {
+		t.Errorf("FprintJuniper\nwant:\n%sgot:\n%s", want, w.String())
+	}
+
+	// cisco to juniper
+	cisco, err := cidrtree.ReadCiscoPrefixLists(strings.NewReader(ciscoPrefixLists))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cisco[0].FprintJuniper(new(strings.Builder)); err == nil {
+		t.Errorf("FprintJuniper with deny rule, expected error")
+	}
+
+	w.Reset()
+	if err := cisco[1].FprintJuniper(w); err != nil {
+		t.Fatal(err)
+	}
+	if want := "set policy-options route-filter-list CUSTOMER6 2001:db8::/32 prefix-length-range /48-/128\n"; w.String() != want {
+		t.Errorf("FprintJuniper\nwant:\n%sgot:\n%s", want, w.String())
+	}
+
+	// deny rule after permit rules, nothing is written
+	pl := cidrtree.NewPrefixList("MIXED")
+	_ = pl.Add(cidrtree.PrefixListRule{Prefix: mustPfx("10.0.0.0/8")})
+	_ = pl.Add(cidrtree.PrefixListRule{Prefix: mustPfx("10.1.0.0/16"), Deny: true})
+
+	w.Reset()
+	if err := pl.FprintJuniper(w); err == nil || w.Len() != 0 {
+		t.Errorf("FprintJuniper with trailing deny rule, got (%q, %v), want no output and error", w.String(), err)
+	}
+}
@@ -0,0 +1,256 @@
+package cidrtree
+
+import (
+	"fmt"
+	mrand "math/rand"
+	"net/netip"
+)
+
+// Ranges is a treap of arbitrary IP ranges [first, last], not aligned to CIDRs, e.g. threat-intel
+// or RIR feeds, where the split into CIDRs would explode the number of entries.
+//
+// The ranges may overlap. The lookup returns the most specific range, the covering range with the greatest
+// first address and for equal first addresses with the least last address. For nested ranges this is
+// the smallest covering range, the same semantics as the longest-prefix-match of [Table].
+//
+// The zero value is ready to use. Ranges is not safe for concurrent mutations.
+type Ranges[V any] struct {
+	root4 *rangeNode[V]
+	root6 *rangeNode[V]
+}
+
+// rangeNode is the recursive data structure of the range treap,
+// sorted by first ascending and last descending, the same order as the CIDRs in [Table].
+type rangeNode[V any] struct {
+	maxLast netip.Addr // augment the treap with the max last address in this subtree
+	left    *rangeNode[V]
+	right   *rangeNode[V]
+	first   netip.Addr
+	last    netip.Addr
+	value   V
+	prio    uint64
+	size    int
+}
+
+// Insert adds the range [first, last] with value, the value of a duplicate range is replaced.
+// An error is returned if first and last are invalid, of different IP versions or first > last.
+func (r *Ranges[V]) Insert(first, last netip.Addr, value V) error {
+	if err := checkRange(first, last); err != nil {
+		return err
+	}
+	first, last = first.Unmap(), last.Unmap()
+
+	root := r.rootFor(first)
+	left, mid, right := (*root).split(first, last)
+	if mid == nil {
+		mid = &rangeNode[V]{first: first, last: last, prio: mrand.Uint64()}
+	}
+	mid.value = value
+	mid.recalc()
+
+	*root = left.join(mid).join(right)
+	return nil
+}
+
+// Delete removes the range [first, last], returns false if the range isn't in the treap.
+func (r *Ranges[V]) Delete(first, last netip.Addr) bool {
+	if checkRange(first, last) != nil {
+		return false
+	}
+	first, last = first.Unmap(), last.Unmap()
+
+	root := r.rootFor(first)
+	left, mid, right := (*root).split(first, last)
+	*root = left.join(right)
+
+	return mid != nil
+}
+
+// Get returns the value of the range [first, last].
+func (r *Ranges[V]) Get(first, last netip.Addr) (value V, ok bool) {
+	if checkRange(first, last) != nil {
+		return
+	}
+	first, last = first.Unmap(), last.Unmap()
+
+	n := *r.rootFor(first)
+	for n != nil {
+		switch c := cmpRange(first, last, n.first, n.last); {
+		case c == 0:
+			return n.value, true
+		case c < 0:
+			n = n.left
+		default:
+			n = n.right
+		}
+	}
+	return
+}
+
+// Lookup returns the most specific range covering ip, see [Ranges].
+func (r *Ranges[V]) Lookup(ip netip.Addr) (first, last netip.Addr, value V, ok bool) {
+	if !ip.IsValid() {
+		return
+	}
+	ip = ip.Unmap()
+
+	if m := (*r.rootFor(ip)).lookup(ip); m != nil {
+		return m.first, m.last, m.value, true
+	}
+	return
+}
+
+// Walk iterates the ranges in ascending order, first the IPv4 then the IPv6 ranges.
+// If the callback function returns false, the walk is stopped.
+func (r *Ranges[V]) Walk(cb func(first, last netip.Addr, value V) bool) {
+	if r.root4.walk(cb) {
+		r.root6.walk(cb)
+	}
+}
+
+// Size returns the number of ranges.
+func (r *Ranges[V]) Size() int {
+	return r.root4.getSize() + r.root6.getSize()
+}
+
+// #####################################################################
+
+func checkRange(first, last netip.Addr) error {
+	if !first.IsValid() || !last.IsValid() {
+		return fmt.Errorf("cidrtree: invalid range [%v, %v]", first, last)
+	}
+	first, last = first.Unmap(), last.Unmap()
+
+	if first.Is4() != last.Is4() {
+		return fmt.Errorf("cidrtree: range [%v, %v] with mixed IP versions", first, last)
+	}
+	if first.Compare(last) > 0 {
+		return fmt.Errorf("cidrtree: range [%v, %v], first is greater than last", first, last)
+	}
+	return nil
+}
+
+func (r *Ranges[V]) rootFor(ip netip.Addr) **rangeNode[V] {
+	if ip.Is4() {
+		return &r.root4
+	}
+	return &r.root6
+}
+
+// cmpRange, first ascending, last descending, supersets before subsets.
+func cmpRange(aFirst, aLast, bFirst, bLast netip.Addr) int {
+	if c := aFirst.Compare(bFirst); c != 0 {
+		return c
+	}
+	return bLast.Compare(aLast)
+}
+
+// lookup returns the last node in sort order covering ip.
+func (n *rangeNode[V]) lookup(ip netip.Addr) *rangeNode[V] {
+	// no covering range in this subtree
+	if n == nil || n.maxLast.Less(ip) {
+		return nil
+	}
+
+	// all ranges in this node and the right subtree start after ip
+	if ip.Less(n.first) {
+		return n.left.lookup(ip)
+	}
+
+	// the right subtree has greater first, or less last for equal first
+	if m := n.right.lookup(ip); m != nil {
+		return m
+	}
+
+	if !n.last.Less(ip) {
+		return n
+	}
+
+	return n.left.lookup(ip)
+}
+
+// split the treap by the range key into left, mid and right, mid is the node with the same key or nil.
+func (n *rangeNode[V]) split(first, last netip.Addr) (left, mid, right *rangeNode[V]) {
+	if n == nil {
+		return nil, nil, nil
+	}
+
+	// never mutate, always clone
+	n = n.copyNode()
+
+	switch c := cmpRange(first, last, n.first, n.last); {
+	case c == 0:
+		left, right = n.left, n.right
+		n.left, n.right = nil, nil
+		n.recalc()
+		return left, n, right
+	case c < 0:
+		l, m, r := n.left.split(first, last)
+		n.left = r
+		n.recalc()
+		return l, m, n
+	default:
+		l, m, r := n.right.split(first, last)
+		n.right = l
+		n.recalc()
+		return n, m, r
+	}
+}
+
+// join two treaps, all keys in n are less than the keys in m.
+func (n *rangeNode[V]) join(m *rangeNode[V]) *rangeNode[V] {
+	if n == nil {
+		return m
+	}
+	if m == nil {
+		return n
+	}
+
+	if n.prio > m.prio {
+		n = n.copyNode()
+		n.right = n.right.join(m)
+		n.recalc()
+		return n
+	}
+
+	m = m.copyNode()
+	m.left = n.join(m.left)
+	m.recalc()
+	return m
+}
+
+func (n *rangeNode[V]) walk(cb func(first, last netip.Addr, value V) bool) bool {
+	if n == nil {
+		return true
+	}
+	return n.left.walk(cb) && cb(n.first, n.last, n.value) && n.right.walk(cb)
+}
+
+func (n *rangeNode[V]) copyNode() *rangeNode[V] {
+	c := *n
+	return &c
+}
+
+func (n *rangeNode[V]) getSize() int {
+	if n == nil {
+		return 0
+	}
+	return n.size
+}
+
+// recalc the augmented fields, only one level deeper must be considered.
+func (n *rangeNode[V]) recalc() {
+	if n == nil {
+		return
+	}
+
+	n.size = 1 + n.left.getSize() + n.right.getSize()
+
+	n.maxLast = n.last
+	if n.left != nil && n.maxLast.Less(n.left.maxLast) {
+		n.maxLast = n.left.maxLast
+	}
+	if n.right != nil && n.maxLast.Less(n.right.maxLast) {
+		n.maxLast = n.right.maxLast
+	}
+}
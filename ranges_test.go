@@ -0,0 +1,121 @@
+package cidrtree_test
+
+import (
+	"math/rand"
+	"net/netip"
+	"testing"
+
+	"github.com/gaissmai/cidrtree"
+)
+
+func TestRangesLookup(t *testing.T) {
+	t.Parallel()
+
+	var r cidrtree.Ranges[string]
+	for _, tt := range []struct{ first, last, value string }{
+		{"10.0.0.0", "10.255.255.255", "A"},
+		{"10.0.0.5", "10.0.0.17", "B"},
+		{"10.0.0.10", "10.0.0.12", "C"},
+		{"10.0.0.15", "10.0.0.40", "D"}, // partial overlap with B
+		{"2001:db8::1", "2001:db8::ffff", "E"},
+	} {
+		if err := r.Insert(mustAddr(tt.first), mustAddr(tt.last), tt.value); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		ip   string
+		want string
+		ok   bool
+	}{
+		{"9.255.255.255", "", false},
+		{"10.0.0.1", "A", true},
+		{"10.0.0.5", "B", true},
+		{"10.0.0.11", "C", true},
+		{"10.0.0.13", "B", true},
+		{"10.0.0.16", "D", true},
+		{"10.0.0.41", "A", true},
+		{"::ffff:10.0.0.11", "C", true},
+		{"2001:db8::", "", false},
+		{"2001:db8::2", "E", true},
+	}
+
+	for _, tt := range tests {
+		_, _, got, ok := r.Lookup(mustAddr(tt.ip))
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("Lookup(%s), got (%q, %v), want (%q, %v)", tt.ip, got, ok, tt.want, tt.ok)
+		}
+	}
+
+	if r.Size() != 5 {
+		t.Errorf("Size(), got %d, want %d", r.Size(), 5)
+	}
+
+	if !r.Delete(mustAddr("10.0.0.10"), mustAddr("10.0.0.12")) {
+		t.Errorf("Delete, got false, want true")
+	}
+	if _, _, got, _ := r.Lookup(mustAddr("10.0.0.11")); got != "B" {
+		t.Errorf("Lookup after Delete, got %q, want %q", got, "B")
+	}
+	if _, ok := r.Get(mustAddr("10.0.0.10"), mustAddr("10.0.0.12")); ok {
+		t.Errorf("Get after Delete, got true, want false")
+	}
+}
+
+func TestRangesInsertError(t *testing.T) {
+	t.Parallel()
+
+	var r cidrtree.Ranges[any]
+	for _, tt := range [][2]netip.Addr{
+		{{}, mustAddr("10.0.0.1")},
+		{mustAddr("10.0.0.1"), mustAddr("::1")},
+		{mustAddr("10.0.0.2"), mustAddr("10.0.0.1")},
+	} {
+		if err := r.Insert(tt[0], tt[1], nil); err == nil {
+			t.Errorf("Insert(%v, %v), expected error", tt[0], tt[1])
+		}
+	}
+}
+
+// compare the lookup against a brute force linear scan
+func TestRangesLookupRandom(t *testing.T) {
+	t.Parallel()
+
+	type item struct{ first, last netip.Addr }
+	var items []item
+
+	var r cidrtree.Ranges[int]
+	for i := 0; i < 1_000; i++ {
+		a := netip.AddrFrom4([4]byte{10, byte(rand.Intn(4)), byte(rand.Intn(256)), 0})
+		b := netip.AddrFrom4([4]byte{10, byte(rand.Intn(4)), byte(rand.Intn(256)), 255})
+		if b.Less(a) {
+			a, b = netip.AddrFrom4([4]byte{10, b.As4()[1], b.As4()[2], 0}), netip.AddrFrom4([4]byte{10, a.As4()[1], a.As4()[2], 255})
+		}
+		if _, ok := r.Get(a, b); ok {
+			continue
+		}
+		_ = r.Insert(a, b, i)
+		items = append(items, item{a, b})
+	}
+
+	for i := 0; i < 2_000; i++ {
+		ip := netip.AddrFrom4([4]byte{10, byte(rand.Intn(4)), byte(rand.Intn(256)), byte(rand.Intn(256))})
+
+		var want item
+		var wantOK bool
+		for _, it := range items {
+			if it.first.Compare(ip) > 0 || it.last.Less(ip) {
+				continue
+			}
+			if !wantOK || want.first.Less(it.first) || (want.first == it.first && it.last.Less(want.last)) {
+				want, wantOK = it, true
+			}
+		}
+
+		first, last, _, ok := r.Lookup(ip)
+		if ok != wantOK || first != want.first || last != want.last {
+			t.Fatalf("Lookup(%v), got ([%v, %v], %v), want ([%v, %v], %v)", ip, first, last, ok, want.first, want.last, wantOK)
+		}
+	}
+}
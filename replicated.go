@@ -0,0 +1,150 @@
+package cidrtree
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"net/netip"
+)
+
+// StateMachine is the interface of a replicated state machine as expected by raft-style
+// consensus libraries. The committed log entries are applied in the same order on all
+// replicas, snapshots compact the log and bring up new replicas.
+type StateMachine interface {
+	// Apply applies a committed log entry.
+	Apply(entry []byte) error
+
+	// Snapshot returns a point-in-time snapshot, it may be written while entries are applied.
+	Snapshot() (io.WriterTo, error)
+
+	// Restore replaces the state with the snapshot read from r.
+	Restore(r io.Reader) error
+}
+
+var _ StateMachine = (*Replicated[any])(nil)
+
+// Replicated is a routing table replicated by a consensus log, it implements [StateMachine].
+// The mutations are proposed to the log as entries from EncodeInsert and EncodeDelete and
+// applied to the [Atomic] table when committed. The lookups are served lock-free by the table.
+//
+// The entries and snapshots reuse the record format of the [WAL], a snapshot is a WAL
+// with the inserts of all entries.
+type Replicated[V any] struct {
+	table  *Atomic[V]
+	encode func(V) ([]byte, error)
+	decode func([]byte) (V, error)
+}
+
+// NewReplicated returns the state machine for table, the values are serialized with encode and decode.
+func NewReplicated[V any](table *Atomic[V], encode func(V) ([]byte, error), decode func([]byte) (V, error)) *Replicated[V] {
+	return &Replicated[V]{table: table, encode: encode, decode: decode}
+}
+
+// EncodeInsert returns the log entry for the insert of pfx with value.
+func (r *Replicated[V]) EncodeInsert(pfx netip.Prefix, value V) ([]byte, error) {
+	if !pfx.IsValid() {
+		return nil, fmt.Errorf("cidrtree: replicated: invalid prefix")
+	}
+
+	data, err := r.encode(value)
+	if err != nil {
+		return nil, fmt.Errorf("cidrtree: replicated: %w", err)
+	}
+	return appendWALRecord(nil, appendWALPayload(nil, walInsert, pfx.Masked(), data)), nil
+}
+
+// EncodeDelete returns the log entry for the delete of pfx.
+func (r *Replicated[V]) EncodeDelete(pfx netip.Prefix) ([]byte, error) {
+	if !pfx.IsValid() {
+		return nil, fmt.Errorf("cidrtree: replicated: invalid prefix")
+	}
+	return appendWALRecord(nil, appendWALPayload(nil, walDelete, pfx.Masked(), nil)), nil
+}
+
+// Apply applies a committed log entry to the table, the table isn't changed for malformed entries.
+func (r *Replicated[V]) Apply(entry []byte) error {
+	size, n := binary.Uvarint(entry)
+	if n <= 0 || size > maxWALRecord || size > uint64(len(entry)) || uint64(len(entry)-n) != size+4 {
+		return errors.New("cidrtree: replicated: malformed entry")
+	}
+
+	payload, sum := entry[n:n+int(size)], binary.BigEndian.Uint32(entry[n+int(size):])
+	if crc32.ChecksumIEEE(payload) != sum {
+		return errors.New("cidrtree: replicated: checksum mismatch")
+	}
+
+	op, pfx, value, err := decodeWAL(payload, r.decode)
+	if err != nil {
+		return fmt.Errorf("cidrtree: replicated: %w", err)
+	}
+
+	switch op {
+	case walInsert:
+		r.table.Insert(pfx, value)
+	case walDelete:
+		r.table.Delete(pfx)
+	}
+	return nil
+}
+
+// Snapshot returns the current table as snapshot. It is free, the published tables
+// of Atomic are immutable, the entries are encoded when the snapshot is written.
+func (r *Replicated[V]) Snapshot() (io.WriterTo, error) {
+	return replicatedSnapshot[V]{table: r.table.Load(), encode: r.encode}, nil
+}
+
+// Restore replaces the table with the snapshot, see [Table.Replay].
+// The table isn't changed if the snapshot can't be read completely.
+func (r *Replicated[V]) Restore(rd io.Reader) error {
+	t := new(Table[V])
+	if _, err := t.Replay(rd, r.decode); err != nil {
+		return err
+	}
+	r.table.Store(t)
+	return nil
+}
+
+// replicatedSnapshot is a frozen table, written as WAL with the inserts of all entries.
+type replicatedSnapshot[V any] struct {
+	table  *Table[V]
+	encode func(V) ([]byte, error)
+}
+
+// WriteTo implements the [io.WriterTo] interface.
+func (s replicatedSnapshot[V]) WriteTo(w io.Writer) (int64, error) {
+	cw := &countWriter{w: w}
+	bw := bufio.NewWriter(cw)
+
+	l := &WAL[V]{w: bw, encode: s.encode}
+
+	var err error
+	s.table.Walk(func(pfx netip.Prefix, value V) bool {
+		var data []byte
+		if data, err = s.encode(value); err != nil {
+			err = fmt.Errorf("cidrtree: replicated: %w", err)
+			return false
+		}
+		err = l.write(walInsert, pfx, data)
+		return err == nil
+	})
+
+	if err == nil {
+		err = bw.Flush()
+	}
+	return cw.n, err
+}
+
+// countWriter counts the bytes written to w.
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
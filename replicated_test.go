@@ -0,0 +1,89 @@
+package cidrtree_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net/netip"
+	"testing"
+
+	"github.com/gaissmai/cidrtree"
+)
+
+func TestReplicated(t *testing.T) {
+	t.Parallel()
+
+	var leader, follower cidrtree.Atomic[int]
+	smLeader := cidrtree.NewReplicated(&leader, encodeInt, decodeInt)
+	smFollower := cidrtree.NewReplicated(&follower, encodeInt, decodeInt)
+
+	// the committed log
+	var log [][]byte
+	for i, s := range []string{"10.0.0.0/8", "10.0.1.0/24", "::/0", "2001:db8::/32"} {
+		entry, err := smLeader.EncodeInsert(mustPfx(s), i+1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		log = append(log, entry)
+	}
+	entry, _ := smLeader.EncodeDelete(mustPfx("10.0.1.0/24"))
+	log = append(log, entry)
+
+	for _, sm := range []cidrtree.StateMachine{smLeader, smFollower} {
+		for _, entry := range log {
+			if err := sm.Apply(entry); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	if follower.Load().String() != leader.Load().String() || leader.Load().Size() != 3 {
+		t.Errorf("Apply, got:\n%v\nwant:\n%v", follower.Load(), leader.Load())
+	}
+
+	// malformed entries
+	corrupt := bytes.Clone(log[0])
+	corrupt[3] ^= 0xff
+	// size+4 wraps around to 0
+	overflow := binary.AppendUvarint(nil, 1<<64-4)
+
+	for _, bad := range [][]byte{nil, corrupt, overflow, log[0][:len(log[0])-1], append(bytes.Clone(log[0]), 0)} {
+		if err := smFollower.Apply(bad); err == nil {
+			t.Errorf("Apply(%v), expected error", bad)
+		}
+	}
+	if _, err := smLeader.EncodeDelete(netip.Prefix{}); err == nil {
+		t.Errorf("EncodeDelete(invalid), expected error")
+	}
+
+	// snapshot, the leader changes while the snapshot is written
+	snap, err := smLeader.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := leader.Load().String()
+
+	entry, _ = smLeader.EncodeInsert(mustPfx("192.168.0.0/16"), 42)
+	_ = smLeader.Apply(entry)
+
+	buf := new(bytes.Buffer)
+	if n, err := snap.WriteTo(buf); err != nil || n != int64(buf.Len()) {
+		t.Fatalf("WriteTo, got (%d, %v), want (%d, nil)", n, err, buf.Len())
+	}
+
+	var fresh cidrtree.Atomic[int]
+	smFresh := cidrtree.NewReplicated(&fresh, encodeInt, decodeInt)
+	if err := smFresh.Restore(bytes.NewReader(buf.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+	if got := fresh.Load().String(); got != want {
+		t.Errorf("Restore, got:\n%v\nwant:\n%v", got, want)
+	}
+
+	// a torn snapshot doesn't change the table
+	if err := smFresh.Restore(bytes.NewReader(buf.Bytes()[:buf.Len()-2])); err == nil {
+		t.Errorf("Restore of torn snapshot, expected error")
+	}
+	if got := fresh.Load().String(); got != want {
+		t.Errorf("Restore of torn snapshot, got:\n%v\nwant:\n%v", got, want)
+	}
+}
@@ -0,0 +1,42 @@
+package cidrtree
+
+import (
+	"fmt"
+	"net/netip"
+)
+
+// Resolve resolves the next hop for ip recursively, as for a FIB from a RIB with recursive routes.
+// The longest-prefix-match for ip is looked up, nextHop returns the next hop of the matched value
+// or connected true for a directly connected route. The next hop is looked up again, until
+// a directly connected route is found. For a route learned in another table, e.g. BGP routes
+// with IGP next hops, resolve the next hop of the route in the IGP table.
+//
+// Returns the resolved on-link address and the chain of matched prefixes.
+// An error is returned if an address is unresolvable or the resolution loops.
+func (t Table[V]) Resolve(ip netip.Addr, nextHop func(V) (hop netip.Addr, connected bool)) (netip.Addr, []netip.Prefix, error) {
+	var path []netip.Prefix
+	seen := make(map[netip.Prefix]bool)
+
+	for {
+		lpm, value, ok := t.Lookup(ip)
+		if !ok {
+			return netip.Addr{}, path, fmt.Errorf("cidrtree: next hop %v unresolvable", ip)
+		}
+
+		if seen[lpm] {
+			return netip.Addr{}, path, fmt.Errorf("cidrtree: next hop %v loops via %v", ip, lpm)
+		}
+		seen[lpm] = true
+		path = append(path, lpm)
+
+		hop, connected := nextHop(value)
+		if connected {
+			return ip, path, nil
+		}
+		if !hop.IsValid() {
+			return netip.Addr{}, path, fmt.Errorf("cidrtree: route %v without next hop", lpm)
+		}
+
+		ip = hop
+	}
+}
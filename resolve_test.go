@@ -0,0 +1,52 @@
+package cidrtree_test
+
+import (
+	"net/netip"
+	"reflect"
+	"testing"
+
+	"github.com/gaissmai/cidrtree"
+)
+
+func TestResolve(t *testing.T) {
+	t.Parallel()
+
+	// the zero address is a directly connected route
+	rib := new(cidrtree.Table[netip.Addr])
+	rib.Insert(mustPfx("192.0.2.0/24"), netip.Addr{})
+	rib.Insert(mustPfx("10.0.0.0/8"), mustAddr("192.0.2.1"))
+	rib.Insert(mustPfx("0.0.0.0/0"), mustAddr("10.1.1.1"))
+	rib.Insert(mustPfx("198.51.100.0/24"), mustAddr("198.51.100.1"))
+	rib.Insert(mustPfx("203.0.113.0/24"), mustAddr("2001:db8::1"))
+
+	nextHop := func(v netip.Addr) (netip.Addr, bool) { return v, !v.IsValid() }
+
+	hop, path, err := rib.Resolve(mustAddr("8.8.8.8"), nextHop)
+	if err != nil {
+		t.Fatalf("Resolve(%v), got error %v", "8.8.8.8", err)
+	}
+	if hop != mustAddr("192.0.2.1") {
+		t.Errorf("Resolve(%v), got hop %v, want %v", "8.8.8.8", hop, "192.0.2.1")
+	}
+
+	want := []netip.Prefix{mustPfx("0.0.0.0/0"), mustPfx("10.0.0.0/8"), mustPfx("192.0.2.0/24")}
+	if !reflect.DeepEqual(path, want) {
+		t.Errorf("Resolve(%v), got path %v, want %v", "8.8.8.8", path, want)
+	}
+
+	// directly connected
+	hop, _, err = rib.Resolve(mustAddr("192.0.2.99"), nextHop)
+	if err != nil || hop != mustAddr("192.0.2.99") {
+		t.Errorf("Resolve(%v), got %v, %v, want %v", "192.0.2.99", hop, err, "192.0.2.99")
+	}
+
+	// next hop resolves via its own route
+	if _, _, err := rib.Resolve(mustAddr("198.51.100.7"), nextHop); err == nil {
+		t.Errorf("Resolve(%v), loop, got nil error", "198.51.100.7")
+	}
+
+	// no IPv6 routes
+	if _, _, err := rib.Resolve(mustAddr("203.0.113.1"), nextHop); err == nil {
+		t.Errorf("Resolve(%v), unresolvable, got nil error", "203.0.113.1")
+	}
+}
@@ -0,0 +1,177 @@
+package cidrtree
+
+import (
+	"net/netip"
+	"slices"
+)
+
+// Path is a route for a prefix learned from a named source, e.g. static, a BGP peer or an IGP.
+type Path[V any] struct {
+	Source string
+	Value  V
+}
+
+// RIB is a routing information base with routes for the same prefix from many sources.
+// All paths are kept, the best path per prefix decides what Lookup returns.
+// A withdrawn best path is replaced by the next best path of the prefix.
+//
+// The zero value is ready to use, only the mutable API is supported.
+type RIB[V any] struct {
+	// Better reports whether path a is preferred over path b, e.g. by administrative distance.
+	// Nil prefers the path learned first.
+	Better func(a, b Path[V]) bool
+
+	// OnBestChange is called if the best path of pfx changes, that is another source wins or
+	// the value of the best source is replaced. If the last path of pfx is withdrawn, ok is false.
+	OnBestChange func(pfx netip.Prefix, best Path[V], ok bool)
+
+	table   Table[[]Path[V]] // sorted, best path first
+	sources map[string]map[netip.Prefix]struct{}
+}
+
+// Insert adds or replaces the path of source for pfx and selects the best path.
+func (r *RIB[V]) Insert(pfx netip.Prefix, source string, value V) {
+	pfx = pfx.Masked() // always canonicalize!
+
+	old, _ := r.table.get(pfx)
+
+	// copy-on-write, the old paths are compared below
+	paths := slices.Clone(old)
+	path := Path[V]{Source: source, Value: value}
+
+	// replace in place, keep the order for ties
+	if i := slices.IndexFunc(paths, func(p Path[V]) bool { return p.Source == source }); i >= 0 {
+		paths[i] = path
+	} else {
+		paths = append(paths, path)
+	}
+	r.sort(paths)
+
+	r.table.Insert(pfx, paths)
+
+	if r.sources == nil {
+		r.sources = make(map[string]map[netip.Prefix]struct{})
+	}
+	if r.sources[source] == nil {
+		r.sources[source] = make(map[netip.Prefix]struct{})
+	}
+	r.sources[source][pfx] = struct{}{}
+
+	// the best path changed, if the best source is replaced or another source wins
+	if len(old) == 0 || paths[0].Source == source || old[0].Source != paths[0].Source {
+		r.notify(pfx, paths)
+	}
+}
+
+// Delete withdraws the path of source for pfx, returns false if there is no such path.
+func (r *RIB[V]) Delete(pfx netip.Prefix, source string) bool {
+	pfx = pfx.Masked() // always canonicalize!
+
+	if _, ok := r.sources[source][pfx]; !ok {
+		return false
+	}
+
+	r.withdraw(pfx, source)
+	return true
+}
+
+// DeleteSource withdraws all paths of source, e.g. for a BGP session down.
+// Returns the number of withdrawn paths.
+func (r *RIB[V]) DeleteSource(source string) int {
+	set := r.sources[source]
+	n := len(set)
+	for pfx := range set {
+		r.withdraw(pfx, source)
+	}
+	return n
+}
+
+// Lookup returns the longest-prefix-match for ip with its best path, see [Table.Lookup].
+func (r *RIB[V]) Lookup(ip netip.Addr) (lpm netip.Prefix, best Path[V], ok bool) {
+	lpm, paths, ok := r.table.Lookup(ip)
+	if !ok {
+		return
+	}
+	return lpm, paths[0], true
+}
+
+// LookupPrefix returns the longest-prefix-match for pfx with its best path, see [Table.LookupPrefix].
+func (r *RIB[V]) LookupPrefix(pfx netip.Prefix) (lpm netip.Prefix, best Path[V], ok bool) {
+	lpm, paths, ok := r.table.LookupPrefix(pfx)
+	if !ok {
+		return
+	}
+	return lpm, paths[0], true
+}
+
+// Paths returns all paths for pfx, the best path first.
+func (r *RIB[V]) Paths(pfx netip.Prefix) []Path[V] {
+	paths, _ := r.table.get(pfx.Masked())
+	return slices.Clone(paths)
+}
+
+// Walk iterates all prefixes with their best path in ascending order, see [Table.Walk].
+// If callback returns `false`, the iteration is aborted.
+func (r *RIB[V]) Walk(cb func(pfx netip.Prefix, best Path[V]) bool) {
+	r.table.Walk(func(pfx netip.Prefix, paths []Path[V]) bool {
+		return cb(pfx, paths[0])
+	})
+}
+
+// Size returns the number of prefixes in the RIB.
+func (r *RIB[V]) Size() int {
+	return r.table.Size()
+}
+
+// withdraw the existing path of source for pfx.
+func (r *RIB[V]) withdraw(pfx netip.Prefix, source string) {
+	set := r.sources[source]
+	delete(set, pfx)
+	if len(set) == 0 {
+		delete(r.sources, source)
+	}
+
+	old, _ := r.table.get(pfx)
+	paths := slices.DeleteFunc(slices.Clone(old), func(p Path[V]) bool { return p.Source == source })
+
+	if len(paths) == 0 {
+		r.table.Delete(pfx)
+		r.notify(pfx, nil)
+		return
+	}
+
+	r.table.Insert(pfx, paths)
+	if old[0].Source == source {
+		r.notify(pfx, paths)
+	}
+}
+
+// sort the paths stable, the best path first.
+func (r *RIB[V]) sort(paths []Path[V]) {
+	if r.Better == nil {
+		return
+	}
+
+	slices.SortStableFunc(paths, func(a, b Path[V]) int {
+		switch {
+		case r.Better(a, b):
+			return -1
+		case r.Better(b, a):
+			return 1
+		}
+		return 0
+	})
+}
+
+// notify the best path change of pfx.
+func (r *RIB[V]) notify(pfx netip.Prefix, paths []Path[V]) {
+	if r.OnBestChange == nil {
+		return
+	}
+
+	if len(paths) == 0 {
+		r.OnBestChange(pfx, Path[V]{}, false)
+		return
+	}
+	r.OnBestChange(pfx, paths[0], true)
+}
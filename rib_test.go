@@ -0,0 +1,108 @@
+package cidrtree_test
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/gaissmai/cidrtree"
+)
+
+func TestRIB(t *testing.T) {
+	t.Parallel()
+
+	// administrative distance per source
+	distance := map[string]int{"static": 1, "ebgp": 20, "ospf": 110}
+
+	type change struct {
+		pfx    netip.Prefix
+		source string
+		ok     bool
+	}
+	var changes []change
+
+	rib := &cidrtree.RIB[netip.Addr]{
+		Better: func(a, b cidrtree.Path[netip.Addr]) bool {
+			return distance[a.Source] < distance[b.Source]
+		},
+		OnBestChange: func(pfx netip.Prefix, best cidrtree.Path[netip.Addr], ok bool) {
+			changes = append(changes, change{pfx, best.Source, ok})
+		},
+	}
+
+	pfx := mustPfx("10.0.0.0/8")
+
+	rib.Insert(pfx, "ospf", mustAddr("192.0.2.1"))
+	rib.Insert(pfx, "ebgp", mustAddr("192.0.2.2"))
+	rib.Insert(pfx, "ospf", mustAddr("192.0.2.3")) // no flip, not the best path
+	rib.Insert(mustPfx("10.1.0.0/16"), "ospf", mustAddr("192.0.2.1"))
+
+	if _, best, _ := rib.Lookup(mustAddr("10.0.0.1")); best.Source != "ebgp" {
+		t.Errorf("Lookup, got best source %v, want %v", best.Source, "ebgp")
+	}
+
+	if paths := rib.Paths(pfx); len(paths) != 2 || paths[1].Value != mustAddr("192.0.2.3") {
+		t.Errorf("Paths(%v), got %v", pfx, paths)
+	}
+
+	rib.Insert(pfx, "static", mustAddr("192.0.2.4"))
+
+	if ok := rib.Delete(pfx, "rip"); ok {
+		t.Errorf("Delete(%v, %v), got %v, want false", pfx, "rip", ok)
+	}
+
+	// withdraw the best path, the next best path wins
+	rib.Delete(pfx, "static")
+
+	if n := rib.DeleteSource("ospf"); n != 2 {
+		t.Errorf("DeleteSource(%v), got %v, want %v", "ospf", n, 2)
+	}
+
+	if lpm, best, _ := rib.LookupPrefix(mustPfx("10.1.1.0/24")); lpm != pfx || best.Source != "ebgp" {
+		t.Errorf("LookupPrefix, got %v %v, want %v %v", lpm, best.Source, pfx, "ebgp")
+	}
+
+	rib.Delete(pfx, "ebgp")
+
+	if n := rib.Size(); n != 0 {
+		t.Errorf("Size, got %v, want %v", n, 0)
+	}
+
+	want := []change{
+		{pfx, "ospf", true},
+		{pfx, "ebgp", true},
+		{mustPfx("10.1.0.0/16"), "ospf", true},
+		{pfx, "static", true},
+		{pfx, "ebgp", true},
+		{mustPfx("10.1.0.0/16"), "", false},
+		{pfx, "", false},
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("OnBestChange, got %v, want %v", changes, want)
+	}
+	for i := range want {
+		if changes[i] != want[i] {
+			t.Errorf("OnBestChange[%d], got %v, want %v", i, changes[i], want[i])
+		}
+	}
+}
+
+func TestRIBFirstLearned(t *testing.T) {
+	t.Parallel()
+
+	rib := new(cidrtree.RIB[int])
+	pfx := mustPfx("2001:db8::/32")
+
+	rib.Insert(pfx, "a", 1)
+	rib.Insert(pfx, "b", 2)
+	rib.Insert(pfx, "a", 3)
+
+	var got []cidrtree.Path[int]
+	rib.Walk(func(_ netip.Prefix, best cidrtree.Path[int]) bool {
+		got = append(got, best)
+		return true
+	})
+
+	if len(got) != 1 || got[0] != (cidrtree.Path[int]{Source: "a", Value: 3}) {
+		t.Errorf("Walk, got %v, want best path %v", got, "a")
+	}
+}
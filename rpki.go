@@ -0,0 +1,138 @@
+package cidrtree
+
+import (
+	"net/netip"
+	"slices"
+)
+
+// VRP is a validated ROA payload, the origin ASN authorized to announce Prefix
+// and its more specifics up to MaxLength.
+type VRP struct {
+	Prefix    netip.Prefix
+	MaxLength int
+	ASN       uint32
+}
+
+// Validity is the route origin validation state of a route, see RFC 6811.
+type Validity int
+
+const (
+	// NotFound routes are not covered by any VRP.
+	NotFound Validity = iota + 1
+
+	// Valid routes are matched by at least one covering VRP.
+	Valid
+
+	// Invalid routes are covered by VRPs, but none of them matches the origin ASN and prefix length.
+	Invalid
+)
+
+// String implements the fmt.Stringer interface.
+func (v Validity) String() string {
+	switch v {
+	case NotFound:
+		return "not-found"
+	case Valid:
+		return "valid"
+	case Invalid:
+		return "invalid"
+	default:
+		return "unknown"
+	}
+}
+
+// VRPTable is a store of VRPs for route origin validation.
+//
+// The zero value is ready to use, only the mutable API is supported.
+type VRPTable struct {
+	table Table[[]VRP]
+	size  int
+}
+
+// Insert adds the vrp, returns false if the vrp is already present.
+func (vt *VRPTable) Insert(vrp VRP) bool {
+	vrp.Prefix = vrp.Prefix.Masked() // always canonicalize!
+
+	old, _ := vt.table.get(vrp.Prefix)
+	if slices.Contains(old, vrp) {
+		return false
+	}
+
+	// copy-on-write, views of the table share the slices
+	vt.table.Insert(vrp.Prefix, append(slices.Clone(old), vrp))
+	vt.size++
+	return true
+}
+
+// Delete removes the vrp, returns false if the vrp isn't present.
+func (vt *VRPTable) Delete(vrp VRP) bool {
+	vrp.Prefix = vrp.Prefix.Masked() // always canonicalize!
+
+	old, _ := vt.table.get(vrp.Prefix)
+	i := slices.Index(old, vrp)
+	if i < 0 {
+		return false
+	}
+
+	vt.size--
+	if len(old) == 1 {
+		vt.table.Delete(vrp.Prefix)
+		return true
+	}
+
+	vt.table.Insert(vrp.Prefix, slices.Delete(slices.Clone(old), i, i+1))
+	return true
+}
+
+// Validate returns the origin validation state of the route pfx announced by asn, see RFC 6811.
+//
+// All VRPs with a prefix covering pfx are candidates, a candidate matches if its ASN is asn
+// and the length of pfx doesn't exceed the MaxLength. A VRP for AS 0 never matches.
+// In contrast to a plain longest-prefix-match, any covering VRP may validate the route,
+// not only the most specific one.
+func (vt *VRPTable) Validate(pfx netip.Prefix, asn uint32) Validity {
+	pfx = pfx.Masked() // always canonicalize!
+
+	covering := false
+	match := func(vrps []VRP) bool {
+		for _, vrp := range vrps {
+			covering = true
+			if asn != 0 && vrp.ASN == asn && pfx.Bits() <= vrp.MaxLength {
+				return true
+			}
+		}
+		return false
+	}
+
+	if vrps, ok := vt.table.get(pfx); ok && match(vrps) {
+		return Valid
+	}
+	for _, n := range vt.table.supernets(pfx) {
+		if match(n.value) {
+			return Valid
+		}
+	}
+
+	if covering {
+		return Invalid
+	}
+	return NotFound
+}
+
+// Walk iterates all VRPs in ascending order of the prefixes, see [Table.Walk].
+// If callback returns `false`, the iteration is aborted.
+func (vt *VRPTable) Walk(cb func(vrp VRP) bool) {
+	vt.table.Walk(func(_ netip.Prefix, vrps []VRP) bool {
+		for _, vrp := range vrps {
+			if !cb(vrp) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// Size returns the number of VRPs.
+func (vt *VRPTable) Size() int {
+	return vt.size
+}
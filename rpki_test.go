@@ -0,0 +1,70 @@
+package cidrtree_test
+
+import (
+	"testing"
+
+	"github.com/gaissmai/cidrtree"
+)
+
+func TestVRPTableValidate(t *testing.T) {
+	t.Parallel()
+
+	vt := new(cidrtree.VRPTable)
+	vt.Insert(cidrtree.VRP{Prefix: mustPfx("192.0.2.0/24"), MaxLength: 24, ASN: 64500})
+	vt.Insert(cidrtree.VRP{Prefix: mustPfx("198.51.100.0/22"), MaxLength: 24, ASN: 64501})
+	vt.Insert(cidrtree.VRP{Prefix: mustPfx("198.51.100.0/24"), MaxLength: 24, ASN: 64502})
+	vt.Insert(cidrtree.VRP{Prefix: mustPfx("203.0.113.0/24"), MaxLength: 24, ASN: 0})
+	vt.Insert(cidrtree.VRP{Prefix: mustPfx("2001:db8::/32"), MaxLength: 48, ASN: 64500})
+
+	if ok := vt.Insert(cidrtree.VRP{Prefix: mustPfx("192.0.2.17/24"), MaxLength: 24, ASN: 64500}); ok {
+		t.Errorf("Insert dupe, got %v, want false", ok)
+	}
+
+	tcs := []struct {
+		pfx  string
+		asn  uint32
+		want cidrtree.Validity
+	}{
+		{"192.0.2.0/24", 64500, cidrtree.Valid},
+		{"192.0.2.0/24", 64999, cidrtree.Invalid},
+		{"192.0.2.0/25", 64500, cidrtree.Invalid}, // exceeds maxLength
+		{"192.0.0.0/16", 64500, cidrtree.NotFound},
+		// less specific VRP validates, although more specific VRP exists
+		{"198.51.100.0/24", 64501, cidrtree.Valid},
+		{"198.51.100.0/24", 64502, cidrtree.Valid},
+		{"198.51.101.0/24", 64502, cidrtree.Invalid},
+		{"203.0.113.0/24", 0, cidrtree.Invalid},
+		{"2001:db8:1::/48", 64500, cidrtree.Valid},
+		{"2001:db8:1::/49", 64500, cidrtree.Invalid},
+		{"2001:db9::/32", 64500, cidrtree.NotFound},
+	}
+
+	for _, tc := range tcs {
+		if got := vt.Validate(mustPfx(tc.pfx), tc.asn); got != tc.want {
+			t.Errorf("Validate(%v, AS%d), got %v, want %v", tc.pfx, tc.asn, got, tc.want)
+		}
+	}
+
+	if n := vt.Size(); n != 5 {
+		t.Errorf("Size, got %v, want %v", n, 5)
+	}
+
+	if ok := vt.Delete(cidrtree.VRP{Prefix: mustPfx("198.51.100.0/22"), MaxLength: 24, ASN: 64501}); !ok {
+		t.Errorf("Delete, got %v, want true", ok)
+	}
+	if ok := vt.Delete(cidrtree.VRP{Prefix: mustPfx("198.51.100.0/22"), MaxLength: 24, ASN: 64501}); ok {
+		t.Errorf("Delete again, got %v, want false", ok)
+	}
+	if got := vt.Validate(mustPfx("198.51.101.0/24"), 64502); got != cidrtree.NotFound {
+		t.Errorf("Validate after Delete, got %v, want %v", got, cidrtree.NotFound)
+	}
+
+	var got []cidrtree.VRP
+	vt.Walk(func(vrp cidrtree.VRP) bool {
+		got = append(got, vrp)
+		return true
+	})
+	if len(got) != 4 || got[0].Prefix != mustPfx("192.0.2.0/24") {
+		t.Errorf("Walk, got %v", got)
+	}
+}
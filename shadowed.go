@@ -0,0 +1,70 @@
+package cidrtree
+
+import "net/netip"
+
+// ShadowKind is the reason why an entry never influences a lookup result.
+type ShadowKind int
+
+const (
+	// Redundant entries have the same value as the covering entry,
+	// the lookup results are identical without them.
+	// A redundant entry may still be the longest-prefix-match for its IPs,
+	// after removal the covering entry matches with the same value.
+	Redundant ShadowKind = iota + 1
+
+	// Covered entries are completely covered by more specific entries,
+	// they are never the longest-prefix-match for any IP.
+	// A covered entry is not redundant if its value differs from the covering entry,
+	// it is still the match of LookupPrefix for the less specific prefixes.
+	Covered
+)
+
+// String implements the fmt.Stringer interface.
+func (k ShadowKind) String() string {
+	switch k {
+	case Redundant:
+		return "redundant"
+	case Covered:
+		return "covered"
+	default:
+		return "unknown"
+	}
+}
+
+// Shadowed is an entry that never influences the result of an IP lookup, see [Table.Shadowed].
+type Shadowed struct {
+	Prefix netip.Prefix
+	Kind   ShadowKind
+	By     netip.Prefix // the covering entry for Redundant, invalid for Covered
+}
+
+// Shadowed reports all entries in ascending order which never influence the result of an IP lookup,
+// e.g. to clean large imported rule sets. An entry is reported only once, Covered takes precedence.
+//
+// Redundant entries may be nested, all of them can be removed without changing any
+// lookup result, see also [Table.Minimize]. Removing a Covered entry changes the result
+// of LookupPrefix for less specific prefixes.
+func (t Table[V]) Shadowed(equal func(a, b V) bool) []Shadowed {
+	var report []Shadowed
+
+	cb := func(n *node[V], parents []*node[V]) bool {
+		if t.HasSubnets(n.cidr) && len(t.gaps(n.cidr)) == 0 {
+			report = append(report, Shadowed{Prefix: n.cidr, Kind: Covered})
+			return true
+		}
+
+		// the direct parent is the last one on the stack
+		if len(parents) > 0 {
+			parent := parents[len(parents)-1]
+			if equal(parent.value, n.value) {
+				report = append(report, Shadowed{Prefix: n.cidr, Kind: Redundant, By: parent.cidr})
+			}
+		}
+		return true
+	}
+
+	t.root4.walkNested(cb)
+	t.root6.walkNested(cb)
+
+	return report
+}
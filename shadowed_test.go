@@ -0,0 +1,46 @@
+package cidrtree_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/gaissmai/cidrtree"
+)
+
+func TestShadowed(t *testing.T) {
+	t.Parallel()
+
+	rtbl := new(cidrtree.Table[string])
+	for s, v := range map[string]string{
+		"10.0.0.0/8":     "A",
+		"10.1.0.0/16":    "A",
+		"10.1.1.0/24":    "A",
+		"10.2.0.0/16":    "B",
+		"10.2.0.0/17":    "C",
+		"10.2.128.0/17":  "D",
+		"192.168.0.0/24": "E",
+		"192.168.0.0/25": "F",
+		"::/0":           "G",
+		"::/1":           "H",
+		"8000::/1":       "G",
+	} {
+		rtbl.Insert(mustPfx(s), v)
+	}
+
+	want := []cidrtree.Shadowed{
+		{Prefix: mustPfx("10.1.0.0/16"), Kind: cidrtree.Redundant, By: mustPfx("10.0.0.0/8")},
+		{Prefix: mustPfx("10.1.1.0/24"), Kind: cidrtree.Redundant, By: mustPfx("10.1.0.0/16")},
+		{Prefix: mustPfx("10.2.0.0/16"), Kind: cidrtree.Covered},
+		{Prefix: mustPfx("::/0"), Kind: cidrtree.Covered},
+		{Prefix: mustPfx("8000::/1"), Kind: cidrtree.Redundant, By: mustPfx("::/0")},
+	}
+
+	got := rtbl.Shadowed(func(a, b string) bool { return a == b })
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Shadowed, got:\n%v\nwant:\n%v", got, want)
+	}
+
+	if s := cidrtree.Covered.String(); s != "covered" {
+		t.Errorf("Covered.String(), got %q, want %q", s, "covered")
+	}
+}
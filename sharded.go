@@ -0,0 +1,189 @@
+package cidrtree
+
+import (
+	"encoding/binary"
+	"net/netip"
+	"sync"
+)
+
+// Sharded is a routing table for write-heavy workloads on many cores.
+//
+// The entries are sharded by the top bits of the address, every shard is an independent
+// table with its own lock, writers to different shards don't block each other.
+// Prefixes shorter than the shard bits span several shards, they are kept in a separate
+// table with its own lock.
+type Sharded[V any] struct {
+	bits    int
+	shards4 []shard[V]
+	shards6 []shard[V]
+	short   shard[V] // prefixes with less than bits
+}
+
+type shard[V any] struct {
+	mu    sync.RWMutex
+	table Table[V]
+}
+
+// NewSharded returns a table with 2^bits shards per IP version, bits is clamped to [0, 16].
+func NewSharded[V any](bits int) *Sharded[V] {
+	bits = min(max(bits, 0), 16)
+	return &Sharded[V]{
+		bits:    bits,
+		shards4: make([]shard[V], 1<<bits),
+		shards6: make([]shard[V], 1<<bits),
+	}
+}
+
+// shardOf returns the shard for the canonical pfx.
+func (s *Sharded[V]) shardOf(pfx netip.Prefix) *shard[V] {
+	if pfx.Bits() < s.bits {
+		return &s.short
+	}
+	return s.shardOfAddr(pfx.Addr())
+}
+
+// shardOfAddr returns the shard for the top bits of ip.
+func (s *Sharded[V]) shardOfAddr(ip netip.Addr) *shard[V] {
+	if s.bits == 0 {
+		if ip.Is4() {
+			return &s.shards4[0]
+		}
+		return &s.shards6[0]
+	}
+
+	if ip.Is4() {
+		a := ip.As4()
+		return &s.shards4[binary.BigEndian.Uint32(a[:])>>(32-s.bits)]
+	}
+	a := ip.As16()
+	return &s.shards6[binary.BigEndian.Uint64(a[:8])>>(64-s.bits)]
+}
+
+// Insert adds pfx with value, see [Table.Insert].
+func (s *Sharded[V]) Insert(pfx netip.Prefix, value V) {
+	pfx = pfx.Masked() // always canonicalize!
+
+	sh := s.shardOf(pfx)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	sh.table.Insert(pfx, value)
+}
+
+// Delete removes pfx, see [Table.Delete].
+func (s *Sharded[V]) Delete(pfx netip.Prefix) bool {
+	pfx = pfx.Masked() // always canonicalize!
+
+	sh := s.shardOf(pfx)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	return sh.table.Delete(pfx)
+}
+
+// Lookup returns the longest-prefix-match for ip, see [Table.Lookup].
+// The entries of the shard are more specific than the short prefixes, they are tried first.
+func (s *Sharded[V]) Lookup(ip netip.Addr) (lpm netip.Prefix, value V, ok bool) {
+	if !ip.IsValid() {
+		return
+	}
+
+	sh := s.shardOfAddr(ip)
+	sh.mu.RLock()
+	lpm, value, ok = sh.table.Lookup(ip)
+	sh.mu.RUnlock()
+
+	if ok {
+		return
+	}
+
+	s.short.mu.RLock()
+	defer s.short.mu.RUnlock()
+	return s.short.table.Lookup(ip)
+}
+
+// LookupPrefix returns the longest-prefix-match for pfx, see [Table.LookupPrefix].
+func (s *Sharded[V]) LookupPrefix(pfx netip.Prefix) (lpm netip.Prefix, value V, ok bool) {
+	if !pfx.IsValid() {
+		return
+	}
+	pfx = pfx.Masked() // always canonicalize!
+
+	// shard entries can't cover a short prefix
+	if pfx.Bits() >= s.bits {
+		sh := s.shardOfAddr(pfx.Addr())
+		sh.mu.RLock()
+		lpm, value, ok = sh.table.LookupPrefix(pfx)
+		sh.mu.RUnlock()
+
+		if ok {
+			return
+		}
+	}
+
+	s.short.mu.RLock()
+	defer s.short.mu.RUnlock()
+	return s.short.table.LookupPrefix(pfx)
+}
+
+// Size returns the number of entries.
+func (s *Sharded[V]) Size() int {
+	n := 0
+	for _, shards := range [][]shard[V]{s.shards4, s.shards6} {
+		for i := range shards {
+			shards[i].mu.RLock()
+			n += shards[i].table.Size()
+			shards[i].mu.RUnlock()
+		}
+	}
+
+	s.short.mu.RLock()
+	defer s.short.mu.RUnlock()
+	return n + s.short.table.Size()
+}
+
+// Walk iterates all entries in ascending order, see [Table.Walk].
+// The short prefixes are locked for the whole walk, the shards one after the other.
+// Concurrent writes to other shards may or may not be visible.
+// If callback returns `false`, the iteration is aborted.
+func (s *Sharded[V]) Walk(cb func(pfx netip.Prefix, value V) bool) {
+	s.short.mu.RLock()
+	defer s.short.mu.RUnlock()
+
+	short := s.short.table.Iter()
+	pending := short.Next()
+
+	for _, shards := range [][]shard[V]{s.shards4, s.shards6} {
+		for i := range shards {
+			if !walkShard(&shards[i], short, &pending, cb) {
+				return
+			}
+		}
+	}
+
+	// the rest of the short prefixes
+	for ; pending; pending = short.Next() {
+		if !cb(short.Prefix(), short.Value()) {
+			return
+		}
+	}
+}
+
+// walkShard merges the shard entries with the pending short prefixes in ascending order.
+func walkShard[V any](sh *shard[V], short *Iterator[V], pending *bool, cb func(netip.Prefix, V) bool) bool {
+	sh.mu.RLock()
+	defer sh.mu.RUnlock()
+
+	it := sh.table.Iter()
+	for it.Next() {
+		for *pending && compare(short.Prefix(), it.Prefix()) < 0 {
+			if !cb(short.Prefix(), short.Value()) {
+				return false
+			}
+			*pending = short.Next()
+		}
+
+		if !cb(it.Prefix(), it.Value()) {
+			return false
+		}
+	}
+	return true
+}
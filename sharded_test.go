@@ -0,0 +1,93 @@
+package cidrtree_test
+
+import (
+	"net/netip"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/gaissmai/cidrtree"
+)
+
+func TestSharded(t *testing.T) {
+	t.Parallel()
+
+	for _, bits := range []int{0, 4, 8, 16} {
+		sharded := cidrtree.NewSharded[int](bits)
+		rtbl := new(cidrtree.Table[int])
+
+		cidrs := shuffleFullTable(10_000)
+		cidrs = append(cidrs, mustPfx("0.0.0.0/0"), mustPfx("10.0.0.0/8"), mustPfx("::/0"), mustPfx("2000::/3"))
+
+		for i, cidr := range cidrs {
+			sharded.Insert(cidr, i)
+			rtbl.Insert(cidr, i)
+		}
+		for _, cidr := range cidrs[:1_000] {
+			if sharded.Delete(cidr) != rtbl.Delete(cidr) {
+				t.Fatalf("bits %d: Delete(%v) differs", bits, cidr)
+			}
+		}
+
+		if sharded.Size() != rtbl.Size() {
+			t.Errorf("bits %d: Size(), got %d, want %d", bits, sharded.Size(), rtbl.Size())
+		}
+
+		for _, cidr := range cidrs {
+			for _, ip := range []netip.Addr{cidr.Addr(), cidr.Addr().Next()} {
+				gotLPM, gotVal, gotOK := sharded.Lookup(ip)
+				wantLPM, wantVal, wantOK := rtbl.Lookup(ip)
+				if gotLPM != wantLPM || gotVal != wantVal || gotOK != wantOK {
+					t.Fatalf("bits %d: Lookup(%v), got (%v, %v, %v), want (%v, %v, %v)", bits, ip, gotLPM, gotVal, gotOK, wantLPM, wantVal, wantOK)
+				}
+			}
+
+			for _, pfxBits := range []int{0, 3, cidr.Bits()} {
+				pfx, _ := cidr.Addr().Prefix(pfxBits)
+				gotLPM, _, gotOK := sharded.LookupPrefix(pfx)
+				wantLPM, _, wantOK := rtbl.LookupPrefix(pfx)
+				if gotLPM != wantLPM || gotOK != wantOK {
+					t.Fatalf("bits %d: LookupPrefix(%v), got (%v, %v), want (%v, %v)", bits, pfx, gotLPM, gotOK, wantLPM, wantOK)
+				}
+			}
+		}
+
+		var got, want []netip.Prefix
+		sharded.Walk(func(pfx netip.Prefix, _ int) bool {
+			got = append(got, pfx)
+			return true
+		})
+		rtbl.Walk(func(pfx netip.Prefix, _ int) bool {
+			want = append(want, pfx)
+			return true
+		})
+
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("bits %d: Walk differs from Table.Walk", bits)
+		}
+	}
+}
+
+func TestShardedConcurrent(t *testing.T) {
+	t.Parallel()
+
+	sharded := cidrtree.NewSharded[int](8)
+	cidrs := shuffleFullTable(10_000)
+
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := w; i < len(cidrs); i += 4 {
+				sharded.Insert(cidrs[i], i)
+				_, _, _ = sharded.Lookup(cidrs[(i+1)%len(cidrs)].Addr())
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	if sharded.Size() != len(cidrs) {
+		t.Errorf("Size() after concurrent inserts, got %d, want %d", sharded.Size(), len(cidrs))
+	}
+}
@@ -0,0 +1,154 @@
+package cidrtree
+
+import (
+	"container/heap"
+	"math"
+	"net/netip"
+	"slices"
+	"time"
+)
+
+// spaceSaving is a bounded set of decaying counters for the heavy hitters, shared by [TopK] and [Churn].
+//
+// If all counters are in use, the counter with the min score is replaced and the new prefix
+// inherits its counts, the space-saving algorithm. The scores are overestimated by at most
+// the score of the replaced counter. The counters are in a min-heap by rank, the eviction
+// is O(log n). Not safe for concurrent use.
+type spaceSaving struct {
+	size     int // max number of counters
+	halfLife time.Duration
+	epoch    time.Time // time of the first count, see rank
+	counters map[netip.Prefix]*ssCounter
+	minHeap  ssHeap
+}
+
+// ssCounter holds the counts decayed to last, the score is the sum of the counts.
+type ssCounter struct {
+	pfx    netip.Prefix
+	counts [2]float64
+	last   time.Time
+	rank   float64
+	index  int // in the heap
+}
+
+// ssEntry is a prefix with its decayed counts, see top.
+type ssEntry struct {
+	pfx    netip.Prefix
+	counts [2]float64
+}
+
+func (e ssEntry) score() float64 {
+	return e.counts[0] + e.counts[1]
+}
+
+func newSpaceSaving(size int, halfLife time.Duration) spaceSaving {
+	return spaceSaving{
+		size:     max(size, 1),
+		halfLife: halfLife,
+		counters: make(map[netip.Prefix]*ssCounter),
+	}
+}
+
+// add counts for pfx at now.
+func (s *spaceSaving) add(pfx netip.Prefix, counts [2]float64, now time.Time) {
+	if s.epoch.IsZero() {
+		s.epoch = now
+	}
+
+	c, ok := s.counters[pfx]
+	switch {
+	case ok:
+	case len(s.counters) < s.size:
+		c = &ssCounter{pfx: pfx}
+		s.counters[pfx] = c
+		heap.Push(&s.minHeap, c)
+	default:
+		// space-saving, replace the counter with the min score
+		c = s.minHeap[0]
+		delete(s.counters, c.pfx)
+		c.pfx = pfx
+		s.counters[pfx] = c
+	}
+
+	c.counts = s.decayed(c, now)
+	c.counts[0] += counts[0]
+	c.counts[1] += counts[1]
+	c.last = now
+	c.rank = s.rank(c)
+	heap.Fix(&s.minHeap, c.index)
+}
+
+// rank orders the counters by score, independent of the time of the comparison.
+// All scores decay by the same factor, the log2 of the score decayed back to the epoch
+// doesn't change with time.
+func (s *spaceSaving) rank(c *ssCounter) float64 {
+	score := c.counts[0] + c.counts[1]
+	if s.halfLife <= 0 {
+		return score
+	}
+	return math.Log2(score) + float64(c.last.Sub(s.epoch))/float64(s.halfLife)
+}
+
+// decayed returns the counts of c decayed to now.
+func (s *spaceSaving) decayed(c *ssCounter, now time.Time) [2]float64 {
+	if s.halfLife <= 0 || c.last.IsZero() {
+		return c.counts
+	}
+	f := math.Exp2(-float64(now.Sub(c.last)) / float64(s.halfLife))
+	return [2]float64{c.counts[0] * f, c.counts[1] * f}
+}
+
+// top returns the k prefixes with the highest decayed scores at now, sorted by descending score.
+func (s *spaceSaving) top(k int, now time.Time) []ssEntry {
+	entries := make([]ssEntry, 0, len(s.counters))
+	for pfx, c := range s.counters {
+		entries = append(entries, ssEntry{pfx: pfx, counts: s.decayed(c, now)})
+	}
+
+	slices.SortFunc(entries, func(a, b ssEntry) int {
+		if a.score() != b.score() {
+			if a.score() > b.score() {
+				return -1
+			}
+			return 1
+		}
+		return compare(a.pfx, b.pfx)
+	})
+
+	if len(entries) > k {
+		entries = entries[:k]
+	}
+	return entries
+}
+
+// reset clears all counters.
+func (s *spaceSaving) reset() {
+	clear(s.counters)
+	s.minHeap = nil
+}
+
+// ssHeap is a min-heap of the counters by rank.
+type ssHeap []*ssCounter
+
+func (h ssHeap) Len() int           { return len(h) }
+func (h ssHeap) Less(i, j int) bool { return h[i].rank < h[j].rank }
+
+func (h ssHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *ssHeap) Push(x any) {
+	c := x.(*ssCounter)
+	c.index = len(*h)
+	*h = append(*h, c)
+}
+
+func (h *ssHeap) Pop() any {
+	old := *h
+	c := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return c
+}
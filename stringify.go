@@ -3,6 +3,8 @@ package cidrtree
 import (
 	"fmt"
 	"io"
+	"net/netip"
+	"os"
 	"strings"
 )
 
@@ -27,7 +29,105 @@ func (t Table[V]) Fprint(w io.Writer) error {
 	return nil
 }
 
+// FprintDepth writes the ordered CIDR tree diagram to w like [Table.Fprint], but only the top
+// maxDepth levels of the containment hierarchy. The hidden subnets of a CIDR are summarized
+// as "… N more", e.g. for an overview of a full BGP table. A maxDepth < 1 is unlimited.
+func (t Table[V]) FprintDepth(w io.Writer, maxDepth int) error {
+	if err := t.root4.fprintDepth(w, maxDepth); err != nil {
+		return err
+	}
+	if err := t.root6.fprintDepth(w, maxDepth); err != nil {
+		return err
+	}
+	return nil
+}
+
+// FprintColor writes the ordered CIDR tree diagram to w like [Table.Fprint], with ANSI colors
+// for interactive sessions. The color of an entry is returned by color as ANSI SGR parameters,
+// e.g. "31" for red or "1;34" for bold blue, the empty string is uncolored.
+// If color is nil, the entries are colored by their depth in the hierarchy.
+// The depth is 0-based as in [Table.WalkTopology], the top-level entries have depth 0.
+//
+// The colors are disabled if w isn't a terminal or the NO_COLOR environment variable is set.
+func (t Table[V]) FprintColor(w io.Writer, color func(pfx netip.Prefix, value V, depth int) string) error {
+	opts := fprintOptions[V]{color: color}
+	if opts.color == nil {
+		opts.color = colorByDepth[V]
+	}
+	if !isTerminal(w) {
+		opts.color = nil
+	}
+
+	if err := t.root4.fprintWith(w, opts); err != nil {
+		return err
+	}
+	if err := t.root6.fprintWith(w, opts); err != nil {
+		return err
+	}
+	return nil
+}
+
+// depthColors, green, yellow, blue, magenta and cyan, repeated for deeper levels.
+var depthColors = []string{"32", "33", "34", "35", "36"}
+
+func colorByDepth[V any](_ netip.Prefix, _ V, depth int) string {
+	return depthColors[depth%len(depthColors)]
+}
+
+// isTerminal reports whether w is a character device and colors are not disabled by NO_COLOR.
+func isTerminal(w io.Writer) bool {
+	if _, ok := os.LookupEnv("NO_COLOR"); ok {
+		return false
+	}
+
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// String4 returns the hierarchical tree diagram of the IPv4 CIDRs as string, see [Table.Fprint4].
+func (t Table[V]) String4() string {
+	w := new(strings.Builder)
+	_ = t.Fprint4(w)
+	return w.String()
+}
+
+// String6 returns the hierarchical tree diagram of the IPv6 CIDRs as string, see [Table.Fprint6].
+func (t Table[V]) String6() string {
+	w := new(strings.Builder)
+	_ = t.Fprint6(w)
+	return w.String()
+}
+
+// Fprint4 writes the ordered CIDR tree diagram of the IPv4 CIDRs to w, see [Table.Fprint].
+func (t Table[V]) Fprint4(w io.Writer) error {
+	return t.root4.fprint(w)
+}
+
+// Fprint6 writes the ordered CIDR tree diagram of the IPv6 CIDRs to w, see [Table.Fprint].
+func (t Table[V]) Fprint6(w io.Writer) error {
+	return t.root6.fprint(w)
+}
+
+// fprintOptions for the tree diagram, the zero value prints the full and plain diagram.
+type fprintOptions[V any] struct {
+	maxDepth int                                               // < 1 is unlimited
+	color    func(pfx netip.Prefix, value V, depth int) string // ANSI SGR parameters, nil is plain
+}
+
 func (n *node[V]) fprint(w io.Writer) error {
+	return n.fprintWith(w, fprintOptions[V]{})
+}
+
+func (n *node[V]) fprintDepth(w io.Writer, maxDepth int) error {
+	return n.fprintWith(w, fprintOptions[V]{maxDepth: maxDepth})
+}
+
+func (n *node[V]) fprintWith(w io.Writer, opts fprintOptions[V]) error {
 	if n == nil {
 		return nil
 	}
@@ -49,19 +149,36 @@ func (n *node[V]) fprint(w io.Writer) error {
 		return err
 	}
 
-	// start recursion with root and empty padding
+	// start recursion with root and empty padding, the nil root is above the top-level depth 0
 	var root *node[V]
-	return root.walkAndStringify(w, pcm, "")
+	return root.walkAndStringify(w, pcm, "", -1, opts)
 }
 
-func (n *node[V]) walkAndStringify(w io.Writer, pcm parentChildsMap[V], pad string) error {
+// walkAndStringify prints n at the 0-based depth and recurses into the childs at depth+1.
+func (n *node[V]) walkAndStringify(w io.Writer, pcm parentChildsMap[V], pad string, depth int, opts fprintOptions[V]) error {
 	// the prefix (pad + glyphe) is already printed on the line on upper level
 	if n != nil {
-		if _, err := fmt.Fprintf(w, "%v (%v)\n", n.cidr, n.value); err != nil {
+		line := fmt.Sprintf("%v (%v)", n.cidr, n.value)
+		if opts.color != nil {
+			if sgr := opts.color(n.cidr, n.value, depth); sgr != "" {
+				line = "\x1b[" + sgr + "m" + line + "\x1b[0m"
+			}
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
 			return err
 		}
 	}
 
+	// depth limit reached, summarize the subnets, maxDepth is the number of levels
+	if opts.maxDepth > 0 && depth+1 == opts.maxDepth {
+		if more := pcm.descendants(n); more > 0 {
+			if _, err := fmt.Fprintf(w, "%s└─ … %d more\n", pad, more); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
 	glyphe := "├─ "
 	spacer := "│  "
 
@@ -81,7 +198,7 @@ func (n *node[V]) walkAndStringify(w io.Writer, pcm parentChildsMap[V], pad stri
 		}
 
 		// recdescent down
-		if err := child.walkAndStringify(w, pcm, pad+spacer); err != nil {
+		if err := child.walkAndStringify(w, pcm, pad+spacer, depth+1, opts); err != nil {
 			return err
 		}
 	}
@@ -97,6 +214,15 @@ type parentChildsMap[T any] struct {
 	stack []*node[T]              // just needed for the algo
 }
 
+// descendants, number of all CIDRs covered by n in the map.
+func (pcm parentChildsMap[T]) descendants(n *node[T]) int {
+	count := 0
+	for _, child := range pcm.pcMap[n] {
+		count += 1 + pcm.descendants(child)
+	}
+	return count
+}
+
 // buildParentChildsMap, in-order traversal
 func (n *node[V]) buildParentChildsMap(pcm parentChildsMap[V]) parentChildsMap[V] {
 	if n == nil {
@@ -0,0 +1,185 @@
+package cidrtree
+
+import (
+	"net/netip"
+	"slices"
+)
+
+// meta data of a node, independent of the value.
+// Never changed in place, always copy-on-write, the meta data may be shared between treaps.
+// The nodes are shared as well, a new meta is set on a path copy of the node, see modifyNode.
+type meta[V any] struct {
+	tags  []string // sorted and unique
+	stale []string // sorted and unique, subset of tags, see MarkAllStale
+}
+
+// withTags returns a copy of the meta data with the tags added.
+func (m *meta[V]) withTags(tags []string) *meta[V] {
+	c := new(meta[V])
+	if m != nil {
+		*c = *m
+		c.tags = slices.Clone(m.tags)
+	}
+
+	for _, tag := range tags {
+		if i, found := slices.BinarySearch(c.tags, tag); !found {
+			c.tags = slices.Insert(c.tags, i, tag)
+		}
+	}
+
+	// tagged again, the tags are fresh
+	c.stale = slices.DeleteFunc(slices.Clone(c.stale), func(tag string) bool {
+		return slices.Contains(tags, tag)
+	})
+	return c
+}
+
+// withoutTags returns a copy of the meta data with the tags removed.
+func (m *meta[V]) withoutTags(tags []string) *meta[V] {
+	if m == nil {
+		return nil
+	}
+
+	c := *m
+	c.tags = slices.DeleteFunc(slices.Clone(m.tags), func(tag string) bool {
+		return slices.Contains(tags, tag)
+	})
+	c.stale = slices.DeleteFunc(slices.Clone(m.stale), func(tag string) bool {
+		return slices.Contains(tags, tag)
+	})
+	return &c
+}
+
+// withStale returns a copy of the meta data with the tag marked as stale.
+func (m *meta[V]) withStale(tag string) *meta[V] {
+	c := *m
+	c.stale = slices.Clone(m.stale)
+	if i, found := slices.BinarySearch(c.stale, tag); !found {
+		c.stale = slices.Insert(c.stale, i, tag)
+	}
+	return &c
+}
+
+// isStale reports whether the tag is marked as stale, nil safe.
+func (m *meta[V]) isStale(tag string) bool {
+	if m == nil {
+		return false
+	}
+	_, found := slices.BinarySearch(m.stale, tag)
+	return found
+}
+
+// hasTag reports whether the tag is set, nil safe.
+func (m *meta[V]) hasTag(tag string) bool {
+	if m == nil {
+		return false
+	}
+	_, found := slices.BinarySearch(m.tags, tag)
+	return found
+}
+
+// Tag attaches the tags to the entry for pfx, independent of its value.
+// Tags are kept if the value is replaced by Insert, they are removed with the entry.
+// Returns false if pfx isn't in the table.
+func (t *Table[V]) Tag(pfx netip.Prefix, tags ...string) bool {
+	t.mustNotBeFrozen()
+	return t.modifyNode(pfx.Masked(), func(c *node[V]) bool {
+		c.meta = c.meta.withTags(tags)
+		return true
+	})
+}
+
+// Untag removes the tags from the entry for pfx, returns false if pfx isn't in the table.
+func (t *Table[V]) Untag(pfx netip.Prefix, tags ...string) bool {
+	t.mustNotBeFrozen()
+	return t.modifyNode(pfx.Masked(), func(c *node[V]) bool {
+		c.meta = c.meta.withoutTags(tags)
+		return true
+	})
+}
+
+// Tags returns the sorted tags of the entry for pfx.
+func (t Table[V]) Tags(pfx netip.Prefix) []string {
+	n := t.findNode(pfx.Masked())
+	if n == nil || n.meta == nil || len(n.meta.tags) == 0 {
+		return nil
+	}
+	return slices.Clone(n.meta.tags)
+}
+
+// WalkTagged iterates all entries with tag in ascending order, see also [Table.Walk].
+// If callback returns `false`, the iteration is aborted.
+func (t Table[V]) WalkTagged(tag string, cb func(pfx netip.Prefix, value V) bool) {
+	walk := func(n *node[V]) bool {
+		if n.meta.hasTag(tag) {
+			return cb(n.cidr, n.value)
+		}
+		return true
+	}
+
+	if !t.root4.walkNodes(walk) {
+		return
+	}
+	t.root6.walkNodes(walk)
+}
+
+// DeleteTagged removes all entries with tag from the table, returns the number of removed entries.
+func (t *Table[V]) DeleteTagged(tag string) int {
+	t.mustNotBeFrozen()
+	var pfxs []netip.Prefix
+	t.WalkTagged(tag, func(pfx netip.Prefix, _ V) bool {
+		pfxs = append(pfxs, pfx)
+		return true
+	})
+
+	for _, pfx := range pfxs {
+		t.Delete(pfx)
+	}
+	return len(pfxs)
+}
+
+// MarkAllStale marks all entries with tag as stale, returns the number of marked entries.
+// Entries tagged again with [Table.Tag] are fresh, the remaining stale entries are removed
+// with [Table.SweepStale], e.g. for a graceful restart of the routing source tag.
+func (t *Table[V]) MarkAllStale(tag string) int {
+	t.mustNotBeFrozen()
+	var count int
+	t.modifyNodes(func(n *node[V]) *node[V] {
+		if !n.meta.hasTag(tag) {
+			return nil
+		}
+		count++
+		c := n.copyNode()
+		c.meta = n.meta.withStale(tag)
+		return c
+	})
+	return count
+}
+
+// SweepStale removes all entries with tag still marked as stale, returns the number of removed entries.
+func (t *Table[V]) SweepStale(tag string) int {
+	t.mustNotBeFrozen()
+	var pfxs []netip.Prefix
+	walk := func(n *node[V]) bool {
+		if n.meta.isStale(tag) {
+			pfxs = append(pfxs, n.cidr)
+		}
+		return true
+	}
+
+	t.root4.walkNodes(walk)
+	t.root6.walkNodes(walk)
+
+	for _, pfx := range pfxs {
+		t.Delete(pfx)
+	}
+	return len(pfxs)
+}
+
+// findNode returns the node for the exact and canonical pfx or nil.
+func (t Table[V]) findNode(pfx netip.Prefix) *node[V] {
+	if pfx.Addr().Is4() {
+		return t.root4.find(pfx)
+	}
+	return t.root6.find(pfx)
+}
@@ -0,0 +1,230 @@
+package cidrtree_test
+
+import (
+	"net/netip"
+	"reflect"
+	"testing"
+
+	"github.com/gaissmai/cidrtree"
+)
+
+func TestTags(t *testing.T) {
+	t.Parallel()
+
+	rtbl := new(cidrtree.Table[any])
+	for _, route := range routes {
+		rtbl.Insert(route.cidr, route.nextHop)
+	}
+
+	if ok := rtbl.Tag(mustPfx("10.0.0.0/9"), "static"); ok {
+		t.Errorf("Tag(%v), got %v, want false", "10.0.0.0/9", ok)
+	}
+
+	for _, s := range []string{"10.0.0.0/8", "10.0.1.0/24", "::1/128"} {
+		if ok := rtbl.Tag(mustPfx(s), "static", "blue"); !ok {
+			t.Errorf("Tag(%v), got %v, want true", s, ok)
+		}
+	}
+	rtbl.Tag(mustPfx("10.0.0.0/8"), "static", "red")
+
+	want := []string{"blue", "red", "static"}
+	if got := rtbl.Tags(mustPfx("10.0.0.0/8")); !reflect.DeepEqual(got, want) {
+		t.Errorf("Tags(%v), got %v, want %v", "10.0.0.0/8", got, want)
+	}
+
+	// keep the tags on value update
+	rtbl.Insert(mustPfx("10.0.0.0/8"), "new value")
+	immutable := rtbl.InsertImmutable(mustPfx("10.0.0.0/8"), "other value")
+
+	if got := immutable.Tags(mustPfx("10.0.0.0/8")); !reflect.DeepEqual(got, want) {
+		t.Errorf("Tags(%v) after Insert, got %v, want %v", "10.0.0.0/8", got, want)
+	}
+
+	rtbl.Untag(mustPfx("10.0.0.0/8"), "red", "blue")
+	want = []string{"static"}
+	if got := rtbl.Tags(mustPfx("10.0.0.0/8")); !reflect.DeepEqual(got, want) {
+		t.Errorf("Tags(%v) after Untag, got %v, want %v", "10.0.0.0/8", got, want)
+	}
+
+	// copy-on-write, immutable table is unchanged
+	want = []string{"blue", "red", "static"}
+	if got := immutable.Tags(mustPfx("10.0.0.0/8")); !reflect.DeepEqual(got, want) {
+		t.Errorf("Tags(%v) of immutable table, got %v, want %v", "10.0.0.0/8", got, want)
+	}
+
+	var tagged []netip.Prefix
+	rtbl.WalkTagged("blue", func(pfx netip.Prefix, _ any) bool {
+		tagged = append(tagged, pfx)
+		return true
+	})
+
+	wantPfxs := []netip.Prefix{mustPfx("10.0.1.0/24"), mustPfx("::1/128")}
+	if !reflect.DeepEqual(tagged, wantPfxs) {
+		t.Errorf("WalkTagged(%v), got %v, want %v", "blue", tagged, wantPfxs)
+	}
+
+	if n := rtbl.DeleteTagged("static"); n != 3 {
+		t.Errorf("DeleteTagged(%v), got %v, want %v", "static", n, 3)
+	}
+
+	if n := rtbl.DeleteTagged("static"); n != 0 {
+		t.Errorf("DeleteTagged(%v), got %v, want %v", "static", n, 0)
+	}
+
+	if got := rtbl.Tags(mustPfx("10.0.0.0/8")); got != nil {
+		t.Errorf("Tags(%v) of deleted entry, got %v, want nil", "10.0.0.0/8", got)
+	}
+}
+
+func TestMarkAllStale(t *testing.T) {
+	t.Parallel()
+
+	rtbl := new(cidrtree.Table[any])
+	for _, route := range routes {
+		rtbl.Insert(route.cidr, route.nextHop)
+	}
+
+	peer := []string{"10.0.0.0/8", "10.0.1.0/24", "192.168.0.0/16", "::1/128"}
+	for _, s := range peer {
+		rtbl.Tag(mustPfx(s), "peer1")
+	}
+	rtbl.Tag(mustPfx("10.0.0.0/8"), "static")
+
+	if n := rtbl.MarkAllStale("peer1"); n != 4 {
+		t.Errorf("MarkAllStale(%v), got %v, want %v", "peer1", n, 4)
+	}
+
+	// peer restart, some routes are re-learned
+	rtbl.Insert(mustPfx("10.0.0.0/8"), "new value")
+	rtbl.Tag(mustPfx("10.0.0.0/8"), "peer1")
+	rtbl.Tag(mustPfx("::1/128"), "peer1")
+
+	// wrong tag, nothing stale
+	if n := rtbl.SweepStale("static"); n != 0 {
+		t.Errorf("SweepStale(%v), got %v, want %v", "static", n, 0)
+	}
+
+	if n := rtbl.SweepStale("peer1"); n != 2 {
+		t.Errorf("SweepStale(%v), got %v, want %v", "peer1", n, 2)
+	}
+
+	for _, s := range []string{"10.0.1.0/24", "192.168.0.0/16"} {
+		if lpm, _, _ := rtbl.LookupPrefix(mustPfx(s)); lpm == mustPfx(s) {
+			t.Errorf("LookupPrefix(%v) after SweepStale, got %v, want deleted", s, lpm)
+		}
+	}
+
+	for _, s := range []string{"10.0.0.0/8", "::1/128"} {
+		if lpm, _, _ := rtbl.LookupPrefix(mustPfx(s)); lpm != mustPfx(s) {
+			t.Errorf("LookupPrefix(%v) after SweepStale, got %v, want %v", s, lpm, s)
+		}
+	}
+
+	if n := rtbl.SweepStale("peer1"); n != 0 {
+		t.Errorf("SweepStale(%v) again, got %v, want %v", "peer1", n, 0)
+	}
+}
+
+func TestTagsShared(t *testing.T) {
+	t.Parallel()
+
+	orig := new(cidrtree.Table[any])
+	for _, route := range routes {
+		orig.Insert(route.cidr, route.nextHop)
+	}
+	orig.Tag(mustPfx("10.0.0.0/8"), "peer1")
+
+	// shares the nodes with orig
+	rtbl := orig.InsertImmutable(mustPfx("172.16.0.0/12"), nil)
+
+	rtbl.Tag(mustPfx("10.0.0.0/8"), "static")
+	rtbl.Untag(mustPfx("10.0.0.0/8"), "peer1")
+	rtbl.Tag(mustPfx("192.168.0.0/16"), "peer1")
+	rtbl.MarkAllStale("peer1")
+
+	if got := orig.Tags(mustPfx("10.0.0.0/8")); !reflect.DeepEqual(got, []string{"peer1"}) {
+		t.Errorf("Tags(%v) of the original table, got %v, want %v", "10.0.0.0/8", got, []string{"peer1"})
+	}
+	if got := orig.Tags(mustPfx("192.168.0.0/16")); got != nil {
+		t.Errorf("Tags(%v) of the original table, got %v, want nil", "192.168.0.0/16", got)
+	}
+	if n := orig.SweepStale("peer1"); n != 0 {
+		t.Errorf("SweepStale(%v) of the original table, got %v, want %v", "peer1", n, 0)
+	}
+}
+
+func TestTagsUnion(t *testing.T) {
+	t.Parallel()
+
+	// the priorities are random, many runs for both orders of the treaps
+	for i := 0; i < 200; i++ {
+		rtbl := new(cidrtree.Table[any])
+		for _, route := range routes {
+			rtbl.Insert(route.cidr, route.nextHop)
+		}
+		rtbl.Tag(mustPfx("10.0.0.0/8"), "static")
+
+		other := new(cidrtree.Table[any])
+		other.Insert(mustPfx("10.0.0.0/8"), "new value")
+
+		// Union changes the nodes of both tables
+		union := rtbl.UnionImmutable(*other)
+		mutable := rtbl.Clone()
+		mutable.Union(*other.Clone())
+
+		for name, tbl := range map[string]*cidrtree.Table[any]{"Union": mutable, "UnionImmutable": union} {
+			if got := tbl.Tags(mustPfx("10.0.0.0/8")); !reflect.DeepEqual(got, []string{"static"}) {
+				t.Fatalf("Tags(%v) after %s, got %v, want %v", "10.0.0.0/8", name, got, []string{"static"})
+			}
+			if _, value, _ := tbl.LookupPrefix(mustPfx("10.0.0.0/8")); value != "new value" {
+				t.Fatalf("LookupPrefix(%v) after %s, got %v, want %v", "10.0.0.0/8", name, value, "new value")
+			}
+		}
+	}
+}
+
+func TestMarkAllStaleBulkOverwrite(t *testing.T) {
+	t.Parallel()
+
+	peer := []string{"10.0.0.0/8", "10.0.1.0/24", "192.168.0.0/16", "::1/128"}
+	relearned := []cidrtree.Entry[any]{
+		{Prefix: mustPfx("10.0.0.0/8"), Value: "new value"},
+		{Prefix: mustPfx("10.0.1.0/24"), Value: "new value"},
+		{Prefix: mustPfx("::1/128"), Value: "new value"},
+	}
+
+	other := new(cidrtree.Table[any])
+	for _, e := range relearned {
+		other.Insert(e.Prefix, e.Value)
+	}
+
+	// the priorities are random, many runs for both orders of the treaps
+	for i := 0; i < 200; i++ {
+		for name, relearn := range map[string]func(rtbl *cidrtree.Table[any]) *cidrtree.Table[any]{
+			"InsertManyImmutable": func(rtbl *cidrtree.Table[any]) *cidrtree.Table[any] { return rtbl.InsertManyImmutable(relearned) },
+			"UnionImmutable":      func(rtbl *cidrtree.Table[any]) *cidrtree.Table[any] { return rtbl.UnionImmutable(*other) },
+		} {
+			rtbl := new(cidrtree.Table[any])
+			for _, route := range routes {
+				rtbl.Insert(route.cidr, route.nextHop)
+			}
+			for _, s := range peer {
+				rtbl.Tag(mustPfx(s), "peer1")
+			}
+			rtbl.MarkAllStale("peer1")
+
+			// the stale marks survive the overwrite, the re-learned routes are tagged again,
+			// a clone for the mutable SweepStale, the nodes are shared with rtbl
+			tbl := relearn(rtbl).Clone()
+			tbl.Tag(mustPfx("10.0.0.0/8"), "peer1")
+			tbl.Tag(mustPfx("::1/128"), "peer1")
+
+			if n := tbl.SweepStale("peer1"); n != 2 {
+				t.Fatalf("SweepStale(%v) after %s, got %v, want %v", "peer1", name, n, 2)
+			}
+			if lpm, _, _ := tbl.LookupPrefix(mustPfx("10.0.1.0/24")); lpm == mustPfx("10.0.1.0/24") {
+				t.Fatalf("LookupPrefix(%v) after %s and SweepStale, got %v, want deleted", "10.0.1.0/24", name, lpm)
+			}
+		}
+	}
+}
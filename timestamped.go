@@ -0,0 +1,117 @@
+package cidrtree
+
+import (
+	"net/netip"
+	"time"
+)
+
+// Timestamped is a table that records the created and updated time of every entry,
+// e.g. to age out the stale learned entries without encoding the timestamps in V.
+//
+// The zero value is ready to use, only the mutable API is supported.
+type Timestamped[V any] struct {
+	// Now returns the current time, nil is time.Now.
+	Now func() time.Time
+
+	table Table[stamped[V]]
+}
+
+// stamped is a value with its timestamps.
+type stamped[V any] struct {
+	value   V
+	created time.Time
+	updated time.Time
+}
+
+// Insert adds pfx with value, an existing entry keeps its created time.
+func (ts *Timestamped[V]) Insert(pfx netip.Prefix, value V) {
+	pfx = pfx.Masked() // always canonicalize!
+
+	now := ts.now()
+	created := now
+	if old, ok := ts.table.get(pfx); ok {
+		created = old.created
+	}
+	ts.table.Insert(pfx, stamped[V]{value: value, created: created, updated: now})
+}
+
+// Touch sets the updated time of pfx to now without changing the value, e.g. for a refresh
+// of a learned entry. Returns false if pfx isn't in the table.
+func (ts *Timestamped[V]) Touch(pfx netip.Prefix) bool {
+	n := ts.table.findNode(pfx.Masked())
+	if n == nil {
+		return false
+	}
+	n.value.updated = ts.now()
+	return true
+}
+
+// Delete removes pfx, returns false if pfx isn't in the table.
+func (ts *Timestamped[V]) Delete(pfx netip.Prefix) bool {
+	return ts.table.Delete(pfx)
+}
+
+// Lookup returns the longest-prefix-match for ip, see [Table.Lookup].
+func (ts *Timestamped[V]) Lookup(ip netip.Addr) (lpm netip.Prefix, value V, ok bool) {
+	lpm, s, ok := ts.table.Lookup(ip)
+	return lpm, s.value, ok
+}
+
+// LookupPrefix returns the longest-prefix-match for pfx, see [Table.LookupPrefix].
+func (ts *Timestamped[V]) LookupPrefix(pfx netip.Prefix) (lpm netip.Prefix, value V, ok bool) {
+	lpm, s, ok := ts.table.LookupPrefix(pfx)
+	return lpm, s.value, ok
+}
+
+// Times returns the created and updated time of the entry for pfx.
+func (ts *Timestamped[V]) Times(pfx netip.Prefix) (created, updated time.Time, ok bool) {
+	s, ok := ts.table.get(pfx.Masked())
+	return s.created, s.updated, ok
+}
+
+// Walk iterates all entries in ascending order with their updated time, see [Table.Walk].
+// If callback returns `false`, the iteration is aborted.
+func (ts *Timestamped[V]) Walk(cb func(pfx netip.Prefix, value V, updated time.Time) bool) {
+	ts.table.Walk(func(pfx netip.Prefix, s stamped[V]) bool {
+		return cb(pfx, s.value, s.updated)
+	})
+}
+
+// WalkOlderThan iterates the entries not updated within the last d in ascending order.
+// If callback returns `false`, the iteration is aborted.
+func (ts *Timestamped[V]) WalkOlderThan(d time.Duration, cb func(pfx netip.Prefix, value V, updated time.Time) bool) {
+	cutoff := ts.now().Add(-d)
+	ts.table.Walk(func(pfx netip.Prefix, s stamped[V]) bool {
+		if s.updated.Before(cutoff) {
+			return cb(pfx, s.value, s.updated)
+		}
+		return true
+	})
+}
+
+// DeleteOlderThan removes the entries not updated within the last d,
+// returns the number of deleted entries.
+func (ts *Timestamped[V]) DeleteOlderThan(d time.Duration) int {
+	var expired []netip.Prefix
+	ts.WalkOlderThan(d, func(pfx netip.Prefix, _ V, _ time.Time) bool {
+		expired = append(expired, pfx)
+		return true
+	})
+
+	for _, pfx := range expired {
+		ts.table.Delete(pfx)
+	}
+	return len(expired)
+}
+
+// Size returns the number of entries.
+func (ts *Timestamped[V]) Size() int {
+	return ts.table.Size()
+}
+
+func (ts *Timestamped[V]) now() time.Time {
+	if ts.Now == nil {
+		return time.Now()
+	}
+	return ts.Now()
+}
@@ -0,0 +1,58 @@
+package cidrtree_test
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/gaissmai/cidrtree"
+)
+
+func TestTimestamped(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	ts := &cidrtree.Timestamped[string]{Now: func() time.Time { return now }}
+
+	ts.Insert(mustPfx("10.0.0.0/8"), "a")
+	ts.Insert(mustPfx("10.1.0.0/16"), "b")
+	ts.Insert(mustPfx("2001:db8::/32"), "c")
+	t0 := now
+
+	now = now.Add(time.Minute)
+	ts.Insert(mustPfx("10.0.0.0/8"), "A")
+
+	if created, updated, ok := ts.Times(mustPfx("10.0.0.0/8")); !ok || !created.Equal(t0) || !updated.Equal(now) {
+		t.Errorf("Times, got %v %v %v, want %v %v true", created, updated, ok, t0, now)
+	}
+	if _, value, _ := ts.Lookup(mustAddr("10.2.0.1")); value != "A" {
+		t.Errorf("Lookup, got %v, want %v", value, "A")
+	}
+
+	now = now.Add(time.Minute)
+	if ok := ts.Touch(mustPfx("2001:db8::/32")); !ok {
+		t.Errorf("Touch, got %v, want true", ok)
+	}
+	if ok := ts.Touch(mustPfx("2001:db9::/32")); ok {
+		t.Errorf("Touch missing, got %v, want false", ok)
+	}
+
+	var old []netip.Prefix
+	ts.WalkOlderThan(90*time.Second, func(pfx netip.Prefix, _ string, _ time.Time) bool {
+		old = append(old, pfx)
+		return true
+	})
+	if len(old) != 1 || old[0] != mustPfx("10.1.0.0/16") {
+		t.Errorf("WalkOlderThan, got %v, want [10.1.0.0/16]", old)
+	}
+
+	if n := ts.DeleteOlderThan(30 * time.Second); n != 2 {
+		t.Errorf("DeleteOlderThan, got %v, want %v", n, 2)
+	}
+	if n := ts.Size(); n != 1 {
+		t.Errorf("Size, got %v, want %v", n, 1)
+	}
+	if lpm, value, ok := ts.LookupPrefix(mustPfx("2001:db8:1::/48")); !ok || lpm != mustPfx("2001:db8::/32") || value != "c" {
+		t.Errorf("LookupPrefix, got %v %v %v", lpm, value, ok)
+	}
+}
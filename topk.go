@@ -0,0 +1,79 @@
+package cidrtree
+
+import (
+	"net/netip"
+	"sync"
+	"time"
+)
+
+// HotPrefix is a prefix with its decayed hit score, see [TopK].
+type HotPrefix struct {
+	Prefix netip.Prefix
+	Score  float64
+}
+
+// TopK reports the approximately hottest prefixes, e.g. the lpm results of Lookup.
+//
+// The hit scores decay exponentially with the given half-life, old traffic fades out.
+// TopK uses the space-saving algorithm with a bounded number of counters, the memory
+// is independent of the number of distinct prefixes. The scores are overestimated
+// by at most the score of the evicted counter, the eviction is O(log k).
+//
+// TopK is safe for concurrent use.
+type TopK struct {
+	mu       sync.Mutex
+	k        int
+	counters spaceSaving
+}
+
+// NewTopK returns a tracker for the k hottest prefixes.
+// A halfLife <= 0 disables the decay.
+func NewTopK(k int, halfLife time.Duration) *TopK {
+	k = max(k, 1)
+
+	// twice as many counters as reported, better accuracy for the k-th item
+	return &TopK{k: k, counters: newSpaceSaving(2*k, halfLife)}
+}
+
+// Hit counts a hit for pfx.
+func (h *TopK) Hit(pfx netip.Prefix) {
+	now := time.Now()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.counters.add(pfx.Masked(), [2]float64{1, 0}, now) // always canonicalize!
+}
+
+// Top returns the k hottest prefixes, sorted by descending score.
+func (h *TopK) Top() []HotPrefix {
+	now := time.Now()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	top := h.counters.top(h.k, now)
+	hot := make([]HotPrefix, 0, len(top))
+	for _, e := range top {
+		hot = append(hot, HotPrefix{Prefix: e.pfx, Score: e.score()})
+	}
+	return hot
+}
+
+// Reset clears all counters.
+func (h *TopK) Reset() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.counters.reset()
+}
+
+// PromoteHot promotes the hottest prefixes of the report in the table, see [Table.Promote].
+// Returns the number of promoted entries.
+func (t *Table[V]) PromoteHot(h *TopK) int {
+	var n int
+	for _, hot := range h.Top() {
+		if t.Promote(hot.Prefix) {
+			n++
+		}
+	}
+	return n
+}
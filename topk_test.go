@@ -0,0 +1,113 @@
+package cidrtree_test
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/gaissmai/cidrtree"
+)
+
+func TestTopK(t *testing.T) {
+	t.Parallel()
+
+	hot := cidrtree.NewTopK(2, 0)
+	if got := hot.Top(); len(got) != 0 {
+		t.Errorf("Top() of empty tracker, got %v, want []", got)
+	}
+
+	hits := map[string]int{
+		"10.0.0.0/8":  10,
+		"10.0.1.0/24": 50,
+		"::/0":        30,
+		"::1/128":     1,
+		"fc00::/7":    2,
+	}
+
+	for s, n := range hits {
+		for i := 0; i < n; i++ {
+			hot.Hit(mustPfx(s))
+		}
+	}
+
+	got := hot.Top()
+	want := []netip.Prefix{mustPfx("10.0.1.0/24"), mustPfx("::/0")}
+
+	if len(got) != len(want) {
+		t.Fatalf("Top(), got %v, want %v", got, want)
+	}
+	for i := range got {
+		if got[i].Prefix != want[i] {
+			t.Errorf("Top()[%d], got %v, want %v", i, got[i].Prefix, want[i])
+		}
+	}
+
+	hot.Reset()
+	if got := hot.Top(); len(got) != 0 {
+		t.Errorf("Top() after Reset, got %v, want []", got)
+	}
+}
+
+func TestTopKDecay(t *testing.T) {
+	t.Parallel()
+
+	hot := cidrtree.NewTopK(1, 10*time.Millisecond)
+
+	for i := 0; i < 100; i++ {
+		hot.Hit(mustPfx("10.0.0.0/8"))
+	}
+
+	// 10 half-lives, the old score is decayed below 1
+	time.Sleep(100 * time.Millisecond)
+
+	for i := 0; i < 5; i++ {
+		hot.Hit(mustPfx("::/0"))
+	}
+
+	got := hot.Top()
+	if len(got) != 1 || got[0].Prefix != mustPfx("::/0") {
+		t.Errorf("Top() after decay, got %v, want %v", got, "::/0")
+	}
+}
+
+func TestPromoteHot(t *testing.T) {
+	t.Parallel()
+
+	rtbl := new(cidrtree.Table[any])
+	for _, route := range routes {
+		rtbl.Insert(route.cidr, route.nextHop)
+	}
+	want := rtbl.String()
+
+	hot := cidrtree.NewTopK(2, 0)
+	hot.Hit(mustPfx("10.0.1.0/24"))
+	hot.Hit(mustPfx("10.0.0.0/9"))
+
+	if n := rtbl.PromoteHot(hot); n != 1 {
+		t.Errorf("PromoteHot(), got %d, want %d", n, 1)
+	}
+
+	if got := rtbl.String(); got != want {
+		t.Errorf("PromoteHot() changed the table, got:\n%v\nwant:\n%v", got, want)
+	}
+}
+
+func TestTopKMasked(t *testing.T) {
+	t.Parallel()
+
+	hot := cidrtree.NewTopK(2, 0)
+	hot.Hit(mustPfx("10.0.0.1/8"))
+	hot.Hit(mustPfx("10.0.0.0/8"))
+	hot.Hit(mustPfx("10.255.0.0/8"))
+
+	got := hot.Top()
+	if len(got) != 1 || got[0].Prefix != mustPfx("10.0.0.0/8") || got[0].Score != 3 {
+		t.Fatalf("Top() of unmasked hits, got %v, want [{10.0.0.0/8 3}]", got)
+	}
+
+	rtbl := new(cidrtree.Table[any])
+	rtbl.Insert(mustPfx("10.0.0.0/8"), nil)
+	if n := rtbl.PromoteHot(hot); n != 1 {
+		t.Errorf("PromoteHot() of unmasked hits, got %d, want %d", n, 1)
+	}
+}
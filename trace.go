@@ -0,0 +1,82 @@
+package cidrtree
+
+import (
+	"context"
+	"net/netip"
+)
+
+// Tracer is the hook for tracing instrumentation, e.g. an adapter to OpenTelemetry.
+//
+// Start is called before the operation, with the context of the caller and the name
+// of the operation, e.g. "cidrtree.Lookup". The returned end func is called after the
+// operation with the attributes, e.g. the table size and the lookup depth.
+type Tracer interface {
+	Start(ctx context.Context, op string) (end func(attrs ...Attr))
+}
+
+// Attr is a trace attribute, the value is an int, bool or string.
+type Attr struct {
+	Key   string
+	Value any
+}
+
+// Traced is a table with tracing instrumentation, the operations are reported to the Tracer.
+// Traced has no overhead for the non-instrumented methods of the embedded table.
+// With a nil Tracer the instrumented methods are the plain table methods without spans.
+type Traced[V any] struct {
+	*Table[V]
+	Tracer Tracer
+}
+
+// LookupContext is Lookup with tracing, the attributes are matched, depth and size.
+func (t Traced[V]) LookupContext(ctx context.Context, ip netip.Addr) (lpm netip.Prefix, value V, ok bool) {
+	if t.Tracer == nil {
+		return t.Lookup(ip)
+	}
+	end := t.Tracer.Start(ctx, "cidrtree.Lookup")
+
+	root := t.root6
+	if ip.Is4() {
+		root = t.root4
+	}
+
+	var depth int
+	lpm, value, ok, depth = root.lpmIP(ip, 0)
+
+	end(Attr{"matched", ok}, Attr{"depth", depth}, Attr{"size", t.Size()})
+	return
+}
+
+// InsertContext is Insert with tracing, the attributes are prefix and size.
+func (t Traced[V]) InsertContext(ctx context.Context, pfx netip.Prefix, value V) {
+	if t.Tracer == nil {
+		t.Insert(pfx, value)
+		return
+	}
+	end := t.Tracer.Start(ctx, "cidrtree.Insert")
+	t.Insert(pfx, value)
+	end(Attr{"prefix", pfx.String()}, Attr{"size", t.Size()})
+}
+
+// DeleteContext is Delete with tracing, the attributes are prefix, found and size.
+func (t Traced[V]) DeleteContext(ctx context.Context, pfx netip.Prefix) bool {
+	if t.Tracer == nil {
+		return t.Delete(pfx)
+	}
+	end := t.Tracer.Start(ctx, "cidrtree.Delete")
+	found := t.Delete(pfx)
+	end(Attr{"prefix", pfx.String()}, Attr{"found", found}, Attr{"size", t.Size()})
+	return found
+}
+
+// UnionContext is Union with tracing, the attributes are other.size and size.
+func (t Traced[V]) UnionContext(ctx context.Context, other Table[V]) {
+	if t.Tracer == nil {
+		t.Union(other)
+		return
+	}
+	end := t.Tracer.Start(ctx, "cidrtree.Union")
+	otherSize := other.Size() // before the mutable union
+	t.Union(other)
+	end(Attr{"other.size", otherSize}, Attr{"size", t.Size()})
+}
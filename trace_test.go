@@ -0,0 +1,93 @@
+package cidrtree_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/gaissmai/cidrtree"
+)
+
+// testTracer records the operations and attributes
+type testTracer struct {
+	log []string
+}
+
+type ctxKey struct{}
+
+func (tr *testTracer) Start(ctx context.Context, op string) func(attrs ...cidrtree.Attr) {
+	return func(attrs ...cidrtree.Attr) {
+		var s []string
+		for _, a := range attrs {
+			if a.Key == "depth" {
+				continue // random treap
+			}
+			s = append(s, fmt.Sprintf("%s=%v", a.Key, a.Value))
+		}
+		tr.log = append(tr.log, fmt.Sprintf("%v %s %s", ctx.Value(ctxKey{}), op, strings.Join(s, " ")))
+	}
+}
+
+func TestTraced(t *testing.T) {
+	t.Parallel()
+
+	tracer := new(testTracer)
+	rtbl := cidrtree.Traced[int]{Table: new(cidrtree.Table[int]), Tracer: tracer}
+	ctx := context.WithValue(context.Background(), ctxKey{}, "parent")
+
+	rtbl.InsertContext(ctx, mustPfx("10.0.0.0/8"), 1)
+	rtbl.InsertContext(ctx, mustPfx("::/0"), 2)
+
+	if _, val, _ := rtbl.LookupContext(ctx, mustAddr("10.1.1.1")); val != 1 {
+		t.Errorf("LookupContext, got %v, want %v", val, 1)
+	}
+	rtbl.LookupContext(ctx, mustAddr("11.1.1.1"))
+
+	other := new(cidrtree.Table[int])
+	other.Insert(mustPfx("fc00::/7"), 3)
+	rtbl.UnionContext(ctx, *other)
+
+	rtbl.DeleteContext(ctx, mustPfx("10.0.0.0/8"))
+
+	// the embedded table is usable without tracing
+	if rtbl.Contains(mustAddr("10.1.1.1")) {
+		t.Errorf("Contains after DeleteContext, got true, want false")
+	}
+
+	want := []string{
+		"parent cidrtree.Insert prefix=10.0.0.0/8 size=1",
+		"parent cidrtree.Insert prefix=::/0 size=2",
+		"parent cidrtree.Lookup matched=true size=2",
+		"parent cidrtree.Lookup matched=false size=2",
+		"parent cidrtree.Union other.size=1 size=3",
+		"parent cidrtree.Delete prefix=10.0.0.0/8 found=true size=2",
+	}
+
+	if got := strings.Join(tracer.log, "\n"); got != strings.Join(want, "\n") {
+		t.Errorf("Traced, got:\n%v\nwant:\n%v", got, strings.Join(want, "\n"))
+	}
+}
+
+func TestTracedNilTracer(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	tt := cidrtree.Traced[int]{Table: new(cidrtree.Table[int])}
+
+	tt.InsertContext(ctx, mustPfx("10.0.0.0/8"), 1)
+
+	other := new(cidrtree.Table[int])
+	other.Insert(mustPfx("10.1.0.0/16"), 2)
+	tt.UnionContext(ctx, *other)
+
+	if lpm, value, ok := tt.LookupContext(ctx, mustAddr("10.1.2.3")); !ok || lpm != mustPfx("10.1.0.0/16") || value != 2 {
+		t.Errorf("LookupContext, got (%v, %v, %v), want (%v, %v, true)", lpm, value, ok, "10.1.0.0/16", 2)
+	}
+	if ok := tt.DeleteContext(ctx, mustPfx("10.0.0.0/8")); !ok {
+		t.Errorf("DeleteContext, got %v, want true", ok)
+	}
+	if got := tt.Size(); got != 1 {
+		t.Errorf("Size, got %v, want %v", got, 1)
+	}
+}
@@ -9,8 +9,12 @@ package cidrtree
 
 import (
 	"cmp"
+	"encoding/binary"
+	"math"
+	"math/big"
 	mrand "math/rand"
 	"net/netip"
+	"slices"
 
 	"github.com/gaissmai/extnetip"
 )
@@ -20,16 +24,62 @@ type Table[V any] struct {
 	// make a treap for every IP version, the bits of the prefix are part of the weighted priority
 	root4 *node[V]
 	root6 *node[V]
+
+	frozen bool // read-only, see Freeze
+}
+
+// View is the read-only part of the Table API, e.g. for plugins that must not mutate the table.
+// Table implements View, the type system prevents mutations.
+type View[V any] interface {
+	Lookup(ip netip.Addr) (lpm netip.Prefix, value V, ok bool)
+	LookupPrefix(pfx netip.Prefix) (lpm netip.Prefix, value V, ok bool)
+	Walk(cb func(pfx netip.Prefix, value V) bool)
+	String() string
+	Size() int
+}
+
+var _ View[any] = Table[any]{}
+
+// Entry is a prefix with its associated value.
+type Entry[V any] struct {
+	Prefix netip.Prefix
+	Value  V
 }
 
 // node is the recursive data structure of the treap.
+//
+// The augmented fields are compact, a netip.Addr is 24 bytes with its zone pointer,
+// the max last address is stored in 16 bytes and the subtree size and height in 32 bits.
 type node[V any] struct {
-	maxUpper *node[V] // augment the treap, see also recalc()
-	left     *node[V]
-	right    *node[V]
-	value    V
-	cidr     netip.Prefix
-	prio     uint64
+	maxLast uint128 // augment the treap with the max last address in this subtree, see also recalc()
+	left    *node[V]
+	right   *node[V]
+	value   V
+	cidr    netip.Prefix
+	prio    uint64
+	size    int32    // augment the treap with the subtree size, see also recalc()
+	height  int16    // augment the treap with the subtree height, see also recalc()
+	minBits uint8    // augment the treap with the min prefix length in this subtree, see also recalc()
+	maxBits uint8    // augment the treap with the max prefix length in this subtree, see also recalc()
+	meta    *meta[V] // optional tags, copy-on-write, see tags.go
+}
+
+// addr returns the address of the IP version of like, see key6.
+func (u uint128) addr(like netip.Addr) netip.Addr {
+	var b [16]byte
+	binary.BigEndian.PutUint64(b[:8], u.hi)
+	binary.BigEndian.PutUint64(b[8:], u.lo)
+
+	ip := netip.AddrFrom16(b)
+	if like.Is4() {
+		return ip.Unmap()
+	}
+	return ip
+}
+
+// less reports whether u is less than v.
+func (u uint128) less(v uint128) bool {
+	return u.hi < v.hi || (u.hi == v.hi && u.lo < v.lo)
 }
 
 // Lookup returns the longest-prefix-match (lpm) for given ip.
@@ -47,33 +97,168 @@ func (t Table[V]) Lookup(ip netip.Addr) (lpm netip.Prefix, value V, ok bool) {
 	return
 }
 
+// LookupFunc returns the longest-prefix-match (lpm) for given ip, skipping all entries
+// for which keep returns false, e.g. routes whose next hop is down.
+// The next-less-specific match is taken instead, with the same early exits as Lookup.
+// If no entry covering ip passes keep, the zero value and false is returned.
+func (t Table[V]) LookupFunc(ip netip.Addr, keep func(pfx netip.Prefix, value V) bool) (lpm netip.Prefix, value V, ok bool) {
+	if ip.Is4() {
+		return t.root4.lpmIPFunc(ip, keep)
+	}
+	return t.root6.lpmIPFunc(ip, keep)
+}
+
+// LookupMaxBits returns the longest-prefix-match (lpm) for given ip, ignoring all entries
+// more specific than maxBits, e.g. the host routes for a coarse classification view.
+// If no entry with at most maxBits covers ip, the zero value and false is returned.
+func (t Table[V]) LookupMaxBits(ip netip.Addr, maxBits int) (lpm netip.Prefix, value V, ok bool) {
+	return t.LookupFunc(ip, func(pfx netip.Prefix, _ V) bool { return pfx.Bits() <= maxBits })
+}
+
+// LookupExcluding returns the longest-prefix-match (lpm) for given ip as if the entry
+// for exclude were absent, e.g. the backup route in a failure simulation.
+// If no other entry covers ip, the zero value and false is returned.
+func (t Table[V]) LookupExcluding(ip netip.Addr, exclude netip.Prefix) (lpm netip.Prefix, value V, ok bool) {
+	exclude = exclude.Masked() // always canonicalize!
+
+	return t.LookupFunc(ip, func(pfx netip.Prefix, _ V) bool { return pfx != exclude })
+}
+
+// Contains reports whether the ip is covered by any CIDR in the table.
+// It stops at the first covering CIDR found, this isn't necessarily the longest-prefix-match.
+//
+// Contains does not allocate memory.
+func (t Table[V]) Contains(ip netip.Addr) bool {
+	if ip.Is4() {
+		return t.root4.contains(ip)
+	}
+	return t.root6.contains(ip)
+}
+
+// HasSubnets reports whether the table contains any CIDR strictly covered by pfx,
+// pfx itself is not taken into account.
+//
+// HasSubnets does not allocate memory.
+func (t Table[V]) HasSubnets(pfx netip.Prefix) bool {
+	pfx = pfx.Masked() // always canonicalize!
+
+	if pfx.Addr().Is4() {
+		return t.root4.hasSubnets(pfx)
+	}
+	return t.root6.hasSubnets(pfx)
+}
+
 // LookupPrefix returns the longest-prefix-match (lpm) for given prefix.
 // If the prefix isn't equal or covered by any CIDR in the table, the zero value and false is returned.
 //
 // LookupPrefix does not allocate memory.
 func (t Table[V]) LookupPrefix(pfx netip.Prefix) (lpm netip.Prefix, value V, ok bool) {
 	pfx = pfx.Masked() // always canonicalize!
+	_, last := extnetip.Range(pfx)
+	key := key6(last)
 
 	if pfx.Addr().Is4() {
 		// don't return the depth
-		lpm, value, ok, _ = t.root4.lpmCIDR(pfx, 0)
+		lpm, value, ok, _ = t.root4.lpmCIDR(pfx, key, 0)
 		return
 	}
 	// don't return the depth
-	lpm, value, ok, _ = t.root6.lpmCIDR(pfx, 0)
+	lpm, value, ok, _ = t.root6.lpmCIDR(pfx, key, 0)
 	return
 }
 
 // Insert adds pfx to the routing table with value of generic type V.
 // If pfx is already present in the table, its value is set to the new value.
 func (t *Table[V]) Insert(pfx netip.Prefix, value V) {
+	t.mustNotBeFrozen()
 	pfx = pfx.Masked() // always canonicalize!
 
 	if pfx.Addr().Is4() {
-		t.root4 = t.root4.insert(makeNode(pfx, value), false)
+		t.root4, _ = t.root4.insert(makeNode(pfx, value), false)
 		return
 	}
-	t.root6 = t.root6.insert(makeNode(pfx, value), false)
+	t.root6, _ = t.root6.insert(makeNode(pfx, value), false)
+}
+
+// Swap adds pfx to the routing table with value of generic type V, like Insert.
+// If pfx was already present in the table, the previous value and true is returned.
+func (t *Table[V]) Swap(pfx netip.Prefix, value V) (old V, existed bool) {
+	t.mustNotBeFrozen()
+	pfx = pfx.Masked() // always canonicalize!
+
+	var dupe *node[V]
+	if pfx.Addr().Is4() {
+		t.root4, dupe = t.root4.insert(makeNode(pfx, value), false)
+	} else {
+		t.root6, dupe = t.root6.insert(makeNode(pfx, value), false)
+	}
+
+	if dupe == nil {
+		return
+	}
+	return dupe.value, true
+}
+
+// CompareAndInsert adds pfx to the routing table with value, like Insert. If pfx is already present,
+// the value is replaced only if better(value, old) reports true. Returns whether value won.
+// The value of a present pfx is replaced with a single traversal, the path to it is copied,
+// nodes shared with clones or snapshots are never changed.
+func (t *Table[V]) CompareAndInsert(pfx netip.Prefix, value V, better func(value, old V) bool) (won bool) {
+	t.mustNotBeFrozen()
+	pfx = pfx.Masked() // always canonicalize!
+
+	found := false
+	won = t.modifyNode(pfx, func(c *node[V]) bool {
+		found = true
+		if !better(value, c.value) {
+			return false
+		}
+		c.value = value
+		return true
+	})
+	if found {
+		return won
+	}
+
+	t.Insert(pfx, value)
+	return true
+}
+
+// CompareAndInsertImmutable is the immutable version of CompareAndInsert, returning a new table.
+// If value doesn't win, the table is returned unchanged.
+func (t Table[V]) CompareAndInsertImmutable(pfx netip.Prefix, value V, better func(value, old V) bool) (*Table[V], bool) {
+	pfx = pfx.Masked() // always canonicalize!
+
+	if old, ok := t.get(pfx); ok && !better(value, old) {
+		return &t, false
+	}
+	return t.InsertImmutable(pfx, value), true
+}
+
+// CompareAndSwap replaces the value of pfx with value only if the current value equals old,
+// e.g. for optimistic concurrency of many controllers. Returns false if pfx isn't present
+// or its value has changed meanwhile. The path to pfx is copied as for CompareAndInsert.
+func (t *Table[V]) CompareAndSwap(pfx netip.Prefix, old, value V, equal func(a, b V) bool) (swapped bool) {
+	t.mustNotBeFrozen()
+
+	return t.modifyNode(pfx.Masked(), func(c *node[V]) bool {
+		if !equal(c.value, old) {
+			return false
+		}
+		c.value = value
+		return true
+	})
+}
+
+// CompareAndSwapImmutable is the immutable version of CompareAndSwap, returning a new table.
+// If the value isn't swapped, the table is returned unchanged.
+func (t Table[V]) CompareAndSwapImmutable(pfx netip.Prefix, old, value V, equal func(a, b V) bool) (*Table[V], bool) {
+	pfx = pfx.Masked() // always canonicalize!
+
+	if current, ok := t.get(pfx); !ok || !equal(current, old) {
+		return &t, false
+	}
+	return t.InsertImmutable(pfx, value), true
 }
 
 // InsertImmutable adds pfx to the table with value of generic type V, returning a new table.
@@ -82,15 +267,38 @@ func (t Table[V]) InsertImmutable(pfx netip.Prefix, value V) *Table[V] {
 	pfx = pfx.Masked() // always canonicalize!
 
 	if pfx.Addr().Is4() {
-		t.root4 = t.root4.insert(makeNode(pfx, value), true)
+		t.root4, _ = t.root4.insert(makeNode(pfx, value), true)
 		return &t
 	}
-	t.root6 = t.root6.insert(makeNode(pfx, value), true)
+	t.root6, _ = t.root6.insert(makeNode(pfx, value), true)
+	return &t
+}
+
+// InsertManyImmutable adds all entries to the table, returning a new table.
+// If a prefix is already present in the table or is duplicated in entries, the last value wins,
+// the tags of the present entries are kept as with Insert.
+//
+// The entries are sorted and built into a treap first, the affected paths in the table
+// are then copied only once and not for every single entry.
+func (t Table[V]) InsertManyImmutable(entries []Entry[V]) *Table[V] {
+	var nodes4, nodes6 []*node[V]
+	for _, e := range entries {
+		n := makeNode(e.Prefix, e.Value)
+		if n.cidr.Addr().Is4() {
+			nodes4 = append(nodes4, n)
+		} else {
+			nodes6 = append(nodes6, n)
+		}
+	}
+
+	t.root4 = t.root4.union(build(nodes4), true, true, nil)
+	t.root6 = t.root6.union(build(nodes6), true, true, nil)
 	return &t
 }
 
 // Delete removes the prefix from table, returns true if it exists, false otherwise.
 func (t *Table[V]) Delete(pfx netip.Prefix) bool {
+	t.mustNotBeFrozen()
 	pfx = pfx.Masked() // always canonicalize!
 
 	is4 := pfx.Addr().Is4()
@@ -134,50 +342,824 @@ func (t Table[V]) DeleteImmutable(pfx netip.Prefix) (*Table[V], bool) {
 		t.root6 = n
 	}
 
-	ok := m != nil
-	return &t, ok
+	ok := m != nil
+	return &t, ok
+}
+
+// DeleteSubtree removes pfx and all prefixes covered by pfx from the table in one pass,
+// returns true if any prefix was removed, false otherwise.
+func (t *Table[V]) DeleteSubtree(pfx netip.Prefix) bool {
+	t.mustNotBeFrozen()
+	pfx = pfx.Masked() // always canonicalize!
+
+	is4 := pfx.Addr().Is4()
+
+	n := t.root6
+	if is4 {
+		n = t.root4
+	}
+
+	// split/join is set to mutable
+	l, m, r := n.splitSubtree(pfx, false)
+	n = l.join(r, false)
+
+	if is4 {
+		t.root4 = n
+	} else {
+		t.root6 = n
+	}
+
+	return m != nil
+}
+
+// DeleteSubtreeImmutable removes pfx and all prefixes covered by pfx,
+// returns the new table and true if any prefix was removed, false otherwise.
+func (t Table[V]) DeleteSubtreeImmutable(pfx netip.Prefix) (*Table[V], bool) {
+	pfx = pfx.Masked() // always canonicalize!
+
+	is4 := pfx.Addr().Is4()
+
+	n := t.root6
+	if is4 {
+		n = t.root4
+	}
+
+	// split/join is set to immutable
+	l, m, r := n.splitSubtree(pfx, true)
+	n = l.join(r, true)
+
+	if is4 {
+		t.root4 = n
+	} else {
+		t.root6 = n
+	}
+
+	ok := m != nil
+	return &t, ok
+}
+
+// Split partitions the table around the key pfx into three tables, below with all prefixes
+// less than pfx in the sort order of [Table.Walk], match with pfx itself if present and above
+// with all prefixes greater than pfx. The IPv4 prefixes sort before the IPv6 prefixes.
+//
+// The union of the three tables is the table, the split is immutable like all Immutable methods
+// and the tables share the nodes with t.
+func (t Table[V]) Split(pfx netip.Prefix) (below, match, above *Table[V]) {
+	pfx = pfx.Masked() // always canonicalize!
+
+	b, m, a := t, t, t
+	b.root4, b.root6 = nil, nil
+	m.root4, m.root6 = nil, nil
+	a.root4, a.root6 = nil, nil
+
+	if pfx.Addr().Is4() {
+		b.root4, m.root4, a.root4 = t.root4.split(pfx, true)
+		a.root6 = t.root6
+	} else {
+		b.root4 = t.root4
+		b.root6, m.root6, a.root6 = t.root6.split(pfx, true)
+	}
+
+	return &b, &m, &a
+}
+
+// ReplaceSubtree removes pfx and all prefixes covered by pfx from the table and
+// splices in all prefixes of sub that are covered by pfx, in one structural operation.
+// Prefixes in sub outside of pfx are ignored, sub itself is not changed.
+func (t *Table[V]) ReplaceSubtree(pfx netip.Prefix, sub Table[V]) {
+	t.mustNotBeFrozen()
+	pfx = pfx.Masked() // always canonicalize!
+
+	is4 := pfx.Addr().Is4()
+
+	n, s := t.root6, sub.root6
+	if is4 {
+		n, s = t.root4, sub.root4
+	}
+
+	// never change sub, split it immutable and clone the covered part,
+	// subsequent mutable operations must not change shared nodes in sub
+	_, s, _ = s.splitSubtree(pfx, true)
+	s = s.clone()
+
+	// split/join is set to mutable
+	l, _, r := n.splitSubtree(pfx, false)
+	n = l.join(s, false).join(r, false)
+
+	if is4 {
+		t.root4 = n
+	} else {
+		t.root6 = n
+	}
+}
+
+// ReplaceSubtreeImmutable removes pfx and all prefixes covered by pfx and splices in all
+// prefixes of sub that are covered by pfx, returning a new table.
+// Prefixes in sub outside of pfx are ignored.
+func (t Table[V]) ReplaceSubtreeImmutable(pfx netip.Prefix, sub Table[V]) *Table[V] {
+	pfx = pfx.Masked() // always canonicalize!
+
+	is4 := pfx.Addr().Is4()
+
+	n, s := t.root6, sub.root6
+	if is4 {
+		n, s = t.root4, sub.root4
+	}
+
+	// split/join is set to immutable
+	_, s, _ = s.splitSubtree(pfx, true)
+	l, _, r := n.splitSubtree(pfx, true)
+	n = l.join(s, true).join(r, true)
+
+	if is4 {
+		t.root4 = n
+	} else {
+		t.root6 = n
+	}
+
+	return &t
+}
+
+// Clone, deep cloning of the routing table. The clone of a frozen table isn't frozen.
+func (t Table[V]) Clone() *Table[V] {
+	t.frozen = false
+	t.root4 = t.root4.clone()
+	t.root6 = t.root6.clone()
+	return &t
+}
+
+// Union combines two tables, changing the receiver table.
+// If there are duplicate entries, the value is taken from the other table.
+// The tags of the duplicate entries are kept from the receiver, as with Insert.
+func (t *Table[V]) Union(other Table[V]) {
+	t.mustNotBeFrozen()
+	other.mustNotBeFrozen() // the nodes of other are changed
+	t.root4 = t.root4.union(other.root4, true, false, nil)
+	t.root6 = t.root6.union(other.root6, true, false, nil)
+}
+
+// Conflict is a prefix present in both tables of a union, with both values.
+type Conflict[V any] struct {
+	Prefix netip.Prefix
+	Old    V // value in the receiver, overwritten
+	New    V // value in the other table, taken
+}
+
+// UnionConflicts combines two tables like Union, changing the receiver table.
+// Additionally all duplicate entries are returned, sorted by prefix.
+func (t *Table[V]) UnionConflicts(other Table[V]) []Conflict[V] {
+	t.mustNotBeFrozen()
+	other.mustNotBeFrozen() // the nodes of other are changed
+	var conflicts4, conflicts6 []Conflict[V]
+
+	t.root4 = t.root4.union(other.root4, true, false, &conflicts4)
+	t.root6 = t.root6.union(other.root6, true, false, &conflicts6)
+
+	// union records in rec-descent order, not in BST order
+	byPrefix := func(a, b Conflict[V]) int {
+		return compare(a.Prefix, b.Prefix)
+	}
+	slices.SortFunc(conflicts4, byPrefix)
+	slices.SortFunc(conflicts6, byPrefix)
+
+	return append(conflicts4, conflicts6...)
+}
+
+// UnionImmutable combines any two tables immutable and returns the combined table.
+// If there are duplicate entries, the value is taken from the other table.
+// The tags of the duplicate entries are kept from the receiver, as with Insert.
+func (t Table[V]) UnionImmutable(other Table[V]) *Table[V] {
+	t.root4 = t.root4.union(other.root4, true, true, nil)
+	t.root6 = t.root6.union(other.root6, true, true, nil)
+	t.frozen = t.frozen || other.frozen // nodes are shared with both tables
+	return &t
+}
+
+// UnionAll combines all tables immutable and returns the combined table.
+// If there are duplicate entries, the value is taken from the last table.
+//
+// The smallest adjacent tables are merged first, the large tables are copied
+// less often than with repeated pairwise unions in the given order.
+func UnionAll[V any](tables ...Table[V]) *Table[V] {
+	if len(tables) == 0 {
+		return new(Table[V])
+	}
+
+	// only adjacent tables are merged, the order of precedence is preserved
+	queue := make([]*Table[V], 0, len(tables))
+	for i := range tables {
+		queue = append(queue, &tables[i])
+	}
+
+	for len(queue) > 1 {
+		best := 0
+		for i := 1; i < len(queue)-1; i++ {
+			if queue[i].Size()+queue[i+1].Size() < queue[best].Size()+queue[best+1].Size() {
+				best = i
+			}
+		}
+
+		queue[best] = queue[best].UnionImmutable(*queue[best+1])
+		queue = slices.Delete(queue, best+1, best+2)
+	}
+
+	if queue[0] == &tables[0] {
+		// a single table, don't alias the variadic slice
+		t := tables[0]
+		return &t
+	}
+	return queue[0]
+}
+
+// Walk iterates the cidrtree in ascending order.
+// The callback function is called with the prefix and value of the respective node and the depth in the tree.
+// If callback returns `false`, the iteration is aborted.
+func (t Table[V]) Walk(cb func(pfx netip.Prefix, value V) bool) {
+	if !t.root4.walk(cb) {
+		return
+	}
+
+	t.root6.walk(cb)
+}
+
+// Walk4 iterates the IPv4 entries in ascending order, the IPv6 treap isn't touched, see [Table.Walk].
+// If callback returns `false`, the iteration is aborted.
+func (t Table[V]) Walk4(cb func(pfx netip.Prefix, value V) bool) {
+	t.root4.walk(cb)
+}
+
+// Walk6 iterates the IPv6 entries in ascending order, the IPv4 treap isn't touched, see [Table.Walk].
+// If callback returns `false`, the iteration is aborted.
+func (t Table[V]) Walk6(cb func(pfx netip.Prefix, value V) bool) {
+	t.root6.walk(cb)
+}
+
+// ModifyAll iterates all entries in ascending order and replaces their values by the
+// values returned from cb, e.g. to re-label a large table in a single pass.
+// The entries for which cb returns del are removed after the traversal.
+// Returns the number of removed entries.
+//
+// The changed nodes are copied, nodes shared with clones or snapshots are never changed.
+func (t *Table[V]) ModifyAll(cb func(pfx netip.Prefix, value V) (newValue V, del bool)) int {
+	t.mustNotBeFrozen()
+	var pfxs []netip.Prefix
+	t.modifyNodes(func(n *node[V]) *node[V] {
+		value, del := cb(n.cidr, n.value)
+		if del {
+			pfxs = append(pfxs, n.cidr)
+			return nil
+		}
+		c := n.copyNode()
+		c.value = value
+		return c
+	})
+
+	for _, pfx := range pfxs {
+		t.Delete(pfx)
+	}
+	return len(pfxs)
+}
+
+// WalkByEnd iterates the cidrtree in ascending order of the last address of each prefix,
+// for equal last addresses the more specific prefix first, as needed by interval-sweep algorithms.
+// This is the post-order of the CIDR nesting, all subnets precede their supernet.
+// If callback returns `false`, the iteration is aborted.
+func (t Table[V]) WalkByEnd(cb func(pfx netip.Prefix, value V) bool) {
+	walk := func(n *node[V]) bool {
+		return cb(n.cidr, n.value)
+	}
+
+	if !t.root4.walkByEnd(walk) {
+		return
+	}
+	t.root6.walkByEnd(walk)
+}
+
+// WalkTopology iterates the cidrtree in ascending order like Walk, the callback gets the CIDR nesting depth
+// of every prefix, the indentation level of [Table.Fprint]. The top-level prefixes have depth 0.
+// If callback returns `false`, the iteration is aborted.
+func (t Table[V]) WalkTopology(cb func(pfx netip.Prefix, value V, depth int) bool) {
+	walk := func(n *node[V], parents []*node[V]) bool {
+		return cb(n.cidr, n.value, len(parents))
+	}
+
+	if !t.root4.walkNested(walk) {
+		return
+	}
+	t.root6.walkNested(walk)
+}
+
+// WalkErr iterates the cidrtree in ascending order like Walk.
+// If callback returns an error, the iteration is aborted and the error is returned.
+func (t Table[V]) WalkErr(cb func(pfx netip.Prefix, value V) error) error {
+	var err error
+	t.Walk(func(pfx netip.Prefix, value V) bool {
+		err = cb(pfx, value)
+		return err == nil
+	})
+	return err
+}
+
+// PrefixesWithValue returns all prefixes in ascending order whose value satisfies pred.
+func (t Table[V]) PrefixesWithValue(pred func(V) bool) []netip.Prefix {
+	var pfxs []netip.Prefix
+	t.Walk(func(pfx netip.Prefix, value V) bool {
+		if pred(value) {
+			pfxs = append(pfxs, pfx)
+		}
+		return true
+	})
+	return pfxs
+}
+
+// PrefixesWithValueEqual returns all prefixes in ascending order whose value is equal to value,
+// the fast path of [Table.PrefixesWithValue] for comparable values.
+func PrefixesWithValueEqual[V comparable](t Table[V], value V) []netip.Prefix {
+	var pfxs []netip.Prefix
+	t.Walk(func(pfx netip.Prefix, v V) bool {
+		if v == value {
+			pfxs = append(pfxs, pfx)
+		}
+		return true
+	})
+	return pfxs
+}
+
+// GroupByValue groups all prefixes by the key of their value, e.g. next hop → routes.
+// The prefixes in every group are in ascending order.
+func GroupByValue[V any, K comparable](t Table[V], key func(V) K) map[K][]netip.Prefix {
+	groups := make(map[K][]netip.Prefix)
+	t.Walk(func(pfx netip.Prefix, v V) bool {
+		k := key(v)
+		groups[k] = append(groups[k], pfx)
+		return true
+	})
+	return groups
+}
+
+// Minimize removes all entries whose value is equal to the value of the covering supernet,
+// e.g. 10.1.0.0/16 → A under 10.0.0.0/8 → A. The lookup results are not changed.
+// Returns the number of removed entries.
+func (t *Table[V]) Minimize(equal func(a, b V) bool) int {
+	t.mustNotBeFrozen()
+	var redundant []netip.Prefix
+
+	cb := func(n *node[V], parents []*node[V]) bool {
+		// the direct parent is the last one on the stack.
+		// The parent itself may be redundant, but then it has the same value as its parent.
+		if len(parents) > 0 && equal(parents[len(parents)-1].value, n.value) {
+			redundant = append(redundant, n.cidr)
+		}
+		return true
+	}
+
+	t.root4.walkNested(cb)
+	t.root6.walkNested(cb)
+
+	for _, pfx := range redundant {
+		t.Delete(pfx)
+	}
+	return len(redundant)
+}
+
+// Collapse replaces complete sibling sets with equal values by their parent prefix,
+// e.g. both /25s of a /24 are replaced by the /24. This is repeated until no sibling set is left,
+// four /26s with equal values are collapsed to one /24.
+// Siblings under an existing parent prefix with different value are not collapsed.
+// The lookup results are not changed, returns the number of collapsed sibling sets.
+func (t *Table[V]) Collapse(equal func(a, b V) bool) int {
+	t.mustNotBeFrozen()
+	var count int
+
+	for {
+		type pair struct {
+			parent netip.Prefix
+			lower  netip.Prefix
+			upper  netip.Prefix
+			value  V
+			insert bool
+		}
+
+		var pairs []pair
+		t.Walk(func(pfx netip.Prefix, value V) bool {
+			if pfx.Bits() == 0 {
+				return true
+			}
+
+			parent := parentOf(pfx)
+			if parent.Addr() != pfx.Addr() {
+				// not the lower half
+				return true
+			}
+			upper := siblingOf(pfx)
+
+			uVal, ok := t.get(upper)
+			if !ok || !equal(value, uVal) {
+				return true
+			}
+
+			pVal, ok := t.get(parent)
+			if ok && !equal(value, pVal) {
+				return true
+			}
+
+			pairs = append(pairs, pair{parent, pfx, upper, value, !ok})
+			return true
+		})
+
+		if len(pairs) == 0 {
+			return count
+		}
+
+		for _, p := range pairs {
+			t.Delete(p.lower)
+			t.Delete(p.upper)
+			if p.insert {
+				t.Insert(p.parent, p.value)
+			}
+		}
+		count += len(pairs)
+	}
+}
+
+// Exclude punches a hole for pfx into all entries covering pfx. The covering entries
+// are replaced by the minimal set of CIDRs covering everything except pfx, inheriting the values.
+// Entries equal to or covered by pfx are not changed.
+// Returns false if pfx isn't covered by any entry.
+//
+//	10.0.0.0/8 → A, Exclude(10.2.3.0/24):
+//
+//	10.0.0.0/15   → A
+//	10.2.0.0/23   → A
+//	10.2.2.0/24   → A
+//	10.2.4.0/22   → A
+//	 ...
+//	10.128.0.0/9  → A
+func (t *Table[V]) Exclude(pfx netip.Prefix) bool {
+	t.mustNotBeFrozen()
+	pfx = pfx.Masked() // always canonicalize!
+
+	// from least to most specific
+	covering := t.supernets(pfx)
+	if len(covering) == 0 {
+		return false
+	}
+
+	// walk up the sibling chain from pfx to the least specific covering entry
+	var pieces []Entry[V]
+	for p := pfx; p.Bits() > covering[0].cidr.Bits(); p = parentOf(p) {
+		sib := siblingOf(p)
+
+		// an existing entry for the sibling already overrides the covering entries
+		if _, ok := t.get(sib); ok {
+			continue
+		}
+
+		// the most specific covering entry of the sibling gives the value
+		var value V
+		for _, c := range covering {
+			if c.cidr.Bits() < p.Bits() {
+				value = c.value
+			}
+		}
+		pieces = append(pieces, Entry[V]{Prefix: sib, Value: value})
+	}
+
+	for _, c := range covering {
+		t.Delete(c.cidr)
+	}
+
+	for _, e := range pieces {
+		t.Insert(e.Prefix, e.Value)
+	}
+
+	return true
+}
+
+// Children returns the entries directly nested under pfx in ascending order, one CIDR containment
+// level down, the relationship rendered by [Table.Fprint]. The entries nested under the children are skipped.
+// If pfx isn't in the table, the top-level entries covered by pfx are returned.
+func (t Table[V]) Children(pfx netip.Prefix) []Entry[V] {
+	pfx = pfx.Masked() // always canonicalize!
+
+	n := t.root6
+	if pfx.Addr().Is4() {
+		n = t.root4
+	}
+
+	_, last := extnetip.Range(pfx)
+	lastHost := netip.PrefixFrom(last, last.BitLen())
+
+	var children []Entry[V]
+	n.walkRange(pfx, lastHost, func(m *node[V]) bool {
+		if m.cidr == pfx {
+			return true
+		}
+
+		// supersets sort before subsets, nested in the previous child
+		if len(children) > 0 && children[len(children)-1].Prefix.Contains(m.cidr.Addr()) {
+			return true
+		}
+
+		children = append(children, Entry[V]{Prefix: m.cidr, Value: m.value})
+		return true
+	})
+
+	return children
+}
+
+// NestingDepth returns the number of entries strictly covering pfx, the depth of pfx in the CIDR hierarchy.
+// The top-level entries have depth 0, pfx itself needs not be in the table.
+func (t Table[V]) NestingDepth(pfx netip.Prefix) int {
+	return len(t.supernets(pfx.Masked()))
+}
+
+// Coverage returns the number of addresses of scope covered by the entries of the table
+// and the covered fraction of scope, e.g. for utilization reports per IP version
+// with the scopes 0.0.0.0/0 and ::/0. Overlapping entries are counted once.
+func (t Table[V]) Coverage(scope netip.Prefix) (covered *big.Int, fraction float64) {
+	scope = scope.Masked() // always canonicalize!
+	covered = new(big.Int)
+	if !scope.IsValid() {
+		return covered, 0
+	}
+
+	bitLen := scope.Addr().BitLen()
+	total := new(big.Int).Lsh(big.NewInt(1), uint(bitLen-scope.Bits()))
+
+	// scope is covered by an entry
+	if _, ok := t.get(scope); ok || len(t.supernets(scope)) > 0 {
+		return total, 1
+	}
+
+	// the top-level entries within scope are disjunct
+	for _, e := range t.Children(scope) {
+		covered.Add(covered, new(big.Int).Lsh(big.NewInt(1), uint(bitLen-e.Prefix.Bits())))
+	}
+
+	fraction, _ = new(big.Rat).SetFrac(covered, total).Float64()
+	return covered, fraction
+}
+
+// supernets returns all entries strictly covering pfx, from least to most specific.
+func (t Table[V]) supernets(pfx netip.Prefix) []*node[V] {
+	n := t.root6
+	if pfx.Addr().Is4() {
+		n = t.root4
+	}
+
+	var covering []*node[V]
+	for bits := 0; bits < pfx.Bits(); bits++ {
+		if m := n.find(netip.PrefixFrom(pfx.Addr(), bits).Masked()); m != nil {
+			covering = append(covering, m)
+		}
+	}
+	return covering
+}
+
+// Complement returns a new table with the CIDRs not covered by any entry of the receiver,
+// within 0.0.0.0/0 and ::/0. The values of the new table are the zero values of V.
+func (t Table[V]) Complement() *Table[V] {
+	c := t.ComplementWithin(netip.MustParsePrefix("0.0.0.0/0"))
+	c6 := t.ComplementWithin(netip.MustParsePrefix("::/0"))
+
+	c.root6 = c6.root6
+	return c
+}
+
+// ComplementWithin returns a new table with the CIDRs within scope not covered by any entry of
+// the receiver. The values of the new table are the zero values of V.
+func (t Table[V]) ComplementWithin(scope netip.Prefix) *Table[V] {
+	scope = scope.Masked() // always canonicalize!
+
+	c := new(Table[V])
+
+	// scope is completely covered
+	if _, ok := t.get(scope); ok || len(t.supernets(scope)) > 0 {
+		return c
+	}
+
+	var zero V
+	for _, pfx := range t.gaps(scope) {
+		c.Insert(pfx, zero)
+	}
+	return c
+}
+
+// gaps returns the CIDRs within scope not covered by any entry strictly covered by scope.
+// The entries equal to or covering the scope are not taken into account.
+func (t Table[V]) gaps(scope netip.Prefix) []netip.Prefix {
+	n := t.root6
+	if scope.Addr().Is4() {
+		n = t.root4
+	}
+
+	first, last := extnetip.Range(scope)
+	lastHost := netip.PrefixFrom(last, last.BitLen())
+
+	var pfxs []netip.Prefix
+	cursor := first
+	done := false
+
+	n.walkRange(scope, lastHost, func(n *node[V]) bool {
+		// skip the scope itself
+		if n.cidr == scope {
+			return true
+		}
+
+		nFirst, nLast := extnetip.Range(n.cidr)
+
+		// nested in the previous top-level entry, already covered
+		if nFirst.Less(cursor) {
+			return true
+		}
+
+		// gap before this top-level entry
+		if cursor.Less(nFirst) {
+			pfxs = extnetip.PrefixesAppend(pfxs, cursor, nFirst.Prev())
+		}
+
+		if nLast == last {
+			done = true
+			return false
+		}
+		cursor = nLast.Next()
+		return true
+	})
+
+	if !done {
+		pfxs = extnetip.PrefixesAppend(pfxs, cursor, last)
+	}
+
+	return pfxs
+}
+
+// Sample returns n uniformly random entries of the table without repetition, in ascending order.
+// If n is greater than the number of entries, all entries are returned, for n <= 0 Sample returns nil.
+// Every entry is selected by rank in O(log n) with the augmented subtree sizes.
+func (t Table[V]) Sample(n int) []Entry[V] {
+	size4 := t.root4.getSize()
+	total := size4 + t.root6.getSize()
+	if n > total {
+		n = total
+	}
+	if n <= 0 {
+		return nil
+	}
+
+	// Floyd's algorithm, n distinct random ranks
+	ranks := make(map[int]struct{}, n)
+	for j := total - n; j < total; j++ {
+		r := mrand.Intn(j + 1)
+		if _, ok := ranks[r]; ok {
+			r = j
+		}
+		ranks[r] = struct{}{}
+	}
+
+	sorted := make([]int, 0, n)
+	for r := range ranks {
+		sorted = append(sorted, r)
+	}
+	slices.Sort(sorted)
+
+	entries := make([]Entry[V], 0, n)
+	for _, r := range sorted {
+		var m *node[V]
+		if r < size4 {
+			m = t.root4.at(r)
+		} else {
+			m = t.root6.at(r - size4)
+		}
+		entries = append(entries, Entry[V]{Prefix: m.cidr, Value: m.value})
+	}
+
+	return entries
+}
+
+// promotedPrio is the lower bound of the top band of priorities, see Promote.
+// Random priorities are in the band with a probability of 2^-32.
+const promotedPrio = math.MaxUint64 - 1<<32 + 1
+
+// Promote moves the entry for pfx towards the root of the treap, returns false if pfx isn't in the table.
+// The priority of the node is raised halfway to the max priority, frequently promoted entries,
+// e.g. the hot prefixes of a [TopK] report, are found with shorter lookup paths.
+// The boost is capped, within the top band the priority is random again. The promoted entries
+// are a random treap on top of the others, the height stays logarithmic also after repeated
+// promotion of many entries. Every call costs just one split, join and insert.
+func (t *Table[V]) Promote(pfx netip.Prefix) bool {
+	t.mustNotBeFrozen()
+	pfx = pfx.Masked() // always canonicalize!
+
+	is4 := pfx.Addr().Is4()
+
+	n := t.root6
+	if is4 {
+		n = t.root4
+	}
+
+	// split/join is set to mutable
+	l, m, r := n.split(pfx, false)
+	n = l.join(r, false)
+
+	if m != nil {
+		// reinsert a copy with higher prio, m may be shared with other treaps
+		m = m.copyNode()
+		m.prio += (math.MaxUint64 - m.prio) / 2
+		if m.prio >= promotedPrio {
+			// capped, a random priority within the top band
+			m.prio = promotedPrio + mrand.Uint64()>>32
+		}
+		m.recalc()
+
+		n, _ = n.insert(m, false)
+	}
+
+	if is4 {
+		t.root4 = n
+	} else {
+		t.root6 = n
+	}
+
+	return m != nil
+}
+
+// Size returns the number of entries in the table, O(1) with the augmented subtree sizes.
+func (t Table[V]) Size() int {
+	return t.root4.getSize() + t.root6.getSize()
+}
+
+// Size4 returns the number of IPv4 entries in the table, O(1).
+func (t Table[V]) Size4() int {
+	return t.root4.getSize()
+}
+
+// Size6 returns the number of IPv6 entries in the table, O(1).
+func (t Table[V]) Size6() int {
+	return t.root6.getSize()
+}
+
+// Height returns the height of the IPv4 and IPv6 treaps, the number of nodes on the longest
+// path from the root, O(1) with the augmented subtree heights. The expected height of the
+// randomized treap is O(log n), a height far beyond 3*log2(n) indicates a degenerated treap.
+func (t Table[V]) Height() (height4, height6 int) {
+	return t.root4.getHeight(), t.root6.getHeight()
+}
+
+// IsEmpty reports whether the table has no entries.
+func (t Table[V]) IsEmpty() bool {
+	return t.root4 == nil && t.root6 == nil
+}
+
+// IsEmpty4 reports whether the table has no IPv4 entries.
+func (t Table[V]) IsEmpty4() bool {
+	return t.root4 == nil
 }
 
-// Clone, deep cloning of the routing table.
-func (t Table[V]) Clone() *Table[V] {
-	t.root4 = t.root4.clone()
-	t.root6 = t.root6.clone()
-	return &t
+// IsEmpty6 reports whether the table has no IPv6 entries.
+func (t Table[V]) IsEmpty6() bool {
+	return t.root6 == nil
 }
 
-// Union combines two tables, changing the receiver table.
-// If there are duplicate entries, the value is taken from the other table.
-func (t *Table[V]) Union(other Table[V]) {
-	t.root4 = t.root4.union(other.root4, true, false)
-	t.root6 = t.root6.union(other.root6, true, false)
+// Freeze marks the table as read-only, all following calls of mutable methods panic.
+// Tables returned by the immutable methods of a frozen table are also frozen, they share nodes.
+// Freeze can't be undone, use Clone to get a mutable copy.
+func (t *Table[V]) Freeze() {
+	t.frozen = true
 }
 
-// UnionImmutable combines any two tables immutable and returns the combined table.
-// If there are duplicate entries, the value is taken from the other table.
-func (t Table[V]) UnionImmutable(other Table[V]) *Table[V] {
-	t.root4 = t.root4.union(other.root4, true, true)
-	t.root6 = t.root6.union(other.root6, true, true)
-	return &t
+// Frozen reports whether the table is read-only, see Freeze.
+func (t Table[V]) Frozen() bool {
+	return t.frozen
 }
 
-// Walk iterates the cidrtree in ascending order.
-// The callback function is called with the prefix and value of the respective node and the depth in the tree.
-// If callback returns `false`, the iteration is aborted.
-func (t Table[V]) Walk(cb func(pfx netip.Prefix, value V) bool) {
-	if !t.root4.walk(cb) {
-		return
+// mustNotBeFrozen panics if the table is frozen.
+func (t Table[V]) mustNotBeFrozen() {
+	if t.frozen {
+		panic("cidrtree: mutable method called on frozen table")
 	}
+}
 
-	t.root6.walk(cb)
+// get the value for the exact pfx, true if pfx is in the table.
+func (t Table[V]) get(pfx netip.Prefix) (value V, ok bool) {
+	n := t.root6
+	if pfx.Addr().Is4() {
+		n = t.root4
+	}
+
+	if n = n.find(pfx); n == nil {
+		return
+	}
+	return n.value, true
 }
 
 // insert into treap, changing nodes are copied, new treap is returned,
 // old treap is modified if immutable is false.
-// If node is already present in the table, its value is set to val.
-func (n *node[V]) insert(m *node[V], immutable bool) *node[V] {
+// If node is already present in the table, it is replaced by m and returned as dupe.
+func (n *node[V]) insert(m *node[V], immutable bool) (root, dupe *node[V]) {
 	if n == nil {
 		// recursion stop condition
-		return m
+		return m, nil
 	}
 
 	// if m is the new root?
@@ -198,7 +1180,8 @@ func (n *node[V]) insert(m *node[V], immutable bool) *node[V] {
 
 		// replace dupe with m. m has same key but different prio than dupe, a join() is required
 		if dupe != nil {
-			return l.join(m.join(r, immutable), immutable)
+			m.meta = dupe.meta // keep the tags
+			return l.join(m.join(r, immutable), immutable), dupe
 		}
 
 		// no duplicate, take m as new root
@@ -209,13 +1192,14 @@ func (n *node[V]) insert(m *node[V], immutable bool) *node[V] {
 		//
 		m.left, m.right = l, r
 		m.recalc() // m has changed, recalc
-		return m
+		return m, nil
 	}
 
 	cmp := compare(m.cidr, n.cidr)
 	if cmp == 0 {
 		// replace duplicate item with m, but m has different prio, a join() is required
-		return n.left.join(m.join(n.right, immutable), immutable)
+		m.meta = n.meta // keep the tags
+		return n.left.join(m.join(n.right, immutable), immutable), n
 	}
 
 	if immutable {
@@ -224,14 +1208,14 @@ func (n *node[V]) insert(m *node[V], immutable bool) *node[V] {
 
 	switch {
 	case cmp < 0: // rec-descent
-		n.left = n.left.insert(m, immutable)
+		n.left, dupe = n.left.insert(m, immutable)
 		//
 		//       R
 		// m    l r
 		//     l   r
 		//
 	case cmp > 0: // rec-descent
-		n.right = n.right.insert(m, immutable)
+		n.right, dupe = n.right.insert(m, immutable)
 		//
 		//   R
 		//  l r    m
@@ -240,12 +1224,13 @@ func (n *node[V]) insert(m *node[V], immutable bool) *node[V] {
 	}
 
 	n.recalc() // n has changed, recalc
-	return n
+	return n, dupe
 }
 
 // union two treaps.
 // flag overwrite isn't public but needed as input for rec-descent calls, see below when trepa are swapped.
-func (n *node[V]) union(b *node[V], overwrite bool, immutable bool) *node[V] {
+// If conflicts is not nil, the duplicate items are recorded, see UnionConflicts.
+func (n *node[V]) union(b *node[V], overwrite bool, immutable bool, conflicts *[]Conflict[V]) *node[V] {
 	// recursion stop condition
 	if n == nil {
 		return b
@@ -270,15 +1255,29 @@ func (n *node[V]) union(b *node[V], overwrite bool, immutable bool) *node[V] {
 	// with the higher priority, skip duplicates
 	l, dupe, r := b.split(n.cidr, immutable)
 
-	// the treaps may have duplicate items
-	if overwrite && dupe != nil {
-		n.cidr = dupe.cidr
-		n.value = dupe.value
+	// record the duplicate items, n and dupe may be swapped, see overwrite flag
+	if conflicts != nil && dupe != nil {
+		if overwrite {
+			*conflicts = append(*conflicts, Conflict[V]{Prefix: n.cidr, Old: n.value, New: dupe.value})
+		} else {
+			*conflicts = append(*conflicts, Conflict[V]{Prefix: n.cidr, Old: dupe.value, New: n.value})
+		}
+	}
+
+	// the treaps may have duplicate items, the tags of the receiver are kept as with Insert,
+	// independent of the priorities
+	if dupe != nil {
+		if overwrite {
+			n.cidr = dupe.cidr
+			n.value = dupe.value
+		} else {
+			n.meta = dupe.meta
+		}
 	}
 
 	// rec-descent
-	n.left = n.left.union(l, overwrite, immutable)
-	n.right = n.right.union(r, overwrite, immutable)
+	n.left = n.left.union(l, overwrite, immutable, conflicts)
+	n.right = n.right.union(r, overwrite, immutable, conflicts)
 
 	n.recalc() // n has changed, recalc
 	return n
@@ -308,8 +1307,196 @@ func (n *node[V]) walk(cb func(netip.Prefix, V) bool) bool {
 	return true
 }
 
-// lpmIP rec-descent
+// find the node with exact cidr, BST search.
+func (n *node[V]) find(cidr netip.Prefix) *node[V] {
+	for n != nil {
+		cmp := compare(cidr, n.cidr)
+		switch {
+		case cmp < 0:
+			n = n.left
+		case cmp > 0:
+			n = n.right
+		default:
+			return n
+		}
+	}
+	return nil
+}
+
+// at returns the node with rank i in ascending order, i must be less than the subtree size.
+func (n *node[V]) at(i int) *node[V] {
+	for {
+		l := n.left.getSize()
+		switch {
+		case i < l:
+			n = n.left
+		case i > l:
+			i -= l + 1
+			n = n.right
+		default:
+			return n
+		}
+	}
+}
+
+// walkNodes in ascending prefix order.
+func (n *node[V]) walkNodes(cb func(*node[V]) bool) bool {
+	if n == nil {
+		return true
+	}
+
+	return n.left.walkNodes(cb) && cb(n) && n.right.walkNodes(cb)
+}
+
+// walkRange in ascending prefix order, only nodes with lo <= cidr <= hi, pruned BST traversal.
+func (n *node[V]) walkRange(lo, hi netip.Prefix, cb func(*node[V]) bool) bool {
+	if n == nil {
+		return true
+	}
+
+	cmpLo := compare(n.cidr, lo)
+	cmpHi := compare(n.cidr, hi)
+
+	// left
+	if cmpLo > 0 && !n.left.walkRange(lo, hi, cb) {
+		return false
+	}
+
+	// do-it
+	if cmpLo >= 0 && cmpHi <= 0 && !cb(n) {
+		return false
+	}
+
+	// right
+	if cmpHi < 0 && !n.right.walkRange(lo, hi, cb) {
+		return false
+	}
+
+	return true
+}
+
+// walkNested in ascending prefix order, the callback gets the stack of covering nodes
+// (CIDR containment) for every node, the direct parent is the last one on the stack.
+// The stack is reused, the callback must not retain it.
+func (n *node[V]) walkNested(cb func(n *node[V], parents []*node[V]) bool) bool {
+	var stack []*node[V]
+
+	return n.walkNodes(func(n *node[V]) bool {
+		// Remember: sort order of CIDRs is lower-left, superset to the left:
+		// remove all nodes from stack not covering this cidr
+		for len(stack) > 0 && !stack[len(stack)-1].cidr.Contains(n.cidr.Addr()) {
+			stack = stack[:len(stack)-1]
+		}
+
+		if !cb(n, stack) {
+			return false
+		}
+
+		stack = append(stack, n)
+		return true
+	})
+}
+
+// segment of the address space, starting at addr up to the start of the next segment,
+// node is the longest-prefix-match for all addresses in the segment, nil if uncovered.
+type segment[V any] struct {
+	start netip.Addr
+	node  *node[V]
+}
+
+// segments partitions the address space of the treap into disjunct segments in ascending order,
+// starting at zero. Adjacent segments have different lpm nodes.
+func (n *node[V]) segments(zero netip.Addr) []segment[V] {
+	segs := []segment[V]{{start: zero}}
+
+	emit := func(start netip.Addr, m *node[V]) {
+		last := &segs[len(segs)-1]
+		if last.start == start {
+			// overridden by a more specific node with the same start
+			last.node = m
+			if len(segs) > 1 && segs[len(segs)-2].node == m {
+				segs = segs[:len(segs)-1]
+			}
+			return
+		}
+		if last.node == m {
+			return
+		}
+		segs = append(segs, segment[V]{start: start, node: m})
+	}
+
+	type open struct {
+		node *node[V]
+		last netip.Addr
+	}
+	var stack []open
+
+	// close the top of stack, the space after it falls back to the parent
+	pop := func() {
+		top := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		var parent *node[V]
+		if len(stack) > 0 {
+			parent = stack[len(stack)-1].node
+		}
+		if next := top.last.Next(); next.IsValid() {
+			emit(next, parent)
+		}
+	}
+
+	n.walkNodes(func(m *node[V]) bool {
+		first, last := extnetip.Range(m.cidr)
+		for len(stack) > 0 && stack[len(stack)-1].last.Less(first) {
+			pop()
+		}
+
+		emit(first, m)
+		stack = append(stack, open{node: m, last: last})
+		return true
+	})
+
+	for len(stack) > 0 {
+		pop()
+	}
+
+	return segs
+}
+
+// walkByEnd calls cb for all nodes in ascending order of the last address, the post-order of the nesting.
+func (n *node[V]) walkByEnd(cb func(*node[V]) bool) bool {
+	var stack []*node[V]
+
+	if !n.walkNodes(func(n *node[V]) bool {
+		// all nodes on the stack not covering n are finished
+		for len(stack) > 0 && !stack[len(stack)-1].cidr.Contains(n.cidr.Addr()) {
+			if !cb(stack[len(stack)-1]) {
+				return false
+			}
+			stack = stack[:len(stack)-1]
+		}
+
+		stack = append(stack, n)
+		return true
+	}) {
+		return false
+	}
+
+	for i := len(stack) - 1; i >= 0; i-- {
+		if !cb(stack[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// lpmIP, the key of ip for the fast exits is computed only once
 func (n *node[V]) lpmIP(ip netip.Addr, depth int) (lpm netip.Prefix, value V, ok bool, atDepth int) {
+	return n.lpmIPKey(ip, key6(ip), depth)
+}
+
+// lpmIPKey rec-descent
+func (n *node[V]) lpmIPKey(ip netip.Addr, key uint128, depth int) (lpm netip.Prefix, value V, ok bool, atDepth int) {
 	for {
 		// recursion stop condition
 		if n == nil {
@@ -317,7 +1504,7 @@ func (n *node[V]) lpmIP(ip netip.Addr, depth int) (lpm netip.Prefix, value V, ok
 		}
 
 		// fast exit with (augmented) max upper value
-		if ipTooBig(ip, n.maxUpper.cidr) {
+		if ipTooBig(key, n.maxLast) {
 			// recursion stop condition
 			return
 		}
@@ -333,7 +1520,7 @@ func (n *node[V]) lpmIP(ip netip.Addr, depth int) (lpm netip.Prefix, value V, ok
 	}
 
 	// right backtracking
-	if lpm, value, ok, atDepth = n.right.lpmIP(ip, depth+1); ok {
+	if lpm, value, ok, atDepth = n.right.lpmIPKey(ip, key, depth+1); ok {
 		return
 	}
 
@@ -343,11 +1530,108 @@ func (n *node[V]) lpmIP(ip netip.Addr, depth int) (lpm netip.Prefix, value V, ok
 	}
 
 	// left rec-descent
-	return n.left.lpmIP(ip, depth+1)
+	return n.left.lpmIPKey(ip, key, depth+1)
+}
+
+// lpmIPFunc, like lpmIP but only matches passing keep
+func (n *node[V]) lpmIPFunc(ip netip.Addr, keep func(netip.Prefix, V) bool) (lpm netip.Prefix, value V, ok bool) {
+	return n.lpmIPFuncKey(ip, key6(ip), keep)
+}
+
+// lpmIPFuncKey rec-descent
+func (n *node[V]) lpmIPFuncKey(ip netip.Addr, key uint128, keep func(netip.Prefix, V) bool) (lpm netip.Prefix, value V, ok bool) {
+	for {
+		// recursion stop condition
+		if n == nil {
+			return
+		}
+
+		// fast exit with (augmented) max upper value
+		if ipTooBig(key, n.maxLast) {
+			// recursion stop condition
+			return
+		}
+
+		// if cidr is already less-or-equal ip
+		if n.cidr.Addr().Compare(ip) <= 0 {
+			break // ok, proceed with this cidr
+		}
+
+		// fast traverse to left
+		n = n.left
+	}
+
+	// right backtracking
+	if lpm, value, ok = n.right.lpmIPFuncKey(ip, key, keep); ok {
+		return
+	}
+
+	// lpm match, if it passes the predicate
+	if n.cidr.Contains(ip) && keep(n.cidr, n.value) {
+		return n.cidr, n.value, true
+	}
+
+	// left rec-descent
+	return n.left.lpmIPFuncKey(ip, key, keep)
+}
+
+// contains, like lpmIP but any match is sufficient
+func (n *node[V]) contains(ip netip.Addr) bool {
+	return n.containsKey(ip, key6(ip))
+}
+
+// containsKey rec-descent
+func (n *node[V]) containsKey(ip netip.Addr, key uint128) bool {
+	for {
+		// recursion stop condition
+		if n == nil {
+			return false
+		}
+
+		// fast exit with (augmented) max upper value
+		if ipTooBig(key, n.maxLast) {
+			// recursion stop condition
+			return false
+		}
+
+		// if cidr is already less-or-equal ip
+		if n.cidr.Addr().Compare(ip) <= 0 {
+			break // ok, proceed with this cidr
+		}
+
+		// fast traverse to left
+		n = n.left
+	}
+
+	// match
+	if n.cidr.Contains(ip) {
+		return true
+	}
+
+	// right backtracking, left rec-descent
+	return n.right.containsKey(ip, key) || n.left.containsKey(ip, key)
+}
+
+// hasSubnets, the subnets of pfx are the direct successors of pfx in the BST order.
+// Find the successor of pfx and check if it is covered by pfx.
+func (n *node[V]) hasSubnets(pfx netip.Prefix) bool {
+	for n != nil {
+		if compare(n.cidr, pfx) <= 0 {
+			n = n.right
+			continue
+		}
+
+		// n is greater than pfx, a subnet or behind all subnets of pfx
+		if pfx.Contains(n.cidr.Addr()) {
+			return true
+		}
+		n = n.left
+	}
+	return false
 }
 
-// lpmCIDR rec-descent
-func (n *node[V]) lpmCIDR(pfx netip.Prefix, depth int) (lpm netip.Prefix, value V, ok bool, atDepth int) {
+// lpmCIDR rec-descent, last is the key of the last address of pfx, computed once by the caller.
+func (n *node[V]) lpmCIDR(pfx netip.Prefix, last uint128, depth int) (lpm netip.Prefix, value V, ok bool, atDepth int) {
 	for {
 		// recursion stop condition
 		if n == nil {
@@ -355,7 +1639,7 @@ func (n *node[V]) lpmCIDR(pfx netip.Prefix, depth int) (lpm netip.Prefix, value
 		}
 
 		// fast exit with (augmented) max upper value
-		if pfxTooBig(pfx, n.maxUpper.cidr) {
+		if ipTooBig(last, n.maxLast) {
 			// recursion stop condition
 			return
 		}
@@ -378,7 +1662,7 @@ func (n *node[V]) lpmCIDR(pfx netip.Prefix, depth int) (lpm netip.Prefix, value
 	}
 
 	// right backtracking
-	if lpm, value, ok, atDepth = n.right.lpmCIDR(pfx, depth+1); ok {
+	if lpm, value, ok, atDepth = n.right.lpmCIDR(pfx, last, depth+1); ok {
 		return
 	}
 
@@ -396,7 +1680,7 @@ func (n *node[V]) lpmCIDR(pfx netip.Prefix, depth int) (lpm netip.Prefix, value
 	// ... or disjunct
 
 	// left rec-descent
-	return n.left.lpmCIDR(pfx, depth+1)
+	return n.left.lpmCIDR(pfx, last, depth+1)
 }
 
 func (n *node[V]) clone() *node[V] {
@@ -470,6 +1754,23 @@ func (n *node[V]) split(cidr netip.Prefix, immutable bool) (left, mid, right *no
 	}
 }
 
+// splitSubtree the treap into all nodes that compare less-than pfx, all nodes covered by pfx
+// (including pfx itself) and all nodes greater-than the last possible subnet of pfx.
+// The covered nodes form a contiguous range in the BST order, two splits are sufficient.
+func (n *node[V]) splitSubtree(pfx netip.Prefix, immutable bool) (left, mid, right *node[V]) {
+	_, last := extnetip.Range(pfx)
+	lastHost := netip.PrefixFrom(last, last.BitLen())
+
+	//      left     |        mid         |    right
+	//  -------- ( pfx ... subnets ... lastHost ) --------
+	//
+	left, m, r := n.split(pfx, immutable)
+	mid, lm, right := r.split(lastHost, immutable)
+
+	mid = m.join(mid, immutable).join(lm, immutable)
+	return left, mid, right
+}
+
 // join combines two disjunct treaps. All nodes in treap n have keys <= that of treap m
 // for this algorithm to work correctly. If the join must be immutable, first copy concerned nodes.
 func (n *node[V]) join(m *node[V], immutable bool) *node[V] {
@@ -515,17 +1816,142 @@ func makeNode[V any](pfx netip.Prefix, value V) *node[V] {
 	n := new(node[V])
 	n.cidr = pfx.Masked() // always store the prefix in normalized form
 	n.value = value
+	// the global source is lock-free, as long as nobody calls the deprecated mrand.Seed
 	n.prio = mrand.Uint64()
 	n.recalc() // init the augmented field with recalc
 	return n
 }
 
+// build a treap from unsorted nodes in linear time after sorting,
+// for duplicate cidrs the last node wins.
+func build[V any](nodes []*node[V]) *node[V] {
+	// stable sort, the last one of duplicates is the last in a run
+	slices.SortStableFunc(nodes, func(a, b *node[V]) int {
+		return compare(a.cidr, b.cidr)
+	})
+
+	// the right spine of the treap, the root is at the bottom
+	var spine []*node[V]
+
+	for i, n := range nodes {
+		// skip duplicates, take the last one
+		if i < len(nodes)-1 && nodes[i+1].cidr == n.cidr {
+			continue
+		}
+
+		// nodes with lower prio on the spine become the left subtree of n
+		var last *node[V]
+		for len(spine) > 0 && spine[len(spine)-1].prio < n.prio {
+			last = spine[len(spine)-1]
+			spine = spine[:len(spine)-1]
+		}
+		n.left = last
+
+		if len(spine) > 0 {
+			spine[len(spine)-1].right = n
+		}
+		spine = append(spine, n)
+	}
+
+	if len(spine) == 0 {
+		return nil
+	}
+
+	root := spine[0]
+	root.recalcAll()
+	return root
+}
+
+// recalcAll the augmented fields in the whole treap, bottom up.
+func (n *node[V]) recalcAll() {
+	if n == nil {
+		return
+	}
+	n.left.recalcAll()
+	n.right.recalcAll()
+	n.recalc()
+}
+
 // copyNode, make a shallow copy of the pointers and the cidr.
 func (n *node[V]) copyNode() *node[V] {
 	c := *n
 	return &c
 }
 
+// modifyNode calls fn with a copy of the node for the canonical pfx, the path from the root
+// to it is copied if fn returns true. Nodes are shared between the treaps of clones and
+// immutable versions, they are never changed in place. Returns false if pfx isn't in the table
+// or fn returns false, then nothing is copied.
+//
+// Only the value and the meta data of the copy may be changed, not the key or the links.
+func (t *Table[V]) modifyNode(pfx netip.Prefix, fn func(c *node[V]) bool) (ok bool) {
+	if pfx.Addr().Is4() {
+		t.root4, ok = t.root4.modify(pfx, fn)
+		return
+	}
+	t.root6, ok = t.root6.modify(pfx, fn)
+	return
+}
+
+// modify rec-descent, returns n unchanged without the node or if fn returns false.
+func (n *node[V]) modify(pfx netip.Prefix, fn func(c *node[V]) bool) (*node[V], bool) {
+	if n == nil {
+		return nil, false
+	}
+
+	// the augmented fields depend on the key and the links only, no recalc needed
+	var c *node[V]
+	switch cmp := compare(pfx, n.cidr); {
+	case cmp < 0:
+		left, ok := n.left.modify(pfx, fn)
+		if !ok {
+			return n, false
+		}
+		c = n.copyNode()
+		c.left = left
+	case cmp > 0:
+		right, ok := n.right.modify(pfx, fn)
+		if !ok {
+			return n, false
+		}
+		c = n.copyNode()
+		c.right = right
+	default:
+		c = n.copyNode()
+		if !fn(c) {
+			return n, false
+		}
+	}
+	return c, true
+}
+
+// modifyNodes calls fn for all nodes in ascending order, fn returns a changed copy of the node
+// or nil if unchanged. The paths to the changed nodes are copied, see modifyNode.
+func (t *Table[V]) modifyNodes(fn func(n *node[V]) *node[V]) {
+	t.root4 = t.root4.modifyEach(fn)
+	t.root6 = t.root6.modifyEach(fn)
+}
+
+// modifyEach rec-descent, in-order.
+func (n *node[V]) modifyEach(fn func(n *node[V]) *node[V]) *node[V] {
+	if n == nil {
+		return nil
+	}
+
+	left := n.left.modifyEach(fn)
+	c := fn(n)
+	right := n.right.modifyEach(fn)
+
+	if c == nil {
+		if left == n.left && right == n.right {
+			return n
+		}
+		c = n.copyNode()
+	}
+	c.left, c.right = left, right
+	return c
+}
+
 // recalc the augmented fields in treap node after each creation/modification
 // with values in descendants.
 // Only one level deeper must be considered. The treap datastructure is very easy to augment.
@@ -534,19 +1960,62 @@ func (n *node[V]) recalc() {
 		return
 	}
 
-	n.maxUpper = n
+	_, last := extnetip.Range(n.cidr)
+	n.maxLast = key6(last)
+	n.size = int32(1 + n.left.getSize() + n.right.getSize())
+	n.height = int16(1 + max(n.left.getHeight(), n.right.getHeight()))
+	n.minBits = uint8(n.cidr.Bits())
+	n.maxBits = n.minBits
 
 	if n.right != nil {
-		if cmpRR(n.right.maxUpper.cidr, n.maxUpper.cidr) > 0 {
-			n.maxUpper = n.right.maxUpper
+		if n.maxLast.less(n.right.maxLast) {
+			n.maxLast = n.right.maxLast
 		}
+		n.minBits = min(n.minBits, n.right.minBits)
+		n.maxBits = max(n.maxBits, n.right.maxBits)
 	}
 
 	if n.left != nil {
-		if cmpRR(n.left.maxUpper.cidr, n.maxUpper.cidr) > 0 {
-			n.maxUpper = n.left.maxUpper
+		if n.maxLast.less(n.left.maxLast) {
+			n.maxLast = n.left.maxLast
 		}
+		n.minBits = min(n.minBits, n.left.minBits)
+		n.maxBits = max(n.maxBits, n.left.maxBits)
+	}
+}
+
+// parentOf returns the prefix one bit shorter, pfx must be canonical and not /0.
+func parentOf(pfx netip.Prefix) netip.Prefix {
+	return netip.PrefixFrom(pfx.Addr(), pfx.Bits()-1).Masked()
+}
+
+// siblingOf returns the other half of the parent prefix, pfx must be canonical and not /0.
+func siblingOf(pfx netip.Prefix) netip.Prefix {
+	parent := parentOf(pfx)
+
+	// pfx is the upper half
+	if parent.Addr() != pfx.Addr() {
+		return netip.PrefixFrom(parent.Addr(), pfx.Bits())
+	}
+
+	// pfx is the lower half, the upper half starts after the last address
+	_, last := extnetip.Range(pfx)
+	return netip.PrefixFrom(last.Next(), pfx.Bits())
+}
+
+// getSize returns the augmented subtree size, nil safe.
+func (n *node[V]) getSize() int {
+	if n == nil {
+		return 0
+	}
+	return int(n.size)
+}
+
+func (n *node[V]) getHeight() int {
+	if n == nil {
+		return 0
 	}
+	return int(n.height)
 }
 
 // compare two prefixes and sort by the left address,
@@ -566,36 +2035,13 @@ func compare(a, b netip.Prefix) int {
 	return cmp.Compare(a.Bits(), b.Bits())
 }
 
-// cmpRR compares the prefixes last address.
-func cmpRR(a, b netip.Prefix) int {
-	if a == b {
-		return 0
-	}
-	_, aLast := extnetip.Range(a)
-	_, bLast := extnetip.Range(b)
-
-	return aLast.Compare(bLast)
-}
-
-// ipTooBig returns true if ip is greater than prefix last ip address.
+// ipTooBig returns true if ip is greater than the last ip address.
 //
 //		  false                    true
 //		    |                        |
 //		    V                        V
 //
-//	  ------- other -------->
-func ipTooBig(ip netip.Addr, other netip.Prefix) bool {
-	_, pLastIP := extnetip.Range(other)
-	return ip.Compare(pLastIP) > 0
-}
-
-// pfxTooBig returns true if prefix last address is greater than other last ip address.
-//
-//	------------ pfx --------------> true
-//	------ pfx ----> false
-//
-//	------- other -------->
-func pfxTooBig(pfx netip.Prefix, other netip.Prefix) bool {
-	_, pfxLastIP := extnetip.Range(pfx)
-	return ipTooBig(pfxLastIP, other)
+//	  ------- last -------->
+func ipTooBig(ip uint128, last uint128) bool {
+	return last.less(ip)
 }
@@ -1,13 +1,16 @@
 package cidrtree_test
 
 import (
+	"errors"
 	"fmt"
+	"math/big"
 	"net/netip"
 	"reflect"
 	"strings"
 	"testing"
 
 	"github.com/gaissmai/cidrtree"
+	"github.com/gaissmai/extnetip"
 )
 
 type routeStr struct {
@@ -524,6 +527,27 @@ func TestLookupCIDR(t *testing.T) {
 	}
 }
 
+func TestLookupAllocs(t *testing.T) {
+	rtbl := new(cidrtree.Table[any])
+	for _, cidr := range shuffleFullTable(10_000) {
+		rtbl.Insert(cidr, nil)
+	}
+
+	canonical := mustPfx("10.0.0.0/8")
+	notCanonical := mustPfx("10.1.2.3/8")
+	ip := mustAddr("10.1.2.3")
+
+	if n := testing.AllocsPerRun(1_000, func() { _, _, _ = rtbl.LookupPrefix(canonical) }); n != 0 {
+		t.Errorf("LookupPrefix(%v), got %v allocs, want 0", canonical, n)
+	}
+	if n := testing.AllocsPerRun(1_000, func() { _, _, _ = rtbl.LookupPrefix(notCanonical) }); n != 0 {
+		t.Errorf("LookupPrefix(%v), got %v allocs, want 0", notCanonical, n)
+	}
+	if n := testing.AllocsPerRun(1_000, func() { _, _, _ = rtbl.Lookup(ip) }); n != 0 {
+		t.Errorf("Lookup(%v), got %v allocs, want 0", ip, n)
+	}
+}
+
 func TestUnion(t *testing.T) {
 	t.Parallel()
 	rtbl := new(cidrtree.Table[any])
@@ -616,6 +640,54 @@ func TestWalk(t *testing.T) {
 	}
 }
 
+func TestModifyAll(t *testing.T) {
+	t.Parallel()
+
+	orig := new(cidrtree.Table[any])
+	for _, route := range routes {
+		orig.Insert(route.cidr, route.nextHop)
+	}
+	want := orig.String()
+
+	// shares all nodes with orig
+	rtbl := orig.InsertImmutable(routes[0].cidr, routes[0].nextHop)
+
+	// re-label IPv4, delete the host routes
+	deleted := rtbl.ModifyAll(func(pfx netip.Prefix, value any) (any, bool) {
+		if pfx.IsSingleIP() {
+			return nil, true
+		}
+		if pfx.Addr().Is4() {
+			return "v4", false
+		}
+		return value, false
+	})
+
+	if deleted != 2 {
+		t.Errorf("ModifyAll, got %v deleted, want %v", deleted, 2)
+	}
+	if n := rtbl.Size(); n != len(routes)-2 {
+		t.Errorf("Size, got %v, want %v", n, len(routes)-2)
+	}
+
+	rtbl.Walk(func(pfx netip.Prefix, value any) bool {
+		if pfx.IsSingleIP() {
+			t.Errorf("ModifyAll, host route %v not deleted", pfx)
+		}
+		if pfx.Addr().Is4() && value != "v4" {
+			t.Errorf("ModifyAll(%v), got value %v, want %v", pfx, value, "v4")
+		}
+		if pfx.Addr().Is6() && value != mustAddr("2001:db8::1") {
+			t.Errorf("ModifyAll(%v), got value %v, want unchanged", pfx, value)
+		}
+		return true
+	})
+
+	if got := orig.String(); got != want {
+		t.Errorf("ModifyAll leaked into the shared table, got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
 func TestWalkStartStop(t *testing.T) {
 	t.Parallel()
 	rtbl := new(cidrtree.Table[any])
@@ -650,3 +722,1526 @@ func TestWalkStartStop(t *testing.T) {
 		t.Fatalf("Walk, expected:\n%sgot:\n%s", expect, w.String())
 	}
 }
+
+func TestWalkByEnd(t *testing.T) {
+	t.Parallel()
+	rtbl := new(cidrtree.Table[any])
+	for _, route := range routes {
+		rtbl.Insert(route.cidr, route.nextHop)
+	}
+
+	var got []string
+	rtbl.WalkByEnd(func(pfx netip.Prefix, _ any) bool {
+		got = append(got, pfx.String())
+		return pfx != mustPfx("ff00::/8")
+	})
+
+	want := []string{
+		"10.0.0.0/24",
+		"10.0.1.0/24",
+		"10.0.0.0/8",
+		"127.0.0.1/32",
+		"127.0.0.0/8",
+		"169.254.0.0/16",
+		"172.16.0.0/12",
+		"192.168.1.0/24",
+		"192.168.0.0/16",
+		"::1/128",
+		"2001:db8::/32",
+		"2000::/3",
+		"fc00::/7",
+		"fe80::/10",
+		"ff00::/8",
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("WalkByEnd, got:\n%v\nwant:\n%v", got, want)
+	}
+}
+
+func TestWalkByEndFullTable(t *testing.T) {
+	t.Parallel()
+	rtbl := new(cidrtree.Table[any])
+	for _, cidr := range shuffleFullTable(10_000) {
+		rtbl.Insert(cidr, nil)
+	}
+
+	lastAddr := func(pfx netip.Prefix) netip.Addr {
+		_, last := extnetip.Range(pfx)
+		return last
+	}
+
+	var prev netip.Prefix
+	var n int
+	rtbl.WalkByEnd(func(pfx netip.Prefix, _ any) bool {
+		if n > 0 {
+			prevLast, last := lastAddr(prev), lastAddr(pfx)
+			if c := prevLast.Compare(last); c > 0 || c == 0 && prev.Bits() < pfx.Bits() {
+				t.Fatalf("WalkByEnd, %v before %v", prev, pfx)
+			}
+		}
+		prev = pfx
+		n++
+		return true
+	})
+
+	if n != 10_000 {
+		t.Errorf("WalkByEnd, got %d prefixes, want %d", n, 10_000)
+	}
+}
+
+func TestFreeze(t *testing.T) {
+	t.Parallel()
+	rtbl := new(cidrtree.Table[any])
+	for _, route := range routes {
+		rtbl.Insert(route.cidr, route.nextHop)
+	}
+	rtbl.Freeze()
+
+	if !rtbl.Frozen() {
+		t.Fatalf("Frozen(), got false, want true")
+	}
+
+	pfx := mustPfx("10.0.0.0/8")
+	mutations := map[string]func(){
+		"Insert":         func() { rtbl.Insert(pfx, nil) },
+		"Delete":         func() { rtbl.Delete(pfx) },
+		"DeleteSubtree":  func() { rtbl.DeleteSubtree(pfx) },
+		"Union":          func() { rtbl.Union(cidrtree.Table[any]{}) },
+		"UnionOther":     func() { new(cidrtree.Table[any]).Union(*rtbl) },
+		"Exclude":        func() { rtbl.Exclude(pfx) },
+		"Tag":            func() { rtbl.Tag(pfx, "foo") },
+		"InsertFrozen":   func() { rtbl.InsertImmutable(pfx, nil).Insert(pfx, nil) },
+		"UnmarshalJSON":  func() { _ = rtbl.UnmarshalJSON([]byte("[]")) },
+		"ReplaceSubtree": func() { rtbl.ReplaceSubtree(pfx, cidrtree.Table[any]{}) },
+	}
+
+	for name, fn := range mutations {
+		func() {
+			defer func() {
+				if r := recover(); r == nil {
+					t.Errorf("%s on frozen table, expected panic", name)
+				}
+			}()
+			fn()
+		}()
+	}
+
+	// immutable methods are allowed, the clone is mutable
+	if _, ok := rtbl.DeleteImmutable(pfx); !ok {
+		t.Errorf("DeleteImmutable(%v) on frozen table, got false, want true", pfx)
+	}
+
+	clone := rtbl.Clone()
+	clone.Insert(mustPfx("10.0.0.0/9"), nil)
+	if clone.Frozen() || rtbl.String() != asTopoStr {
+		t.Errorf("Clone of frozen table isn't independent")
+	}
+}
+
+func TestView(t *testing.T) {
+	t.Parallel()
+	rtbl := new(cidrtree.Table[any])
+
+	var view cidrtree.View[any] = rtbl
+	if view.Size() != 0 {
+		t.Errorf("Size() of empty table, got %d, want 0", view.Size())
+	}
+
+	for _, route := range routes {
+		rtbl.Insert(route.cidr, route.nextHop)
+	}
+
+	if got, want := view.Size(), len(routes); got != want {
+		t.Errorf("Size(), got %d, want %d", got, want)
+	}
+
+	if lpm, _, _ := view.Lookup(mustAddr("10.0.1.1")); lpm != mustPfx("10.0.1.0/24") {
+		t.Errorf("Lookup(%v), got %v, want %v", "10.0.1.1", lpm, "10.0.1.0/24")
+	}
+
+	if view.String() != asTopoStr {
+		t.Errorf("String(), got:\n%v\nwant:\n%v", view.String(), asTopoStr)
+	}
+
+	rtbl.Delete(mustPfx("10.0.1.0/24"))
+	if got, want := view.Size(), len(routes)-1; got != want {
+		t.Errorf("Size() after Delete, got %d, want %d", got, want)
+	}
+}
+
+func TestUnionAll(t *testing.T) {
+	t.Parallel()
+
+	if got := cidrtree.UnionAll[int](); got.Size() != 0 {
+		t.Errorf("UnionAll(), got size %d, want 0", got.Size())
+	}
+
+	var tables []cidrtree.Table[int]
+	var want cidrtree.Table[int]
+
+	for i := 0; i < 20; i++ {
+		var tbl cidrtree.Table[int]
+		// different sizes and many duplicates
+		for _, cidr := range shuffleFullTable(1 + i*i*10) {
+			tbl.Insert(cidr, i)
+		}
+		tables = append(tables, tbl)
+		want.Union(*tbl.Clone())
+	}
+
+	before := make([]string, len(tables))
+	for i := range tables {
+		before[i] = tables[i].String()
+	}
+
+	got := cidrtree.UnionAll(tables...)
+	if got.String() != want.String() {
+		t.Errorf("UnionAll differs from pairwise Union in order")
+	}
+
+	for i := range tables {
+		if tables[i].String() != before[i] {
+			t.Fatalf("UnionAll changed table %d", i)
+		}
+	}
+
+	if single := cidrtree.UnionAll(tables[3]); single.String() != before[3] {
+		t.Errorf("UnionAll of single table, got:\n%v\nwant:\n%v", single, before[3])
+	}
+}
+
+func TestWalkTopology(t *testing.T) {
+	t.Parallel()
+
+	rtbl := new(cidrtree.Table[any])
+	for _, route := range routes {
+		rtbl.Insert(route.cidr, route.nextHop)
+	}
+
+	// rebuild the hierarchy of Fprint without the tree branches
+	w := new(strings.Builder)
+	rtbl.WalkTopology(func(pfx netip.Prefix, val any, depth int) bool {
+		fmt.Fprintf(w, "%s%v (%v)\n", strings.Repeat("  ", depth), pfx, val)
+		return true
+	})
+
+	want := `10.0.0.0/8 (203.0.113.0)
+  10.0.0.0/24 (203.0.113.0)
+  10.0.1.0/24 (203.0.113.0)
+127.0.0.0/8 (203.0.113.0)
+  127.0.0.1/32 (203.0.113.0)
+169.254.0.0/16 (203.0.113.0)
+172.16.0.0/12 (203.0.113.0)
+192.168.0.0/16 (203.0.113.0)
+  192.168.1.0/24 (203.0.113.0)
+::/0 (2001:db8::1)
+  ::1/128 (2001:db8::1)
+  2000::/3 (2001:db8::1)
+    2001:db8::/32 (2001:db8::1)
+  fc00::/7 (2001:db8::1)
+  fe80::/10 (2001:db8::1)
+  ff00::/8 (2001:db8::1)
+`
+	if w.String() != want {
+		t.Errorf("WalkTopology, expected:\n%sgot:\n%s", want, w.String())
+	}
+
+	var n int
+	rtbl.WalkTopology(func(netip.Prefix, any, int) bool {
+		n++
+		return n < 3
+	})
+	if n != 3 {
+		t.Errorf("WalkTopology stop, got %d calls, want %d", n, 3)
+	}
+}
+
+func TestWalkErr(t *testing.T) {
+	t.Parallel()
+	rtbl := new(cidrtree.Table[any])
+	for _, route := range routes {
+		rtbl.Insert(route.cidr, route.nextHop)
+	}
+	w := new(strings.Builder)
+
+	if err := rtbl.WalkErr(func(pfx netip.Prefix, val any) error {
+		_, err := fmt.Fprintf(w, "%v (%v)\n", pfx, val)
+		return err
+	}); err != nil {
+		t.Fatalf("WalkErr, unexpected error: %v", err)
+	}
+	if w.String() != asStr {
+		t.Fatalf("WalkErr, expected:\n%sgot:\n%s", asStr, w.String())
+	}
+
+	errStop := errors.New("stop")
+	var n int
+	err := rtbl.WalkErr(func(pfx netip.Prefix, _ any) error {
+		if pfx == mustPfx("127.0.0.0/8") {
+			return errStop
+		}
+		n++
+		return nil
+	})
+
+	if !errors.Is(err, errStop) || n != 3 {
+		t.Errorf("WalkErr, got (%v, %d), want (%v, %d)", err, n, errStop, 3)
+	}
+}
+
+func TestSplit(t *testing.T) {
+	t.Parallel()
+
+	rtbl := new(cidrtree.Table[any])
+	for _, route := range routes {
+		rtbl.Insert(route.cidr, route.nextHop)
+	}
+	want := rtbl.String()
+
+	tcs := []struct {
+		pfx       netip.Prefix
+		wantMatch bool
+	}{
+		{mustPfx("10.0.0.0/8"), true},
+		{mustPfx("10.0.0.0/9"), false},
+		{mustPfx("0.0.0.0/0"), false},
+		{mustPfx("::/0"), true},
+		{mustPfx("2001:db8::/32"), true},
+		{mustPfx("2001:db8::/33"), false},
+		{mustPfx("ffff::/16"), false},
+	}
+
+	for _, tc := range tcs {
+		below, match, above := rtbl.Split(tc.pfx)
+
+		if got := match.Size() == 1; got != tc.wantMatch {
+			t.Errorf("Split(%v), match, got %v, want %v", tc.pfx, got, tc.wantMatch)
+		}
+
+		// sort order of Walk, IPv4 before IPv6
+		less := func(a, b netip.Prefix) bool {
+			if a.Addr().Is4() != b.Addr().Is4() {
+				return a.Addr().Is4()
+			}
+			if c := a.Addr().Compare(b.Addr()); c != 0 {
+				return c < 0
+			}
+			return a.Bits() < b.Bits()
+		}
+
+		below.Walk(func(pfx netip.Prefix, _ any) bool {
+			if !less(pfx, tc.pfx) {
+				t.Errorf("Split(%v), below contains %v", tc.pfx, pfx)
+			}
+			return true
+		})
+
+		above.Walk(func(pfx netip.Prefix, _ any) bool {
+			if !less(tc.pfx, pfx) {
+				t.Errorf("Split(%v), above contains %v", tc.pfx, pfx)
+			}
+			return true
+		})
+
+		if got := below.UnionImmutable(*match).UnionImmutable(*above).String(); got != want {
+			t.Errorf("Split(%v), union of the parts differ\n%s", tc.pfx, got)
+		}
+
+		// immutable
+		if got := rtbl.String(); got != want {
+			t.Fatalf("Split(%v) changed the table", tc.pfx)
+		}
+	}
+}
+
+func TestChildren(t *testing.T) {
+	t.Parallel()
+
+	rtbl := new(cidrtree.Table[any])
+	for _, route := range routes {
+		rtbl.Insert(route.cidr, route.nextHop)
+	}
+
+	tcs := []struct {
+		pfx  netip.Prefix
+		want []string
+	}{
+		{mustPfx("10.0.0.0/8"), []string{"10.0.0.0/24", "10.0.1.0/24"}},
+		{mustPfx("10.0.0.0/24"), nil},
+		{mustPfx("0.0.0.0/0"), []string{"10.0.0.0/8", "127.0.0.0/8", "169.254.0.0/16", "172.16.0.0/12", "192.168.0.0/16"}},
+		{mustPfx("::/0"), []string{"::1/128", "2000::/3", "fc00::/7", "fe80::/10", "ff00::/8"}},
+		{mustPfx("2000::/3"), []string{"2001:db8::/32"}},
+		{mustPfx("2001::/16"), []string{"2001:db8::/32"}},
+	}
+
+	for _, tc := range tcs {
+		var got []string
+		for _, e := range rtbl.Children(tc.pfx) {
+			got = append(got, e.Prefix.String())
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("Children(%v), got %v, want %v", tc.pfx, got, tc.want)
+		}
+	}
+}
+
+func TestNestingDepth(t *testing.T) {
+	t.Parallel()
+
+	rtbl := new(cidrtree.Table[any])
+	for _, route := range routes {
+		rtbl.Insert(route.cidr, route.nextHop)
+	}
+
+	tcs := []struct {
+		pfx  netip.Prefix
+		want int
+	}{
+		{mustPfx("10.0.0.0/8"), 0},
+		{mustPfx("10.0.0.0/24"), 1},
+		{mustPfx("10.0.0.1/32"), 2},
+		{mustPfx("11.0.0.0/8"), 0},
+		{mustPfx("::/0"), 0},
+		{mustPfx("2001:db8::/32"), 2},
+		{mustPfx("2001:db8:1::/48"), 3},
+	}
+
+	for _, tc := range tcs {
+		if got := rtbl.NestingDepth(tc.pfx); got != tc.want {
+			t.Errorf("NestingDepth(%v), got %d, want %d", tc.pfx, got, tc.want)
+		}
+	}
+}
+
+func TestCoverage(t *testing.T) {
+	t.Parallel()
+
+	rtbl := new(cidrtree.Table[any])
+	for _, route := range routes {
+		rtbl.Insert(route.cidr, route.nextHop)
+	}
+
+	tcs := []struct {
+		scope        netip.Prefix
+		wantCovered  string
+		wantFraction float64
+	}{
+		// 10/8 + 127/8 + 169.254/16 + 172.16/12 + 192.168/16
+		{mustPfx("0.0.0.0/0"), "34734080", 34734080.0 / (1 << 32)},
+		{mustPfx("10.0.0.0/8"), "16777216", 1},
+		{mustPfx("10.1.2.0/24"), "256", 1},
+		{mustPfx("192.0.0.0/8"), "65536", 1.0 / 256},
+		{mustPfx("11.0.0.0/8"), "0", 0},
+		{mustPfx("::/0"), "340282366920938463463374607431768211456", 1},
+	}
+
+	for _, tc := range tcs {
+		covered, fraction := rtbl.Coverage(tc.scope)
+		if covered.String() != tc.wantCovered || fraction != tc.wantFraction {
+			t.Errorf("Coverage(%v), got (%v, %v), want (%v, %v)", tc.scope, covered, fraction, tc.wantCovered, tc.wantFraction)
+		}
+	}
+
+	rtbl.Delete(mustPfx("::/0"))
+	covered, _ := rtbl.Coverage(mustPfx("::/0"))
+	// ::1/128 + 2000::/3 + fc00::/7 + fe80::/10 + ff00::/8
+	want := new(big.Int).Lsh(big.NewInt(1), 125)
+	want.Add(want, big.NewInt(1))
+	want.Add(want, new(big.Int).Lsh(big.NewInt(1), 121))
+	want.Add(want, new(big.Int).Lsh(big.NewInt(1), 118))
+	want.Add(want, new(big.Int).Lsh(big.NewInt(1), 120))
+	if covered.Cmp(want) != 0 {
+		t.Errorf("Coverage(::/0), got %v, want %v", covered, want)
+	}
+}
+
+func TestDeleteSubtree(t *testing.T) {
+	t.Parallel()
+
+	rtbl := new(cidrtree.Table[any])
+	for _, route := range routes {
+		rtbl.Insert(route.cidr, route.nextHop)
+	}
+
+	if ok := rtbl.DeleteSubtree(mustPfx("11.0.0.0/8")); ok {
+		t.Errorf("DeleteSubtree(%v), got %v, want false", "11.0.0.0/8", ok)
+	}
+
+	// not in table, but covers 10.0.0.0/24 and 10.0.1.0/24
+	if ok := rtbl.DeleteSubtree(mustPfx("10.0.0.0/23")); !ok {
+		t.Errorf("DeleteSubtree(%v), got %v, want true", "10.0.0.0/23", ok)
+	}
+
+	// all IPv6 routes
+	if ok := rtbl.DeleteSubtree(mustPfx("::/0")); !ok {
+		t.Errorf("DeleteSubtree(%v), got %v, want true", "::/0", ok)
+	}
+
+	expect := `▼
+├─ 10.0.0.0/8 (203.0.113.0)
+├─ 127.0.0.0/8 (203.0.113.0)
+│  └─ 127.0.0.1/32 (203.0.113.0)
+├─ 169.254.0.0/16 (203.0.113.0)
+├─ 172.16.0.0/12 (203.0.113.0)
+└─ 192.168.0.0/16 (203.0.113.0)
+   └─ 192.168.1.0/24 (203.0.113.0)
+`
+	if rtbl.String() != expect {
+		t.Errorf("DeleteSubtree\nwant:\n%sgot:\n%s", expect, rtbl.String())
+	}
+}
+
+func TestDeleteSubtreeImmutable(t *testing.T) {
+	t.Parallel()
+
+	rtbl := new(cidrtree.Table[any])
+	for _, route := range routes {
+		rtbl.Insert(route.cidr, route.nextHop)
+	}
+	clone := rtbl.Clone()
+
+	rtbl2, ok := rtbl.DeleteSubtreeImmutable(mustPfx("2000::/3"))
+	if !ok {
+		t.Errorf("DeleteSubtreeImmutable(%v), got %v, want true", "2000::/3", ok)
+	}
+
+	if !reflect.DeepEqual(rtbl, clone) {
+		t.Fatal("DeleteSubtreeImmutable changed receiver")
+	}
+
+	for _, pfx := range []netip.Prefix{mustPfx("2000::/3"), mustPfx("2001:db8::/32")} {
+		if lpm, _, _ := rtbl2.LookupPrefix(pfx); lpm != mustPfx("::/0") {
+			t.Errorf("LookupPrefix(%v), got %v, want %v", pfx, lpm, mustPfx("::/0"))
+		}
+	}
+
+	// compare with single deletes in a big table
+	tc := shuffleFullTable(10_000)
+	rtbl3 := new(cidrtree.Table[any])
+	rtbl4 := new(cidrtree.Table[any])
+	for _, cidr := range tc {
+		rtbl3.Insert(cidr, nil)
+	}
+
+	scope := mustPfx("128.0.0.0/2")
+	for _, cidr := range tc {
+		if !scope.Overlaps(cidr) || cidr.Bits() < scope.Bits() {
+			rtbl4.Insert(cidr, nil)
+		}
+	}
+
+	rtbl3, _ = rtbl3.DeleteSubtreeImmutable(scope)
+	if rtbl3.String() != rtbl4.String() {
+		t.Errorf("DeleteSubtreeImmutable(%v), tables differ", scope)
+	}
+}
+
+func TestReplaceSubtree(t *testing.T) {
+	t.Parallel()
+
+	rtbl := new(cidrtree.Table[any])
+	for _, route := range routes {
+		rtbl.Insert(route.cidr, route.nextHop)
+	}
+
+	sub := new(cidrtree.Table[any])
+	sub.Insert(mustPfx("10.0.0.0/8"), "A")
+	sub.Insert(mustPfx("10.2.0.0/16"), "B")
+	sub.Insert(mustPfx("10.3.0.0/16"), "C")
+	// outside of scope, must be ignored
+	sub.Insert(mustPfx("11.0.0.0/8"), "X")
+	sub.Insert(mustPfx("::/0"), "X")
+	subClone := sub.Clone()
+
+	// immutable first
+	rtbl2 := rtbl.ReplaceSubtreeImmutable(mustPfx("10.0.0.0/8"), *sub)
+
+	// now mutable with the same result
+	rtbl.ReplaceSubtree(mustPfx("10.0.0.0/8"), *sub)
+
+	if !reflect.DeepEqual(sub, subClone) {
+		t.Fatal("ReplaceSubtree changed sub table")
+	}
+
+	expect := `▼
+├─ 10.0.0.0/8 (A)
+│  ├─ 10.2.0.0/16 (B)
+│  └─ 10.3.0.0/16 (C)
+├─ 127.0.0.0/8 (203.0.113.0)
+│  └─ 127.0.0.1/32 (203.0.113.0)
+├─ 169.254.0.0/16 (203.0.113.0)
+├─ 172.16.0.0/12 (203.0.113.0)
+└─ 192.168.0.0/16 (203.0.113.0)
+   └─ 192.168.1.0/24 (203.0.113.0)
+▼
+└─ ::/0 (2001:db8::1)
+   ├─ ::1/128 (2001:db8::1)
+   ├─ 2000::/3 (2001:db8::1)
+   │  └─ 2001:db8::/32 (2001:db8::1)
+   ├─ fc00::/7 (2001:db8::1)
+   ├─ fe80::/10 (2001:db8::1)
+   └─ ff00::/8 (2001:db8::1)
+`
+	if rtbl.String() != expect {
+		t.Errorf("ReplaceSubtree\nwant:\n%sgot:\n%s", expect, rtbl.String())
+	}
+
+	if rtbl2.String() != expect {
+		t.Errorf("ReplaceSubtreeImmutable\nwant:\n%sgot:\n%s", expect, rtbl2.String())
+	}
+
+	// replace with empty sub table is DeleteSubtree
+	rtbl.ReplaceSubtree(mustPfx("::/0"), cidrtree.Table[any]{})
+	if _, _, ok := rtbl.Lookup(mustAddr("::1")); ok {
+		t.Errorf("ReplaceSubtree with empty table, Lookup(::1) got %v, want false", ok)
+	}
+}
+
+func TestInsertManyImmutableKeepsTags(t *testing.T) {
+	t.Parallel()
+
+	// the priorities are random, many runs for both orders of the treaps
+	for i := 0; i < 200; i++ {
+		rtbl := new(cidrtree.Table[any])
+		for _, route := range routes {
+			rtbl.Insert(route.cidr, route.nextHop)
+		}
+		rtbl.Tag(mustPfx("10.0.0.0/8"), "static")
+		rtbl.Tag(mustPfx("::1/128"), "static")
+
+		rtbl2 := rtbl.InsertManyImmutable([]cidrtree.Entry[any]{
+			{Prefix: mustPfx("10.0.0.0/8"), Value: "new value"},
+			{Prefix: mustPfx("::1/128"), Value: "new value"},
+			{Prefix: mustPfx("172.16.0.0/12"), Value: "new entry"},
+		})
+
+		for _, s := range []string{"10.0.0.0/8", "::1/128"} {
+			if got := rtbl2.Tags(mustPfx(s)); !reflect.DeepEqual(got, []string{"static"}) {
+				t.Fatalf("Tags(%v) after InsertManyImmutable, got %v, want %v", s, got, []string{"static"})
+			}
+			if _, value, _ := rtbl2.LookupPrefix(mustPfx(s)); value != "new value" {
+				t.Fatalf("LookupPrefix(%v) after InsertManyImmutable, got %v, want %v", s, value, "new value")
+			}
+		}
+	}
+}
+
+func TestInsertManyImmutable(t *testing.T) {
+	t.Parallel()
+
+	rtbl := new(cidrtree.Table[any])
+	for _, route := range routes[:8] {
+		rtbl.Insert(route.cidr, route.nextHop)
+	}
+	clone := rtbl.Clone()
+
+	var entries []cidrtree.Entry[any]
+	for _, route := range routes {
+		entries = append(entries, cidrtree.Entry[any]{Prefix: route.cidr, Value: "dupe"})
+	}
+	for _, route := range routes {
+		entries = append(entries, cidrtree.Entry[any]{Prefix: route.cidr, Value: route.nextHop})
+	}
+
+	rtbl2 := rtbl.InsertManyImmutable(entries)
+
+	if !reflect.DeepEqual(rtbl, clone) {
+		t.Fatal("InsertManyImmutable changed receiver")
+	}
+
+	if rtbl2.String() != asTopoStr {
+		t.Errorf("InsertManyImmutable\nwant:\n%sgot:\n%s", asTopoStr, rtbl2.String())
+	}
+
+	// compare with single inserts in a big table
+	tc := shuffleFullTable(10_000)
+	entries = entries[:0]
+	rtbl3 := new(cidrtree.Table[any])
+	for i, cidr := range tc {
+		rtbl3.Insert(cidr, i)
+		entries = append(entries, cidrtree.Entry[any]{Prefix: cidr, Value: i})
+	}
+
+	rtbl4 := new(cidrtree.Table[any]).InsertManyImmutable(entries)
+	if rtbl3.String() != rtbl4.String() {
+		t.Error("InsertManyImmutable, tables differ")
+	}
+
+	for _, cidr := range tc {
+		if _, _, ok := rtbl4.LookupPrefix(cidr); !ok {
+			t.Fatalf("LookupPrefix(%v), want true, got %v", cidr, ok)
+		}
+	}
+}
+
+func TestSwap(t *testing.T) {
+	t.Parallel()
+
+	rtbl := new(cidrtree.Table[any])
+	for _, route := range routes {
+		if _, existed := rtbl.Swap(route.cidr, route.nextHop); existed {
+			t.Errorf("Swap(%v), got existed %v, want false", route.cidr, existed)
+		}
+	}
+
+	if rtbl.String() != asTopoStr {
+		t.Errorf("Fprint()\nwant:\n%sgot:\n%s", asTopoStr, rtbl.String())
+	}
+
+	for _, route := range routes {
+		old, existed := rtbl.Swap(route.cidr, "new value")
+		if !existed || old != route.nextHop {
+			t.Errorf("Swap(%v) = (%v, %v), want (%v, %v)", route.cidr, old, existed, route.nextHop, true)
+		}
+	}
+
+	for _, route := range routes {
+		if _, value, _ := rtbl.LookupPrefix(route.cidr); value != "new value" {
+			t.Errorf("LookupPrefix(%v), got value %v, want %v", route.cidr, value, "new value")
+		}
+	}
+}
+
+func TestCompareAndInsert(t *testing.T) {
+	t.Parallel()
+
+	lower := func(value, old int) bool { return value < old }
+
+	rtbl := new(cidrtree.Table[int])
+	pfx := mustPfx("10.0.0.0/8")
+
+	if won := rtbl.CompareAndInsert(pfx, 20, lower); !won {
+		t.Errorf("CompareAndInsert(%v, %v), new prefix, got %v, want true", pfx, 20, won)
+	}
+	if won := rtbl.CompareAndInsert(pfx, 110, lower); won {
+		t.Errorf("CompareAndInsert(%v, %v), got %v, want false", pfx, 110, won)
+	}
+	if won := rtbl.CompareAndInsert(pfx, 1, lower); !won {
+		t.Errorf("CompareAndInsert(%v, %v), got %v, want true", pfx, 1, won)
+	}
+
+	if _, value, _ := rtbl.LookupPrefix(pfx); value != 1 {
+		t.Errorf("LookupPrefix(%v), got value %v, want %v", pfx, value, 1)
+	}
+
+	immu, won := rtbl.CompareAndInsertImmutable(pfx, 5, lower)
+	if _, value, _ := immu.LookupPrefix(pfx); won || value != 1 {
+		t.Errorf("CompareAndInsertImmutable(%v, %v), got %v %v, want false 1", pfx, 5, won, value)
+	}
+
+	immu, won = rtbl.CompareAndInsertImmutable(pfx, 0, lower)
+	if _, value, _ := immu.LookupPrefix(pfx); !won || value != 0 {
+		t.Errorf("CompareAndInsertImmutable(%v, %v), got %v %v, want true 0", pfx, 0, won, value)
+	}
+
+	// the receiver is unchanged
+	if _, value, _ := rtbl.LookupPrefix(pfx); value != 1 {
+		t.Errorf("LookupPrefix(%v) of receiver, got value %v, want %v", pfx, value, 1)
+	}
+
+	// the nodes shared with an immutable version are not changed
+	shared := rtbl.InsertImmutable(mustPfx("10.1.0.0/16"), 7)
+	if won := shared.CompareAndInsert(pfx, 0, lower); !won {
+		t.Errorf("CompareAndInsert(%v, %v), got %v, want true", pfx, 0, won)
+	}
+	if _, value, _ := rtbl.LookupPrefix(pfx); value != 1 {
+		t.Errorf("CompareAndInsert leaked into the shared table, got value %v, want %v", value, 1)
+	}
+}
+
+func TestCompareAndSwap(t *testing.T) {
+	t.Parallel()
+
+	equal := func(a, b string) bool { return a == b }
+
+	rtbl := new(cidrtree.Table[string])
+	pfx := mustPfx("10.0.0.0/8")
+
+	if swapped := rtbl.CompareAndSwap(pfx, "", "a", equal); swapped {
+		t.Errorf("CompareAndSwap(%v), missing prefix, got %v, want false", pfx, swapped)
+	}
+
+	rtbl.Insert(pfx, "a")
+	if swapped := rtbl.CompareAndSwap(pfx, "x", "b", equal); swapped {
+		t.Errorf("CompareAndSwap(%v, x, b), got %v, want false", pfx, swapped)
+	}
+	if swapped := rtbl.CompareAndSwap(pfx, "a", "b", equal); !swapped {
+		t.Errorf("CompareAndSwap(%v, a, b), got %v, want true", pfx, swapped)
+	}
+	if _, value, _ := rtbl.LookupPrefix(pfx); value != "b" {
+		t.Errorf("LookupPrefix(%v), got value %v, want %v", pfx, value, "b")
+	}
+
+	immu, swapped := rtbl.CompareAndSwapImmutable(pfx, "a", "c", equal)
+	if _, value, _ := immu.LookupPrefix(pfx); swapped || value != "b" {
+		t.Errorf("CompareAndSwapImmutable(%v, a, c), got %v %v, want false b", pfx, swapped, value)
+	}
+
+	immu, swapped = rtbl.CompareAndSwapImmutable(pfx, "b", "c", equal)
+	if _, value, _ := immu.LookupPrefix(pfx); !swapped || value != "c" {
+		t.Errorf("CompareAndSwapImmutable(%v, b, c), got %v %v, want true c", pfx, swapped, value)
+	}
+
+	// the receiver is unchanged
+	if _, value, _ := rtbl.LookupPrefix(pfx); value != "b" {
+		t.Errorf("LookupPrefix(%v) of receiver, got value %v, want %v", pfx, value, "b")
+	}
+
+	// the nodes shared with an immutable version are not changed
+	shared := rtbl.InsertImmutable(mustPfx("10.1.0.0/16"), "x")
+	if swapped := shared.CompareAndSwap(pfx, "b", "d", equal); !swapped {
+		t.Errorf("CompareAndSwap(%v, b, d), got %v, want true", pfx, swapped)
+	}
+	if _, value, _ := rtbl.LookupPrefix(pfx); value != "b" {
+		t.Errorf("CompareAndSwap leaked into the shared table, got value %v, want %v", value, "b")
+	}
+}
+
+func TestReplaceSubtreeKeepsSub(t *testing.T) {
+	t.Parallel()
+
+	tc := shuffleFullTable(10_000)
+
+	rtbl := new(cidrtree.Table[any])
+	sub := new(cidrtree.Table[any])
+	for i, cidr := range tc {
+		if i%2 == 0 {
+			rtbl.Insert(cidr, nil)
+		} else {
+			sub.Insert(cidr, nil)
+		}
+	}
+	want := sub.String()
+
+	for _, scope := range []netip.Prefix{mustPfx("0.0.0.0/1"), mustPfx("128.0.0.0/2"), mustPfx("2000::/3")} {
+		rtbl.ReplaceSubtree(scope, *sub)
+		for _, cidr := range shuffleFullTable(1_000) {
+			rtbl.Insert(cidr, nil)
+		}
+	}
+
+	if sub.String() != want {
+		t.Fatal("ReplaceSubtree changed sub table")
+	}
+}
+
+func TestUnionConflicts(t *testing.T) {
+	t.Parallel()
+
+	rtbl := new(cidrtree.Table[any])
+	rtbl2 := new(cidrtree.Table[any])
+	for i, route := range routes {
+		rtbl.Insert(route.cidr, 1)
+		if i%2 == 0 {
+			rtbl2.Insert(route.cidr, 2)
+		}
+	}
+
+	// no conflicts, disjunct
+	rtbl2.Insert(mustPfx("1.2.3.0/24"), 2)
+
+	conflicts := rtbl.UnionConflicts(*rtbl2)
+	if len(conflicts) != len(routes)/2 {
+		t.Fatalf("UnionConflicts, got %d conflicts, want %d", len(conflicts), len(routes)/2)
+	}
+
+	var last netip.Prefix
+	for i, c := range conflicts {
+		if c.Old != 1 || c.New != 2 {
+			t.Errorf("UnionConflicts, got %v, want old: 1, new: 2", c)
+		}
+
+		if _, value, _ := rtbl.LookupPrefix(c.Prefix); value != 2 {
+			t.Errorf("UnionConflicts, value of %v not overwritten", c.Prefix)
+		}
+
+		// v4 before v6, ascending
+		if i > 0 && last.Addr().Is4() == c.Prefix.Addr().Is4() && last.Addr().Compare(c.Prefix.Addr()) > 0 {
+			t.Errorf("UnionConflicts, not sorted: %v > %v", last, c.Prefix)
+		}
+		last = c.Prefix
+	}
+
+	// big table with the swapped treaps in rec-descent
+	rtbl3 := new(cidrtree.Table[any])
+	rtbl4 := new(cidrtree.Table[any])
+	for _, cidr := range shuffleFullTable(10_000) {
+		rtbl3.Insert(cidr, 3)
+		rtbl4.Insert(cidr, 4)
+	}
+
+	conflicts = rtbl3.UnionConflicts(*rtbl4)
+	if len(conflicts) != 10_000 {
+		t.Fatalf("UnionConflicts, got %d conflicts, want %d", len(conflicts), 10_000)
+	}
+	for _, c := range conflicts {
+		if c.Old != 3 || c.New != 4 {
+			t.Fatalf("UnionConflicts, got %v, want old: 3, new: 4", c)
+		}
+	}
+}
+
+func TestContains(t *testing.T) {
+	t.Parallel()
+
+	rtbl := new(cidrtree.Table[any])
+	for _, route := range routes {
+		rtbl.Insert(route.cidr, route.nextHop)
+	}
+
+	tcs := []struct {
+		ip   netip.Addr
+		want bool
+	}{
+		{mustAddr("10.0.1.17"), true},
+		{mustAddr("10.2.3.4"), true},
+		{mustAddr("12.0.0.0"), false},
+		{mustAddr("255.255.255.255"), false},
+		{mustAddr("::2"), true},
+		{mustAddr("2001:db8:affe:cafe::dead:beef"), true},
+	}
+
+	for _, tt := range tcs {
+		if got := rtbl.Contains(tt.ip); got != tt.want {
+			t.Errorf("Contains(%v) = %v, want %v", tt.ip, got, tt.want)
+		}
+	}
+
+	// ##########################################
+
+	tc := shuffleFullTable(10_000)
+	rtbl2 := new(cidrtree.Table[any])
+	for _, cidr := range tc[:5_000] {
+		rtbl2.Insert(cidr, nil)
+	}
+
+	for _, cidr := range tc {
+		for _, ip := range []netip.Addr{cidr.Addr(), cidr.Addr().Prev(), cidr.Addr().Next()} {
+			_, _, want := rtbl2.Lookup(ip)
+			if got := rtbl2.Contains(ip); got != want {
+				t.Fatalf("Contains(%v) = %v, want %v", ip, got, want)
+			}
+		}
+	}
+}
+
+func TestHasSubnets(t *testing.T) {
+	t.Parallel()
+
+	rtbl := new(cidrtree.Table[any])
+	for _, route := range routes {
+		rtbl.Insert(route.cidr, route.nextHop)
+	}
+
+	tcs := []struct {
+		pfx  netip.Prefix
+		want bool
+	}{
+		{mustPfx("10.0.0.0/8"), true},
+		{mustPfx("10.0.0.0/7"), true},
+		{mustPfx("10.0.1.0/24"), false},
+		{mustPfx("10.0.1.0/23"), true},
+		{mustPfx("10.0.2.0/23"), false},
+		{mustPfx("127.0.0.0/8"), true},
+		{mustPfx("169.254.0.0/16"), false},
+		{mustPfx("0.0.0.0/0"), true},
+		{mustPfx("::/0"), true},
+		{mustPfx("2000::/3"), true},
+		{mustPfx("2001:db8::/32"), false},
+		{mustPfx("fe80::/16"), false},
+	}
+
+	for _, tt := range tcs {
+		if got := rtbl.HasSubnets(tt.pfx); got != tt.want {
+			t.Errorf("HasSubnets(%v) = %v, want %v", tt.pfx, got, tt.want)
+		}
+	}
+}
+
+func TestFprint46(t *testing.T) {
+	t.Parallel()
+	rtbl := new(cidrtree.Table[any])
+	for _, route := range routes {
+		rtbl.Insert(route.cidr, route.nextHop)
+	}
+
+	if got := rtbl.String4() + rtbl.String6(); got != asTopoStr {
+		t.Errorf("String4() + String6()\nwant:\n%sgot:\n%s", asTopoStr, got)
+	}
+
+	w := new(strings.Builder)
+	if err := rtbl.Fprint6(w); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(w.String(), "▼\n└─ ::/0 (2001:db8::1)\n") || strings.Contains(w.String(), "10.0.0.0/8") {
+		t.Errorf("Fprint6, not as expected, got:\n%s", w.String())
+	}
+
+	var zeroTable cidrtree.Table[any]
+	if zeroTable.String4() != "" || zeroTable.String6() != "" {
+		t.Errorf("String4(), String6() of zero value, want \"\"")
+	}
+}
+
+func TestSize46(t *testing.T) {
+	t.Parallel()
+
+	rtbl := new(cidrtree.Table[any])
+	for _, route := range routes {
+		rtbl.Insert(route.cidr, route.nextHop)
+	}
+
+	if got := rtbl.Size4(); got != 9 {
+		t.Errorf("Size4, got %v, want %v", got, 9)
+	}
+	if got := rtbl.Size6(); got != 7 {
+		t.Errorf("Size6, got %v, want %v", got, 7)
+	}
+
+	// the sizes are maintained by the immutable methods
+	immu, _ := rtbl.DeleteSubtreeImmutable(mustPfx("10.0.0.0/8"))
+	if got := immu.Size4(); got != 6 {
+		t.Errorf("Size4 after DeleteSubtreeImmutable, got %v, want %v", got, 6)
+	}
+
+	below, match, above := rtbl.Split(mustPfx("2000::/3"))
+	if got := below.Size6() + match.Size6() + above.Size6(); got != 7 {
+		t.Errorf("Size6 after Split, got %v, want %v", got, 7)
+	}
+}
+
+func TestIsEmpty(t *testing.T) {
+	t.Parallel()
+
+	rtbl := new(cidrtree.Table[any])
+	if !rtbl.IsEmpty() || !rtbl.IsEmpty4() || !rtbl.IsEmpty6() {
+		t.Errorf("IsEmpty, IsEmpty4, IsEmpty6 of zero value, want true")
+	}
+
+	rtbl.Insert(mustPfx("10.0.0.0/8"), nil)
+	if rtbl.IsEmpty() || rtbl.IsEmpty4() || !rtbl.IsEmpty6() {
+		t.Errorf("IsEmpty, IsEmpty4, IsEmpty6 with IPv4 entry, got %v %v %v, want false false true",
+			rtbl.IsEmpty(), rtbl.IsEmpty4(), rtbl.IsEmpty6())
+	}
+
+	rtbl.Insert(mustPfx("::/0"), nil)
+	rtbl.Delete(mustPfx("10.0.0.0/8"))
+	if rtbl.IsEmpty() || !rtbl.IsEmpty4() || rtbl.IsEmpty6() {
+		t.Errorf("IsEmpty, IsEmpty4, IsEmpty6 with IPv6 entry, got %v %v %v, want false true false",
+			rtbl.IsEmpty(), rtbl.IsEmpty4(), rtbl.IsEmpty6())
+	}
+
+	rtbl.Delete(mustPfx("::/0"))
+	if !rtbl.IsEmpty() {
+		t.Errorf("IsEmpty after Delete, got false, want true")
+	}
+}
+
+func TestFprintDepth(t *testing.T) {
+	t.Parallel()
+	rtbl := new(cidrtree.Table[any])
+	for _, route := range routes {
+		rtbl.Insert(route.cidr, route.nextHop)
+	}
+
+	want := `▼
+├─ 10.0.0.0/8 (203.0.113.0)
+│  └─ … 2 more
+├─ 127.0.0.0/8 (203.0.113.0)
+│  └─ … 1 more
+├─ 169.254.0.0/16 (203.0.113.0)
+├─ 172.16.0.0/12 (203.0.113.0)
+└─ 192.168.0.0/16 (203.0.113.0)
+   └─ … 1 more
+▼
+└─ ::/0 (2001:db8::1)
+   └─ … 6 more
+`
+
+	w := new(strings.Builder)
+	if err := rtbl.FprintDepth(w, 1); err != nil {
+		t.Fatal(err)
+	}
+	if w.String() != want {
+		t.Errorf("FprintDepth(1)\nwant:\n%sgot:\n%s", want, w.String())
+	}
+
+	w.Reset()
+	if err := rtbl.FprintDepth(w, 2); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(w.String(), "   ├─ 2000::/3 (2001:db8::1)\n   │  └─ … 1 more\n") {
+		t.Errorf("FprintDepth(2), got:\n%s", w.String())
+	}
+
+	// unlimited
+	for _, depth := range []int{0, 3} {
+		w.Reset()
+		if err := rtbl.FprintDepth(w, depth); err != nil {
+			t.Fatal(err)
+		}
+		if w.String() != asTopoStr {
+			t.Errorf("FprintDepth(%d)\nwant:\n%sgot:\n%s", depth, asTopoStr, w.String())
+		}
+	}
+}
+
+func TestFprintColor(t *testing.T) {
+	t.Parallel()
+	rtbl := new(cidrtree.Table[any])
+	for _, route := range routes {
+		rtbl.Insert(route.cidr, route.nextHop)
+	}
+
+	// not a terminal, no colors
+	w := new(strings.Builder)
+	if err := rtbl.FprintColor(w, nil); err != nil {
+		t.Fatal(err)
+	}
+	if w.String() != asTopoStr {
+		t.Errorf("FprintColor, no terminal\nwant:\n%sgot:\n%s", asTopoStr, w.String())
+	}
+}
+
+func TestPrefixesWithValue(t *testing.T) {
+	t.Parallel()
+
+	rtbl := new(cidrtree.Table[netip.Addr])
+	for _, route := range routes {
+		rtbl.Insert(route.cidr, route.nextHop)
+	}
+
+	nextHop := mustAddr("2001:db8::1")
+	want := []netip.Prefix{
+		mustPfx("::/0"),
+		mustPfx("::1/128"),
+		mustPfx("2000::/3"),
+		mustPfx("2001:db8::/32"),
+		mustPfx("fc00::/7"),
+		mustPfx("fe80::/10"),
+		mustPfx("ff00::/8"),
+	}
+
+	got := rtbl.PrefixesWithValue(func(v netip.Addr) bool { return v == nextHop })
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("PrefixesWithValue(%v)\nwant: %v\ngot:  %v", nextHop, want, got)
+	}
+
+	got = cidrtree.PrefixesWithValueEqual(*rtbl, nextHop)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("PrefixesWithValueEqual(%v)\nwant: %v\ngot:  %v", nextHop, want, got)
+	}
+
+	if got := cidrtree.PrefixesWithValueEqual(*rtbl, mustAddr("1.2.3.4")); got != nil {
+		t.Errorf("PrefixesWithValueEqual(%v), want nil, got: %v", "1.2.3.4", got)
+	}
+}
+
+func TestGroupByValue(t *testing.T) {
+	t.Parallel()
+
+	rtbl := new(cidrtree.Table[any])
+	for _, route := range routes {
+		rtbl.Insert(route.cidr, route.nextHop)
+	}
+
+	groups := cidrtree.GroupByValue(*rtbl, func(v any) string {
+		return v.(netip.Addr).String()
+	})
+
+	if len(groups) != 2 || len(groups["203.0.113.0"]) != 9 || len(groups["2001:db8::1"]) != 7 {
+		t.Fatalf("GroupByValue, got %v", groups)
+	}
+
+	if got := groups["203.0.113.0"][0]; got != mustPfx("10.0.0.0/8") {
+		t.Errorf("GroupByValue, first prefix, got %v, want %v", got, "10.0.0.0/8")
+	}
+
+	// group by the address family of the next hop
+	groups2 := cidrtree.GroupByValue(*rtbl, func(v any) bool {
+		return v.(netip.Addr).Is4()
+	})
+
+	if len(groups2[true]) != 9 || len(groups2[false]) != 7 {
+		t.Fatalf("GroupByValue, got %v", groups2)
+	}
+}
+
+func TestMinimize(t *testing.T) {
+	t.Parallel()
+
+	rtbl := new(cidrtree.Table[string])
+	rtbl.Insert(mustPfx("10.0.0.0/8"), "A")
+	rtbl.Insert(mustPfx("10.1.0.0/16"), "A")
+	rtbl.Insert(mustPfx("10.1.1.0/24"), "A")
+	rtbl.Insert(mustPfx("10.1.2.0/24"), "B")
+	rtbl.Insert(mustPfx("10.1.2.0/25"), "A")
+	rtbl.Insert(mustPfx("10.1.2.0/26"), "B")
+	rtbl.Insert(mustPfx("10.2.0.0/16"), "B")
+	rtbl.Insert(mustPfx("11.0.0.0/8"), "A")
+	rtbl.Insert(mustPfx("::/0"), "A")
+	rtbl.Insert(mustPfx("::1/128"), "A")
+
+	clone := rtbl.Clone()
+
+	equal := func(a, b string) bool { return a == b }
+	if n := rtbl.Minimize(equal); n != 3 {
+		t.Errorf("Minimize, got %d, want %d", n, 3)
+	}
+
+	expect := `▼
+├─ 10.0.0.0/8 (A)
+│  ├─ 10.1.2.0/24 (B)
+│  │  └─ 10.1.2.0/25 (A)
+│  │     └─ 10.1.2.0/26 (B)
+│  └─ 10.2.0.0/16 (B)
+└─ 11.0.0.0/8 (A)
+▼
+└─ ::/0 (A)
+`
+	if rtbl.String() != expect {
+		t.Errorf("Minimize\nwant:\n%sgot:\n%s", expect, rtbl.String())
+	}
+
+	// the lookups are not changed
+	clone.Walk(func(pfx netip.Prefix, _ string) bool {
+		for _, ip := range []netip.Addr{pfx.Addr(), pfx.Addr().Next()} {
+			_, want, _ := clone.Lookup(ip)
+			if _, got, _ := rtbl.Lookup(ip); got != want {
+				t.Errorf("Lookup(%v) after Minimize, got %v, want %v", ip, got, want)
+			}
+		}
+		return true
+	})
+
+	if n := rtbl.Minimize(equal); n != 0 {
+		t.Errorf("Minimize again, got %d, want %d", n, 0)
+	}
+}
+
+func TestCollapse(t *testing.T) {
+	t.Parallel()
+
+	rtbl := new(cidrtree.Table[string])
+	rtbl.Insert(mustPfx("10.0.0.0/26"), "A")
+	rtbl.Insert(mustPfx("10.0.0.64/26"), "A")
+	rtbl.Insert(mustPfx("10.0.0.128/26"), "A")
+	rtbl.Insert(mustPfx("10.0.0.192/26"), "A")
+	rtbl.Insert(mustPfx("10.0.0.200/32"), "B")
+	// other value
+	rtbl.Insert(mustPfx("10.0.1.0/25"), "A")
+	rtbl.Insert(mustPfx("10.0.1.128/25"), "B")
+	// parent with other value
+	rtbl.Insert(mustPfx("10.0.2.0/24"), "C")
+	rtbl.Insert(mustPfx("10.0.2.0/25"), "A")
+	rtbl.Insert(mustPfx("10.0.2.128/25"), "A")
+	// parent with same value
+	rtbl.Insert(mustPfx("2001:db8::/32"), "A")
+	rtbl.Insert(mustPfx("2001:db8::/33"), "A")
+	rtbl.Insert(mustPfx("2001:db8:8000::/33"), "A")
+
+	equal := func(a, b string) bool { return a == b }
+	if n := rtbl.Collapse(equal); n != 4 {
+		t.Errorf("Collapse, got %d, want %d", n, 4)
+	}
+
+	expect := `▼
+├─ 10.0.0.0/24 (A)
+│  └─ 10.0.0.200/32 (B)
+├─ 10.0.1.0/25 (A)
+├─ 10.0.1.128/25 (B)
+└─ 10.0.2.0/24 (C)
+   ├─ 10.0.2.0/25 (A)
+   └─ 10.0.2.128/25 (A)
+▼
+└─ 2001:db8::/32 (A)
+`
+	if rtbl.String() != expect {
+		t.Errorf("Collapse\nwant:\n%sgot:\n%s", expect, rtbl.String())
+	}
+}
+
+func TestExclude(t *testing.T) {
+	t.Parallel()
+
+	rtbl := new(cidrtree.Table[string])
+	rtbl.Insert(mustPfx("10.0.0.0/8"), "A")
+	rtbl.Insert(mustPfx("10.2.0.0/16"), "B")
+	rtbl.Insert(mustPfx("10.2.3.128/25"), "C")
+	rtbl.Insert(mustPfx("10.2.2.0/24"), "D")
+
+	clone := rtbl.Clone()
+
+	if ok := rtbl.Exclude(mustPfx("11.0.0.0/8")); ok {
+		t.Errorf("Exclude(%v), got %v, want false", "11.0.0.0/8", ok)
+	}
+
+	if ok := rtbl.Exclude(mustPfx("10.2.3.0/24")); !ok {
+		t.Errorf("Exclude(%v), got %v, want true", "10.2.3.0/24", ok)
+	}
+
+	expect := `▼
+├─ 10.0.0.0/15 (A)
+├─ 10.2.0.0/23 (B)
+├─ 10.2.2.0/24 (D)
+├─ 10.2.3.128/25 (C)
+├─ 10.2.4.0/22 (B)
+├─ 10.2.8.0/21 (B)
+├─ 10.2.16.0/20 (B)
+├─ 10.2.32.0/19 (B)
+├─ 10.2.64.0/18 (B)
+├─ 10.2.128.0/17 (B)
+├─ 10.3.0.0/16 (A)
+├─ 10.4.0.0/14 (A)
+├─ 10.8.0.0/13 (A)
+├─ 10.16.0.0/12 (A)
+├─ 10.32.0.0/11 (A)
+├─ 10.64.0.0/10 (A)
+└─ 10.128.0.0/9 (A)
+`
+	if rtbl.String() != expect {
+		t.Errorf("Exclude\nwant:\n%sgot:\n%s", expect, rtbl.String())
+	}
+
+	// lookups are unchanged outside of the hole, the hole has no covering entry
+	for _, s := range []string{"10.0.0.1", "10.2.0.1", "10.2.2.1", "10.2.3.200", "10.2.200.1", "10.3.0.1", "10.200.0.1"} {
+		ip := mustAddr(s)
+		_, want, _ := clone.Lookup(ip)
+		if _, got, _ := rtbl.Lookup(ip); got != want {
+			t.Errorf("Lookup(%v) after Exclude, got %v, want %v", ip, got, want)
+		}
+	}
+
+	if lpm, _, ok := rtbl.Lookup(mustAddr("10.2.3.1")); ok {
+		t.Errorf("Lookup(%v) in excluded prefix, got %v, want no match", "10.2.3.1", lpm)
+	}
+}
+
+func TestComplement(t *testing.T) {
+	t.Parallel()
+
+	rtbl := new(cidrtree.Table[any])
+	rtbl.Insert(mustPfx("0.0.0.0/2"), nil)
+	rtbl.Insert(mustPfx("10.0.0.0/8"), nil)
+	rtbl.Insert(mustPfx("128.0.0.0/2"), nil)
+	rtbl.Insert(mustPfx("255.255.255.255/32"), nil)
+	rtbl.Insert(mustPfx("8000::/1"), nil)
+
+	// 192.0.0.0/2 minus 255.255.255.255/32 are 30 CIDRs
+	c := rtbl.Complement()
+
+	if got := c.String6(); got != "▼\n└─ ::/1 (<nil>)\n" {
+		t.Errorf("Complement IPv6, got:\n%s", got)
+	}
+
+	var pfxs []netip.Prefix
+	c.Walk(func(pfx netip.Prefix, _ any) bool {
+		if pfx.Addr().Is4() {
+			pfxs = append(pfxs, pfx)
+		}
+		return true
+	})
+
+	if len(pfxs) != 1+30 || pfxs[0] != mustPfx("64.0.0.0/2") || pfxs[len(pfxs)-1] != mustPfx("255.255.255.254/32") {
+		t.Errorf("Complement IPv4, got: %v", pfxs)
+	}
+
+	// complement of complement are the top-level entries of the original
+	cc := c.Complement()
+	want := "▼\n├─ 0.0.0.0/2 (<nil>)\n├─ 128.0.0.0/2 (<nil>)\n└─ 255.255.255.255/32 (<nil>)\n▼\n└─ 8000::/1 (<nil>)\n"
+	if cc.String() != want {
+		t.Errorf("Complement of Complement\nwant:\n%sgot:\n%s", want, cc.String())
+	}
+
+	// scope
+	if got := rtbl.ComplementWithin(mustPfx("10.1.0.0/16")).String(); got != "" {
+		t.Errorf("ComplementWithin covered scope, got:\n%s", got)
+	}
+
+	rtbl.Insert(mustPfx("192.168.0.0/24"), nil)
+	rtbl.Insert(mustPfx("192.168.0.128/25"), nil)
+	rtbl.Insert(mustPfx("192.168.1.0/25"), nil)
+	want = "▼\n├─ 192.168.1.128/25 (<nil>)\n├─ 192.168.2.0/23 (<nil>)\n└─ 192.168.4.0/22 (<nil>)\n"
+	if got := rtbl.ComplementWithin(mustPfx("192.168.0.0/21")).String(); got != want {
+		t.Errorf("ComplementWithin\nwant:\n%sgot:\n%s", want, got)
+	}
+
+	var zeroTable cidrtree.Table[any]
+	want = "▼\n└─ 0.0.0.0/0 (<nil>)\n▼\n└─ ::/0 (<nil>)\n"
+	if got := zeroTable.Complement().String(); got != want {
+		t.Errorf("Complement of zero value\nwant:\n%sgot:\n%s", want, got)
+	}
+}
+
+func TestSample(t *testing.T) {
+	t.Parallel()
+
+	rtbl := new(cidrtree.Table[any])
+	for _, route := range routes {
+		rtbl.Insert(route.cidr, route.nextHop)
+	}
+
+	if got := rtbl.Sample(100); len(got) != len(routes) {
+		t.Errorf("Sample(100), got %d entries, want %d", len(got), len(routes))
+	}
+
+	var zeroTable cidrtree.Table[any]
+	if got := zeroTable.Sample(1); len(got) != 0 {
+		t.Errorf("Sample(1) of zero value, got %v, want []", got)
+	}
+
+	for _, n := range []int{0, -1} {
+		if got := rtbl.Sample(n); got != nil {
+			t.Errorf("Sample(%d), got %v, want nil", n, got)
+		}
+	}
+
+	seen := make(map[netip.Prefix]int)
+	for i := 0; i < 1_000; i++ {
+		sample := rtbl.Sample(4)
+		if len(sample) != 4 {
+			t.Fatalf("Sample(4), got %d entries, want %d", len(sample), 4)
+		}
+
+		for j, e := range sample {
+			if _, value, _ := rtbl.LookupPrefix(e.Prefix); value != e.Value {
+				t.Fatalf("Sample, entry %v not in table", e)
+			}
+
+			// ascending and distinct, v4 before v6
+			if j > 0 && sample[j-1].Prefix.Addr().Is4() == e.Prefix.Addr().Is4() &&
+				sample[j-1].Prefix.Addr().Compare(e.Prefix.Addr()) > 0 {
+				t.Fatalf("Sample, not in ascending order: %v", sample)
+			}
+			seen[e.Prefix]++
+		}
+	}
+
+	// every entry must have a chance
+	if len(seen) != len(routes) {
+		t.Errorf("Sample, got %d distinct entries in all samples, want %d", len(seen), len(routes))
+	}
+}
+
+func TestLookupFunc(t *testing.T) {
+	t.Parallel()
+
+	rtbl := new(cidrtree.Table[string])
+	rtbl.Insert(mustPfx("0.0.0.0/0"), "up")
+	rtbl.Insert(mustPfx("10.0.0.0/8"), "up")
+	rtbl.Insert(mustPfx("10.1.0.0/16"), "down")
+	rtbl.Insert(mustPfx("10.1.1.0/24"), "down")
+	rtbl.Insert(mustPfx("10.1.2.0/24"), "up")
+	rtbl.Insert(mustPfx("::/0"), "down")
+
+	up := func(_ netip.Prefix, v string) bool { return v == "up" }
+
+	tcs := []struct {
+		ip     netip.Addr
+		want   netip.Prefix
+		wantOK bool
+	}{
+		{mustAddr("10.1.1.1"), mustPfx("10.0.0.0/8"), true},
+		{mustAddr("10.1.2.1"), mustPfx("10.1.2.0/24"), true},
+		{mustAddr("10.1.3.1"), mustPfx("10.0.0.0/8"), true},
+		{mustAddr("11.0.0.1"), mustPfx("0.0.0.0/0"), true},
+		{mustAddr("::1"), netip.Prefix{}, false},
+	}
+
+	for _, tt := range tcs {
+		if got, _, ok := rtbl.LookupFunc(tt.ip, up); ok != tt.wantOK || got != tt.want {
+			t.Errorf("LookupFunc(%v) = (%v, %v), want (%v, %v)", tt.ip, got, ok, tt.want, tt.wantOK)
+		}
+	}
+
+	// ##########################################
+
+	tc := shuffleFullTable(10_000)
+	rtbl2 := new(cidrtree.Table[any])
+	for _, cidr := range tc {
+		rtbl2.Insert(cidr, nil)
+	}
+
+	all := func(netip.Prefix, any) bool { return true }
+	for _, cidr := range tc {
+		ip := cidr.Addr()
+		want, _, _ := rtbl2.Lookup(ip)
+		if got, _, _ := rtbl2.LookupFunc(ip, all); got != want {
+			t.Fatalf("LookupFunc(%v) = %v, want %v", ip, got, want)
+		}
+
+		// skip the lpm, must be the next-less-specific match
+		skip := func(pfx netip.Prefix, _ any) bool { return pfx != want }
+		got, _, ok := rtbl2.LookupFunc(ip, skip)
+
+		rtbl2.Delete(want)
+		want2, _, ok2 := rtbl2.Lookup(ip)
+		rtbl2.Insert(want, nil)
+
+		if ok != ok2 || got != want2 {
+			t.Fatalf("LookupFunc(%v) skipping %v = %v, want %v", ip, want, got, want2)
+		}
+	}
+}
+
+func TestLookupMaxBits(t *testing.T) {
+	t.Parallel()
+
+	rtbl := new(cidrtree.Table[any])
+	for _, route := range routes {
+		rtbl.Insert(route.cidr, route.nextHop)
+	}
+	rtbl.Insert(mustPfx("10.0.0.1/32"), nil)
+
+	tcs := []struct {
+		ip      netip.Addr
+		maxBits int
+		want    netip.Prefix
+		wantOK  bool
+	}{
+		{mustAddr("10.0.0.1"), 32, mustPfx("10.0.0.1/32"), true},
+		{mustAddr("10.0.0.1"), 24, mustPfx("10.0.0.0/24"), true},
+		{mustAddr("10.0.0.1"), 23, mustPfx("10.0.0.0/8"), true},
+		{mustAddr("10.0.0.1"), 7, netip.Prefix{}, false},
+		{mustAddr("2001:db8::1"), 128, mustPfx("2001:db8::/32"), true},
+		{mustAddr("2001:db8::1"), 31, mustPfx("2000::/3"), true},
+		{mustAddr("2001:db8::1"), 2, mustPfx("::/0"), true},
+	}
+
+	for _, tt := range tcs {
+		if got, _, ok := rtbl.LookupMaxBits(tt.ip, tt.maxBits); ok != tt.wantOK || got != tt.want {
+			t.Errorf("LookupMaxBits(%v, %v) = (%v, %v), want (%v, %v)", tt.ip, tt.maxBits, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestLookupExcluding(t *testing.T) {
+	t.Parallel()
+
+	rtbl := new(cidrtree.Table[any])
+	for _, route := range routes {
+		rtbl.Insert(route.cidr, route.nextHop)
+	}
+
+	tcs := []struct {
+		ip      netip.Addr
+		exclude netip.Prefix
+		want    netip.Prefix
+		wantOK  bool
+	}{
+		{mustAddr("10.0.0.1"), mustPfx("10.0.0.0/24"), mustPfx("10.0.0.0/8"), true},
+		{mustAddr("10.0.0.1"), mustPfx("10.0.0.17/24"), mustPfx("10.0.0.0/8"), true},
+		{mustAddr("10.0.0.1"), mustPfx("10.0.1.0/24"), mustPfx("10.0.0.0/24"), true},
+		{mustAddr("10.0.0.1"), netip.Prefix{}, mustPfx("10.0.0.0/24"), true},
+		{mustAddr("10.1.0.1"), mustPfx("10.0.0.0/8"), netip.Prefix{}, false},
+		{mustAddr("::1"), mustPfx("::1/128"), mustPfx("::/0"), true},
+	}
+
+	for _, tt := range tcs {
+		if got, _, ok := rtbl.LookupExcluding(tt.ip, tt.exclude); ok != tt.wantOK || got != tt.want {
+			t.Errorf("LookupExcluding(%v, %v) = (%v, %v), want (%v, %v)", tt.ip, tt.exclude, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
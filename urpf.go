@@ -0,0 +1,53 @@
+package cidrtree
+
+import "net/netip"
+
+// RPFMode is the mode of the unicast reverse path forwarding check, see [Table.VerifySource].
+type RPFMode int
+
+const (
+	// RPFStrict passes a packet only if the route to the source points back to the arrival
+	// interface, for single-homed edges.
+	RPFStrict RPFMode = iota + 1
+
+	// RPFLoose passes a packet if there is any route to the source, for multi-homed
+	// and asymmetric routing.
+	RPFLoose
+)
+
+// String implements the fmt.Stringer interface.
+func (m RPFMode) String() string {
+	switch m {
+	case RPFStrict:
+		return "strict"
+	case RPFLoose:
+		return "loose"
+	default:
+		return "unknown"
+	}
+}
+
+// VerifySource is the uRPF anti-spoofing check for the source address of a packet.
+// The longest-prefix-match for src is looked up, in strict mode arrived reports whether the
+// matched value, e.g. the interface or next hops of the route, includes the arrival interface
+// of the packet. In loose mode any match passes, arrived isn't called and may be nil.
+//
+// As usual for uRPF, the default routes 0.0.0.0/0 and ::/0 never verify a source,
+// the next-less-specific match is taken instead, see [Table.LookupFunc].
+func (t Table[V]) VerifySource(src netip.Addr, mode RPFMode, arrived func(V) bool) bool {
+	_, value, ok := t.LookupFunc(src, func(pfx netip.Prefix, _ V) bool {
+		return pfx.Bits() > 0
+	})
+	if !ok {
+		return false
+	}
+
+	switch mode {
+	case RPFStrict:
+		return arrived(value)
+	case RPFLoose:
+		return true
+	default:
+		return false
+	}
+}
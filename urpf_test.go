@@ -0,0 +1,49 @@
+package cidrtree_test
+
+import (
+	"testing"
+
+	"github.com/gaissmai/cidrtree"
+)
+
+func TestVerifySource(t *testing.T) {
+	t.Parallel()
+
+	// the values are the interfaces of the routes
+	rtbl := new(cidrtree.Table[string])
+	rtbl.Insert(mustPfx("0.0.0.0/0"), "wan")
+	rtbl.Insert(mustPfx("10.0.0.0/8"), "lan")
+	rtbl.Insert(mustPfx("192.0.2.0/24"), "wan")
+	rtbl.Insert(mustPfx("2001:db8::/32"), "lan")
+
+	on := func(iface string) func(string) bool {
+		return func(v string) bool { return v == iface }
+	}
+
+	tcs := []struct {
+		src   string
+		iface string
+		mode  cidrtree.RPFMode
+		want  bool
+	}{
+		{"10.1.2.3", "lan", cidrtree.RPFStrict, true},
+		{"10.1.2.3", "wan", cidrtree.RPFStrict, false}, // spoofed
+		{"10.1.2.3", "wan", cidrtree.RPFLoose, true},
+		{"192.0.2.1", "wan", cidrtree.RPFStrict, true},
+		{"8.8.8.8", "wan", cidrtree.RPFStrict, false}, // default route only
+		{"8.8.8.8", "wan", cidrtree.RPFLoose, false},
+		{"2001:db8::1", "lan", cidrtree.RPFStrict, true},
+		{"2001:db9::1", "lan", cidrtree.RPFLoose, false},
+		{"10.1.2.3", "lan", cidrtree.RPFMode(0), false},
+	}
+
+	for _, tc := range tcs {
+		if got := rtbl.VerifySource(mustAddr(tc.src), tc.mode, on(tc.iface)); got != tc.want {
+			t.Errorf("VerifySource(%v, %v) via %v, got %v, want %v", tc.src, tc.mode, tc.iface, got, tc.want)
+		}
+	}
+
+	if rtbl.VerifySource(mustAddr("10.1.2.3"), cidrtree.RPFLoose, nil) != true {
+		t.Errorf("VerifySource, loose mode with nil arrived, got false, want true")
+	}
+}
@@ -0,0 +1,232 @@
+package cidrtree
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ReadVRPs returns the VRPs of a JSON export of a relying party software,
+// e.g. rpki-client -j or routinator vrps --format json.
+//
+//	{"roas":[{"asn":"AS64500","prefix":"192.0.2.0/24","maxLength":24,"ta":"ripe"}]}
+//
+// The ASN is a number or a string with an optional AS prefix, unknown fields are ignored.
+func ReadVRPs(r io.Reader) ([]VRP, error) {
+	var export struct {
+		Roas []jsonVRP `json:"roas"`
+	}
+
+	if err := json.NewDecoder(r).Decode(&export); err != nil {
+		return nil, fmt.Errorf("cidrtree: vrps: %w", err)
+	}
+
+	vrps := make([]VRP, 0, len(export.Roas))
+	for i, roa := range export.Roas {
+		vrp, err := roa.vrp(roa.MaxLength)
+		if err != nil {
+			return nil, fmt.Errorf("cidrtree: vrps: roa %d: %w", i, err)
+		}
+		vrps = append(vrps, vrp)
+	}
+	return vrps, nil
+}
+
+// SLURM is a simplified local internet number resource management file, see RFC 8416.
+// Only the prefix filters and the prefix assertions are supported, the BGPsec parts are ignored.
+type SLURM struct {
+	Filters    []SLURMFilter
+	Assertions []VRP
+}
+
+// SLURMFilter removes the VRPs covered by Prefix and with the origin ASN,
+// an invalid Prefix or a nil ASN matches all.
+type SLURMFilter struct {
+	Prefix netip.Prefix
+	ASN    *uint32
+}
+
+// ReadSLURM returns the local exceptions of a SLURM file, see RFC 8416.
+func ReadSLURM(r io.Reader) (*SLURM, error) {
+	var file struct {
+		Version *int `json:"slurmVersion"`
+		Filters struct {
+			Prefix []jsonVRP `json:"prefixFilters"`
+		} `json:"validationOutputFilters"`
+		Assertions struct {
+			Prefix []jsonVRP `json:"prefixAssertions"`
+		} `json:"locallyAddedAssertions"`
+	}
+
+	if err := json.NewDecoder(r).Decode(&file); err != nil {
+		return nil, fmt.Errorf("cidrtree: slurm: %w", err)
+	}
+	if file.Version == nil || *file.Version != 1 {
+		return nil, fmt.Errorf("cidrtree: slurm: unsupported slurmVersion")
+	}
+
+	s := new(SLURM)
+	for i, f := range file.Filters.Prefix {
+		if f.Prefix == "" && f.ASN == nil {
+			return nil, fmt.Errorf("cidrtree: slurm: prefix filter %d: neither prefix nor asn", i)
+		}
+
+		var filter SLURMFilter
+		if f.Prefix != "" {
+			pfx, err := netip.ParsePrefix(f.Prefix)
+			if err != nil {
+				return nil, fmt.Errorf("cidrtree: slurm: prefix filter %d: %w", i, err)
+			}
+			filter.Prefix = pfx.Masked()
+		}
+		if f.ASN != nil {
+			asn := uint32(*f.ASN)
+			filter.ASN = &asn
+		}
+		s.Filters = append(s.Filters, filter)
+	}
+
+	for i, a := range file.Assertions.Prefix {
+		vrp, err := a.vrp(a.MaxPrefixLength)
+		if err != nil {
+			return nil, fmt.Errorf("cidrtree: slurm: prefix assertion %d: %w", i, err)
+		}
+		s.Assertions = append(s.Assertions, vrp)
+	}
+
+	return s, nil
+}
+
+// Apply returns the VRPs without the filtered ones and with the assertions added.
+// The input slice is not modified.
+func (s *SLURM) Apply(vrps []VRP) []VRP {
+	result := make([]VRP, 0, len(vrps)+len(s.Assertions))
+	for _, vrp := range vrps {
+		if !slices.ContainsFunc(s.Filters, func(f SLURMFilter) bool { return f.match(vrp) }) {
+			result = append(result, vrp)
+		}
+	}
+	return append(result, s.Assertions...)
+}
+
+// match reports whether the filter removes vrp.
+func (f SLURMFilter) match(vrp VRP) bool {
+	if f.ASN != nil && *f.ASN != vrp.ASN {
+		return false
+	}
+	if f.Prefix.IsValid() && !(f.Prefix.Bits() <= vrp.Prefix.Bits() && f.Prefix.Contains(vrp.Prefix.Addr())) {
+		return false
+	}
+	return true
+}
+
+// Diff returns the VRPs to announce and to withdraw to get from the VRPs of the table
+// to the next VRPs, both in ascending order, e.g. for the serial notifications of RTR.
+func (vt *VRPTable) Diff(next []VRP) (announced, withdrawn []VRP) {
+	want := make(map[VRP]struct{}, len(next))
+	for _, vrp := range next {
+		vrp.Prefix = vrp.Prefix.Masked() // always canonicalize!
+		want[vrp] = struct{}{}
+	}
+
+	vt.Walk(func(vrp VRP) bool {
+		if _, ok := want[vrp]; ok {
+			delete(want, vrp)
+		} else {
+			withdrawn = append(withdrawn, vrp)
+		}
+		return true
+	})
+
+	for vrp := range want {
+		announced = append(announced, vrp)
+	}
+
+	// Walk returns the VRPs of a prefix in insertion order
+	slices.SortFunc(announced, compareVRP)
+	slices.SortFunc(withdrawn, compareVRP)
+
+	return announced, withdrawn
+}
+
+// Refresh updates the table in place to the next VRPs and returns the applied changes,
+// see [VRPTable.Diff]. Long-running validators don't have to rebuild the table in each cycle.
+func (vt *VRPTable) Refresh(next []VRP) (announced, withdrawn []VRP) {
+	announced, withdrawn = vt.Diff(next)
+	for _, vrp := range withdrawn {
+		vt.Delete(vrp)
+	}
+	for _, vrp := range announced {
+		vt.Insert(vrp)
+	}
+	return announced, withdrawn
+}
+
+// compareVRP sorts by prefix as the table, then by maxLength and ASN.
+func compareVRP(a, b VRP) int {
+	if c := compare(a.Prefix, b.Prefix); c != 0 {
+		return c
+	}
+	if c := cmp.Compare(a.MaxLength, b.MaxLength); c != 0 {
+		return c
+	}
+	return cmp.Compare(a.ASN, b.ASN)
+}
+
+// jsonVRP is a prefix record of the VRP exports and of SLURM files.
+type jsonVRP struct {
+	ASN             *jsonASN `json:"asn"`
+	Prefix          string   `json:"prefix"`
+	MaxLength       *int     `json:"maxLength"`
+	MaxPrefixLength *int     `json:"maxPrefixLength"`
+}
+
+// vrp returns the checked VRP, a missing maxLength is the prefix length.
+func (j jsonVRP) vrp(maxLength *int) (VRP, error) {
+	if j.ASN == nil {
+		return VRP{}, fmt.Errorf("missing asn")
+	}
+
+	pfx, err := netip.ParsePrefix(j.Prefix)
+	if err != nil {
+		return VRP{}, err
+	}
+
+	vrp := VRP{Prefix: pfx.Masked(), MaxLength: pfx.Bits(), ASN: uint32(*j.ASN)}
+	if maxLength != nil {
+		vrp.MaxLength = *maxLength
+	}
+
+	if vrp.MaxLength < pfx.Bits() || vrp.MaxLength > pfx.Addr().BitLen() {
+		return VRP{}, fmt.Errorf("maxLength %d out of range for %s", vrp.MaxLength, pfx)
+	}
+	return vrp, nil
+}
+
+// jsonASN is an ASN as JSON number or string, e.g. 64500, "64500" or "AS64500".
+type jsonASN uint32
+
+// UnmarshalJSON implements the [json.Unmarshaler] interface.
+func (a *jsonASN) UnmarshalJSON(data []byte) error {
+	asn, err := parseASN(string(bytes.Trim(data, `"`)))
+	if err != nil {
+		return err
+	}
+	*a = jsonASN(asn)
+	return nil
+}
+
+// parseASN parses the ASN in asplain notation with an optional AS prefix.
+func parseASN(s string) (uint32, error) {
+	asn, err := strconv.ParseUint(strings.TrimPrefix(strings.ToUpper(s), "AS"), 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid asn %q", s)
+	}
+	return uint32(asn), nil
+}
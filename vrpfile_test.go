@@ -0,0 +1,163 @@
+package cidrtree_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gaissmai/cidrtree"
+)
+
+const vrpExport = `{
+  "metadata": {"buildtime": "2024-01-01T00:00:00Z"},
+  "roas": [
+    {"asn": "AS64500", "prefix": "192.0.2.0/24", "maxLength": 24, "ta": "ripe"},
+    {"asn": 64501, "prefix": "198.51.100.0/22", "maxLength": 24, "ta": "arin"},
+    {"asn": "64502", "prefix": "2001:db8::/32", "ta": "apnic"}
+  ]
+}`
+
+const slurmFile = `{
+  "slurmVersion": 1,
+  "validationOutputFilters": {
+    "prefixFilters": [
+      {"prefix": "198.51.100.0/22", "comment": "all VRPs covered"},
+      {"asn": 64502}
+    ],
+    "bgpsecFilters": []
+  },
+  "locallyAddedAssertions": {
+    "prefixAssertions": [
+      {"asn": 64503, "prefix": "203.0.113.0/24", "maxPrefixLength": 25}
+    ],
+    "bgpsecAssertions": []
+  }
+}`
+
+func TestReadVRPs(t *testing.T) {
+	t.Parallel()
+
+	vrps, err := cidrtree.ReadVRPs(strings.NewReader(vrpExport))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []cidrtree.VRP{
+		{Prefix: mustPfx("192.0.2.0/24"), MaxLength: 24, ASN: 64500},
+		{Prefix: mustPfx("198.51.100.0/22"), MaxLength: 24, ASN: 64501},
+		{Prefix: mustPfx("2001:db8::/32"), MaxLength: 32, ASN: 64502},
+	}
+	if len(vrps) != len(want) {
+		t.Fatalf("ReadVRPs, got %v, want %v", vrps, want)
+	}
+	for i := range want {
+		if vrps[i] != want[i] {
+			t.Errorf("ReadVRPs[%d], got %v, want %v", i, vrps[i], want[i])
+		}
+	}
+
+	for _, bad := range []string{
+		`{"roas": [{"asn": "ASX", "prefix": "192.0.2.0/24", "maxLength": 24}]}`,
+		`{"roas": [{"prefix": "192.0.2.0/24", "maxLength": 24}]}`,
+		`{"roas": [{"asn": 1, "prefix": "192.0.2.0/24", "maxLength": 23}]}`,
+		`{"roas": [{"asn": 1, "prefix": "192.0.2.0/24", "maxLength": 33}]}`,
+		`{"roas": [{"asn": 1, "prefix": "192.0.2.0", "maxLength": 24}]}`,
+		`{"roas": `,
+	} {
+		if _, err := cidrtree.ReadVRPs(strings.NewReader(bad)); err == nil {
+			t.Errorf("ReadVRPs(%s), expected error", bad)
+		}
+	}
+}
+
+func TestReadSLURM(t *testing.T) {
+	t.Parallel()
+
+	vrps, _ := cidrtree.ReadVRPs(strings.NewReader(vrpExport))
+	slurm, err := cidrtree.ReadSLURM(strings.NewReader(slurmFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := slurm.Apply(vrps)
+	want := []cidrtree.VRP{
+		{Prefix: mustPfx("192.0.2.0/24"), MaxLength: 24, ASN: 64500},
+		{Prefix: mustPfx("203.0.113.0/24"), MaxLength: 25, ASN: 64503},
+	}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("Apply, got %v, want %v", got, want)
+	}
+	if len(vrps) != 3 {
+		t.Errorf("Apply modified the input, got %v", vrps)
+	}
+
+	for _, bad := range []string{
+		`{"slurmVersion": 2}`,
+		`{"slurmVersion": 1, "validationOutputFilters": {"prefixFilters": [{"comment": "empty"}]}}`,
+		`{"slurmVersion": 1, "locallyAddedAssertions": {"prefixAssertions": [{"prefix": "10.0.0.0/8"}]}}`,
+	} {
+		if _, err := cidrtree.ReadSLURM(strings.NewReader(bad)); err == nil {
+			t.Errorf("ReadSLURM(%s), expected error", bad)
+		}
+	}
+}
+
+func TestVRPTableRefresh(t *testing.T) {
+	t.Parallel()
+
+	vrps, _ := cidrtree.ReadVRPs(strings.NewReader(vrpExport))
+
+	vt := new(cidrtree.VRPTable)
+	if announced, withdrawn := vt.Refresh(vrps); len(announced) != 3 || len(withdrawn) != 0 {
+		t.Fatalf("Refresh, got %v %v, want 3 announced", announced, withdrawn)
+	}
+
+	next := []cidrtree.VRP{
+		vrps[1],
+		{Prefix: mustPfx("10.0.0.0/8"), MaxLength: 8, ASN: 64510},
+		vrps[0],
+	}
+
+	announced, withdrawn := vt.Diff(next)
+	if len(announced) != 1 || announced[0].ASN != 64510 || len(withdrawn) != 1 || withdrawn[0] != vrps[2] {
+		t.Errorf("Diff, got %v %v", announced, withdrawn)
+	}
+	if vt.Size() != 3 {
+		t.Errorf("Diff modified the table, got size %v", vt.Size())
+	}
+
+	vt.Refresh(next)
+	if announced, withdrawn := vt.Diff(next); len(announced) != 0 || len(withdrawn) != 0 {
+		t.Errorf("Refresh, table differs from next, got %v %v", announced, withdrawn)
+	}
+	if got := vt.Validate(mustPfx("2001:db8::/32"), 64502); got != cidrtree.NotFound {
+		t.Errorf("Validate after Refresh, got %v, want %v", got, cidrtree.NotFound)
+	}
+}
+
+func TestVRPTableDiffSorted(t *testing.T) {
+	t.Parallel()
+
+	// several VRPs for the same prefix, not in ascending order
+	vrps := []cidrtree.VRP{
+		{Prefix: mustPfx("192.0.2.0/24"), MaxLength: 24, ASN: 64502},
+		{Prefix: mustPfx("192.0.2.0/24"), MaxLength: 28, ASN: 64501},
+		{Prefix: mustPfx("192.0.2.0/24"), MaxLength: 24, ASN: 64501},
+		{Prefix: mustPfx("10.0.0.0/8"), MaxLength: 8, ASN: 64500},
+	}
+
+	vt := new(cidrtree.VRPTable)
+	announced, _ := vt.Diff(vrps)
+	for _, vrp := range vrps {
+		vt.Insert(vrp)
+	}
+	_, withdrawn := vt.Diff(nil)
+
+	want := []cidrtree.VRP{vrps[3], vrps[2], vrps[0], vrps[1]}
+	if !reflect.DeepEqual(announced, want) {
+		t.Errorf("Diff announced, got %v, want %v", announced, want)
+	}
+	if !reflect.DeepEqual(withdrawn, want) {
+		t.Errorf("Diff withdrawn, got %v, want %v", withdrawn, want)
+	}
+}
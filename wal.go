@@ -0,0 +1,228 @@
+package cidrtree
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"net/netip"
+	"slices"
+	"sync"
+)
+
+// WAL record operations.
+const (
+	walVersion byte = 0 // version header, see walFormat
+	walInsert  byte = 1
+	walDelete  byte = 2
+)
+
+// walFormat is the current version of the WAL record format.
+//
+//	0: insert and delete records, logs without version record
+//	1: version record before the first record of every WAL instance
+const walFormat = 1
+
+// WAL is a write-ahead log for a table. Every mutation is appended to the writer
+// before it is applied to the table, [Table.Replay] reconstructs the table from a snapshot and the log.
+//
+// Record format, all records are checksummed:
+//
+//	uvarint(len(payload)) | payload | crc32(payload)
+//	payload: op | addr len (4 or 16) | addr | bits | value (insert only)
+//	version: 0 | format version
+//
+// Every WAL writes a version record before its first record, appending to an
+// existing log is safe. Logs written by older versions of this package are migrated by Replay.
+type WAL[V any] struct {
+	mu     sync.Mutex
+	table  *Table[V]
+	w      io.Writer
+	encode func(V) ([]byte, error)
+	buf    []byte
+
+	versioned bool // version record written
+}
+
+// NewWAL returns a write-ahead log for table, the values are serialized with encode.
+// The writer should be synced by the caller, e.g. an *os.File opened with O_APPEND|O_SYNC.
+func NewWAL[V any](table *Table[V], w io.Writer, encode func(V) ([]byte, error)) *WAL[V] {
+	return &WAL[V]{table: table, w: w, encode: encode}
+}
+
+// Insert logs the insert of pfx with value and applies it to the table.
+// The table isn't changed if the record can't be written.
+func (l *WAL[V]) Insert(pfx netip.Prefix, value V) error {
+	data, err := l.encode(value)
+	if err != nil {
+		return fmt.Errorf("cidrtree: wal: %w", err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := l.write(walInsert, pfx.Masked(), data); err != nil {
+		return err
+	}
+	l.table.Insert(pfx, value)
+	return nil
+}
+
+// Delete logs the delete of pfx and applies it to the table, returns true if pfx was in the table.
+// The table isn't changed if the record can't be written.
+func (l *WAL[V]) Delete(pfx netip.Prefix) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := l.write(walDelete, pfx.Masked(), nil); err != nil {
+		return false, err
+	}
+	return l.table.Delete(pfx), nil
+}
+
+// write a single mutation record, preceded by the version record.
+func (l *WAL[V]) write(op byte, pfx netip.Prefix, value []byte) error {
+	if !pfx.IsValid() {
+		return fmt.Errorf("cidrtree: wal: invalid prefix")
+	}
+
+	if !l.versioned {
+		if err := l.writeRecord([]byte{walVersion, walFormat}); err != nil {
+			return err
+		}
+		l.versioned = true
+	}
+
+	l.buf = appendWALPayload(l.buf[:0], op, pfx, value)
+	return l.writeRecord(l.buf)
+}
+
+// writeRecord writes the payload as single record with one call to the underlying writer.
+func (l *WAL[V]) writeRecord(payload []byte) error {
+	if _, err := l.w.Write(appendWALRecord(nil, payload)); err != nil {
+		return fmt.Errorf("cidrtree: wal: %w", err)
+	}
+	return nil
+}
+
+// appendWALPayload appends the payload of a mutation record to buf.
+func appendWALPayload(buf []byte, op byte, pfx netip.Prefix, value []byte) []byte {
+	addr := pfx.Addr().AsSlice()
+
+	buf = append(buf, op, byte(len(addr)))
+	buf = append(buf, addr...)
+	buf = append(buf, byte(pfx.Bits()))
+	return append(buf, value...)
+}
+
+// appendWALRecord appends the payload with length and checksum as single record to dst.
+func appendWALRecord(dst, payload []byte) []byte {
+	dst = slices.Grow(dst, binary.MaxVarintLen64+len(payload)+4)
+	dst = binary.AppendUvarint(dst, uint64(len(payload)))
+	dst = append(dst, payload...)
+	return binary.BigEndian.AppendUint32(dst, crc32.ChecksumIEEE(payload))
+}
+
+// maxWALRecord limits the size of a record payload in Replay.
+const maxWALRecord = 1 << 24
+
+// Replay applies the records of the write-ahead log to the table, e.g. after loading the last snapshot.
+// It returns the number of applied mutation records. A torn last record, e.g. after a crash,
+// is reported as [io.ErrUnexpectedEOF], all complete records before it are applied.
+func (t *Table[V]) Replay(r io.Reader, decode func([]byte) (V, error)) (int, error) {
+	t.mustNotBeFrozen()
+
+	br := bufio.NewReader(r)
+	var n int
+
+	// logs without version record are version 0
+	version := 0
+
+	for {
+		size, err := binary.ReadUvarint(br)
+		if errors.Is(err, io.EOF) {
+			return n, nil
+		}
+		if err != nil {
+			return n, fmt.Errorf("cidrtree: wal record %d: %w", n+1, err)
+		}
+		if size > maxWALRecord {
+			return n, fmt.Errorf("cidrtree: wal record %d: size %d too big", n+1, size)
+		}
+
+		record := make([]byte, size+4)
+		if _, err := io.ReadFull(br, record); err != nil {
+			if errors.Is(err, io.EOF) {
+				err = io.ErrUnexpectedEOF
+			}
+			return n, fmt.Errorf("cidrtree: wal record %d: %w", n+1, err)
+		}
+
+		payload, sum := record[:size], binary.BigEndian.Uint32(record[size:])
+		if crc32.ChecksumIEEE(payload) != sum {
+			return n, fmt.Errorf("cidrtree: wal record %d: checksum mismatch", n+1)
+		}
+
+		if len(payload) == 2 && payload[0] == walVersion {
+			if version = int(payload[1]); version > walFormat {
+				return n, fmt.Errorf("cidrtree: wal: unsupported format version %d", version)
+			}
+			continue
+		}
+
+		// the mutation records are unchanged in all versions, no migration needed yet
+		switch version {
+		case 0, 1:
+			if err := t.applyWAL(payload, decode); err != nil {
+				return n, fmt.Errorf("cidrtree: wal record %d: %w", n+1, err)
+			}
+		}
+		n++
+	}
+}
+
+// applyWAL decodes and applies a single record payload.
+func (t *Table[V]) applyWAL(payload []byte, decode func([]byte) (V, error)) error {
+	op, pfx, v, err := decodeWAL(payload, decode)
+	if err != nil {
+		return err
+	}
+
+	switch op {
+	case walInsert:
+		t.Insert(pfx, v)
+	case walDelete:
+		t.Delete(pfx)
+	}
+	return nil
+}
+
+// decodeWAL decodes the payload of a mutation record, the value only for inserts.
+func decodeWAL[V any](payload []byte, decode func([]byte) (V, error)) (op byte, pfx netip.Prefix, value V, err error) {
+	if len(payload) < 2 {
+		err = errors.New("malformed record")
+		return
+	}
+	op, alen := payload[0], int(payload[1])
+
+	if (alen != 4 && alen != 16) || len(payload) < 3+alen {
+		err = errors.New("malformed record")
+		return
+	}
+
+	addr, _ := netip.AddrFromSlice(payload[2 : 2+alen])
+	if pfx, err = addr.Prefix(int(payload[2+alen])); err != nil {
+		return
+	}
+
+	switch op {
+	case walInsert:
+		value, err = decode(payload[3+alen:])
+	case walDelete:
+	default:
+		err = fmt.Errorf("unknown operation %d", op)
+	}
+	return
+}
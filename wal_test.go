@@ -0,0 +1,122 @@
+package cidrtree_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+	"strconv"
+	"testing"
+
+	"github.com/gaissmai/cidrtree"
+)
+
+func encodeInt(v int) ([]byte, error) { return strconv.AppendInt(nil, int64(v), 10), nil }
+func decodeInt(b []byte) (int, error) { return strconv.Atoi(string(b)) }
+
+func TestWAL(t *testing.T) {
+	t.Parallel()
+
+	// snapshot
+	snapshot := new(cidrtree.Table[int])
+	snapshot.Insert(mustPfx("192.168.0.0/16"), 0)
+
+	live := snapshot.Clone()
+	log := new(bytes.Buffer)
+	wal := cidrtree.NewWAL(live, log, encodeInt)
+
+	for i, s := range []string{"10.0.0.0/8", "10.0.1.0/24", "::/0", "2001:db8::/32"} {
+		if err := wal.Insert(mustPfx(s), i+1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if ok, err := wal.Delete(mustPfx("10.0.1.0/24")); !ok || err != nil {
+		t.Fatalf("Delete, got (%v, %v), want (true, nil)", ok, err)
+	}
+	if err := wal.Insert(mustPfx("::/0"), 42); err != nil {
+		t.Fatal(err)
+	}
+
+	replayed := snapshot.Clone()
+	n, err := replayed.Replay(bytes.NewReader(log.Bytes()), decodeInt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 6 {
+		t.Errorf("Replay, got %d records, want %d", n, 6)
+	}
+
+	if replayed.String() != live.String() {
+		t.Errorf("Replay, got:\n%v\nwant:\n%v", replayed, live)
+	}
+
+	// torn last record
+	torn := new(cidrtree.Table[int])
+	n, err = torn.Replay(bytes.NewReader(log.Bytes()[:log.Len()-3]), decodeInt)
+	if !errors.Is(err, io.ErrUnexpectedEOF) || n != 5 {
+		t.Errorf("Replay of torn log, got (%d, %v), want (%d, %v)", n, err, 5, io.ErrUnexpectedEOF)
+	}
+
+	// corrupted record
+	corrupt := bytes.Clone(log.Bytes())
+	corrupt[3] ^= 0xff
+	if _, err := new(cidrtree.Table[int]).Replay(bytes.NewReader(corrupt), decodeInt); err == nil {
+		t.Errorf("Replay of corrupted log, expected error")
+	}
+}
+
+type failWriter struct{}
+
+func (failWriter) Write([]byte) (int, error) { return 0, errors.New("disk full") }
+
+func TestWALWriteError(t *testing.T) {
+	t.Parallel()
+
+	rtbl := new(cidrtree.Table[int])
+	wal := cidrtree.NewWAL(rtbl, failWriter{}, encodeInt)
+
+	if err := wal.Insert(mustPfx("10.0.0.0/8"), 1); err == nil {
+		t.Errorf("Insert, expected error")
+	}
+	if rtbl.Size() != 0 {
+		t.Errorf("Insert with write error changed the table")
+	}
+}
+
+func TestWALVersion(t *testing.T) {
+	t.Parallel()
+
+	log := new(bytes.Buffer)
+	wal := cidrtree.NewWAL(new(cidrtree.Table[int]), log, encodeInt)
+	_ = wal.Insert(mustPfx("10.0.0.0/8"), 1)
+	_ = wal.Insert(mustPfx("::/0"), 2)
+
+	// append with a new WAL instance, a second version record
+	wal = cidrtree.NewWAL(new(cidrtree.Table[int]), log, encodeInt)
+	_ = wal.Insert(mustPfx("10.0.0.0/8"), 3)
+
+	rtbl := new(cidrtree.Table[int])
+	if n, err := rtbl.Replay(bytes.NewReader(log.Bytes()), decodeInt); n != 3 || err != nil {
+		t.Fatalf("Replay of appended log, got (%d, %v), want (3, nil)", n, err)
+	}
+	if _, v, _ := rtbl.Lookup(mustAddr("10.0.0.1")); v != 3 {
+		t.Errorf("Replay of appended log, got %d, want %d", v, 3)
+	}
+
+	// legacy version 0, without version record: len | op | format | crc32
+	const versionRecord = 1 + 2 + 4
+	legacy := log.Bytes()[versionRecord:]
+
+	if n, err := new(cidrtree.Table[int]).Replay(bytes.NewReader(legacy), decodeInt); n != 3 || err != nil {
+		t.Errorf("Replay of legacy log, got (%d, %v), want (3, nil)", n, err)
+	}
+
+	// unsupported future version
+	future := new(bytes.Buffer)
+	future.Write([]byte{2, 0, 99})
+	future.Write(binary.BigEndian.AppendUint32(nil, crc32.ChecksumIEEE([]byte{0, 99})))
+	if _, err := new(cidrtree.Table[int]).Replay(future, decodeInt); err == nil {
+		t.Errorf("Replay of future version, expected error")
+	}
+}
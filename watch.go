@@ -0,0 +1,213 @@
+package cidrtree
+
+import (
+	"net/netip"
+	"slices"
+
+	"github.com/gaissmai/extnetip"
+)
+
+// Event is a change of an entry, see [Watched].
+type Event[V any] struct {
+	Prefix  netip.Prefix
+	Value   V    // the new value, the zero value if deleted
+	Deleted bool // the entry was removed
+}
+
+// Watched is a table with change notifications per scope. A watcher registers interest
+// in a covering prefix and receives only the events for the entries inside it,
+// e.g. a controller responsible for a single allocation.
+//
+// The table isn't embedded, all mutations must be made with the methods of Watched
+// to be notified. The single and bulk mutations Insert, Delete, Union, DeleteSubtree,
+// ReplaceSubtree and ModifyAll are supported, use Table for all read-only operations.
+type Watched[V any] struct {
+	table *Table[V]
+
+	scopes Table[[]*watcher[V]] // registered watchers per scope, in registration order
+}
+
+type watcher[V any] struct {
+	fn        func(Event[V])
+	cancelled bool // cancelled during the delivery of an event
+}
+
+// NewWatched returns the watched table t, t must not be changed afterwards.
+func NewWatched[V any](t *Table[V]) *Watched[V] {
+	return &Watched[V]{table: t}
+}
+
+// Watch registers fn for the events of all entries covered by scope, scope included.
+// The default routes 0.0.0.0/0 and ::/0 watch all changes of their IP version.
+// The events are delivered synchronously after the mutation, to the less specific scopes first.
+// Calling cancel stops the delivery to fn.
+func (w *Watched[V]) Watch(scope netip.Prefix, fn func(Event[V])) (cancel func()) {
+	scope = scope.Masked() // always canonicalize!
+
+	wr := &watcher[V]{fn: fn}
+
+	old, _ := w.scopes.get(scope)
+	w.scopes.Insert(scope, append(slices.Clone(old), wr))
+
+	return func() {
+		wr.cancelled = true
+
+		old, ok := w.scopes.get(scope)
+		if !ok {
+			return
+		}
+
+		// copy-on-write, the events in flight use the old slice
+		watchers := slices.DeleteFunc(slices.Clone(old), func(x *watcher[V]) bool { return x == wr })
+		if len(watchers) == 0 {
+			w.scopes.Delete(scope)
+			return
+		}
+		w.scopes.Insert(scope, watchers)
+	}
+}
+
+// Insert adds pfx to the table and notifies the watchers of pfx, see [Table.Insert].
+func (w *Watched[V]) Insert(pfx netip.Prefix, value V) {
+	pfx = pfx.Masked() // always canonicalize!
+
+	w.table.Insert(pfx, value)
+	w.notify(Event[V]{Prefix: pfx, Value: value})
+}
+
+// Delete removes pfx from the table and notifies the watchers of pfx if pfx was present, see [Table.Delete].
+func (w *Watched[V]) Delete(pfx netip.Prefix) bool {
+	pfx = pfx.Masked() // always canonicalize!
+
+	found := w.table.Delete(pfx)
+	if found {
+		w.notify(Event[V]{Prefix: pfx, Deleted: true})
+	}
+	return found
+}
+
+// Union combines the other table into the table and notifies the watchers of all entries
+// of other, see [Table.Union].
+func (w *Watched[V]) Union(other Table[V]) {
+	// Union changes the nodes of other, collect the events before
+	var events []Event[V]
+	other.Walk(func(pfx netip.Prefix, value V) bool {
+		events = append(events, Event[V]{Prefix: pfx, Value: value})
+		return true
+	})
+
+	w.table.Union(other)
+	w.notify(events...)
+}
+
+// DeleteSubtree removes pfx and all prefixes covered by pfx and notifies the watchers
+// of all removed entries, see [Table.DeleteSubtree].
+func (w *Watched[V]) DeleteSubtree(pfx netip.Prefix) bool {
+	pfx = pfx.Masked() // always canonicalize!
+
+	var events []Event[V]
+	for _, e := range w.table.subtree(pfx) {
+		events = append(events, Event[V]{Prefix: e.Prefix, Deleted: true})
+	}
+
+	found := w.table.DeleteSubtree(pfx)
+	w.notify(events...)
+	return found
+}
+
+// ReplaceSubtree replaces pfx and all prefixes covered by pfx with the prefixes of sub
+// covered by pfx, see [Table.ReplaceSubtree]. The watchers are notified of the removed
+// entries first, then of all spliced in entries.
+func (w *Watched[V]) ReplaceSubtree(pfx netip.Prefix, sub Table[V]) {
+	pfx = pfx.Masked() // always canonicalize!
+
+	added := sub.subtree(pfx)
+
+	var events []Event[V]
+	for _, e := range w.table.subtree(pfx) {
+		if _, ok := sub.get(e.Prefix); !ok {
+			events = append(events, Event[V]{Prefix: e.Prefix, Deleted: true})
+		}
+	}
+	for _, e := range added {
+		events = append(events, Event[V]{Prefix: e.Prefix, Value: e.Value})
+	}
+
+	w.table.ReplaceSubtree(pfx, sub)
+	w.notify(events...)
+}
+
+// ModifyAll replaces the values of all entries by the values returned from cb and
+// notifies the watchers of all changed and removed entries, see [Table.ModifyAll].
+func (w *Watched[V]) ModifyAll(cb func(pfx netip.Prefix, value V) (newValue V, del bool)) int {
+	var events []Event[V]
+	n := w.table.ModifyAll(func(pfx netip.Prefix, value V) (V, bool) {
+		newValue, del := cb(pfx, value)
+		if del {
+			events = append(events, Event[V]{Prefix: pfx, Deleted: true})
+		} else {
+			events = append(events, Event[V]{Prefix: pfx, Value: newValue})
+		}
+		return newValue, del
+	})
+
+	w.notify(events...)
+	return n
+}
+
+// Lookup returns the longest-prefix-match for ip, see [Table.Lookup].
+func (w *Watched[V]) Lookup(ip netip.Addr) (lpm netip.Prefix, value V, ok bool) {
+	return w.table.Lookup(ip)
+}
+
+// LookupPrefix returns the longest-prefix-match for pfx, see [Table.LookupPrefix].
+func (w *Watched[V]) LookupPrefix(pfx netip.Prefix) (lpm netip.Prefix, value V, ok bool) {
+	return w.table.LookupPrefix(pfx)
+}
+
+// Table returns a clone of the watched table for all other read-only operations, O(n).
+// Changes of the clone are not notified and don't affect the watched table.
+func (w *Watched[V]) Table() Table[V] {
+	return *w.table.Clone()
+}
+
+// notify the watchers of all scopes covering the prefixes of the events, in order of the events.
+func (w *Watched[V]) notify(events ...Event[V]) {
+	if w.scopes.IsEmpty() {
+		return
+	}
+
+	for _, ev := range events {
+		var watchers []*watcher[V]
+		for _, n := range w.scopes.supernets(ev.Prefix) {
+			watchers = append(watchers, n.value...)
+		}
+		if exact, ok := w.scopes.get(ev.Prefix); ok {
+			watchers = append(watchers, exact...)
+		}
+
+		for _, wr := range watchers {
+			if !wr.cancelled {
+				wr.fn(ev)
+			}
+		}
+	}
+}
+
+// subtree returns pfx and all entries covered by pfx in ascending order.
+func (t Table[V]) subtree(pfx netip.Prefix) []Entry[V] {
+	n := t.root6
+	if pfx.Addr().Is4() {
+		n = t.root4
+	}
+
+	_, last := extnetip.Range(pfx)
+	lastHost := netip.PrefixFrom(last, last.BitLen())
+
+	var entries []Entry[V]
+	n.walkRange(pfx, lastHost, func(m *node[V]) bool {
+		entries = append(entries, Entry[V]{Prefix: m.cidr, Value: m.value})
+		return true
+	})
+	return entries
+}
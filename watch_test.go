@@ -0,0 +1,148 @@
+package cidrtree_test
+
+import (
+	"net/netip"
+	"slices"
+	"testing"
+
+	"github.com/gaissmai/cidrtree"
+)
+
+func TestWatched(t *testing.T) {
+	t.Parallel()
+
+	wt := cidrtree.NewWatched(new(cidrtree.Table[int]))
+
+	var all, alloc, host []netip.Prefix
+	cancelAll := wt.Watch(mustPfx("0.0.0.0/0"), func(ev cidrtree.Event[int]) { all = append(all, ev.Prefix) })
+	wt.Watch(mustPfx("10.1.0.0/16"), func(ev cidrtree.Event[int]) { alloc = append(alloc, ev.Prefix) })
+	wt.Watch(mustPfx("10.1.2.3/32"), func(ev cidrtree.Event[int]) { host = append(host, ev.Prefix) })
+
+	var deleted []cidrtree.Event[int]
+	wt.Watch(mustPfx("10.1.0.0/16"), func(ev cidrtree.Event[int]) {
+		if ev.Deleted {
+			deleted = append(deleted, ev)
+		}
+	})
+
+	wt.Insert(mustPfx("10.0.0.0/8"), 1)    // outside the allocation
+	wt.Insert(mustPfx("10.1.0.0/16"), 2)   // the scope itself
+	wt.Insert(mustPfx("10.1.2.0/24"), 3)   // inside
+	wt.Insert(mustPfx("10.1.2.3/32"), 4)   // inside, host scope
+	wt.Insert(mustPfx("2001:db8::/32"), 5) // other IP version
+
+	if ok := wt.Delete(mustPfx("10.1.2.0/24")); !ok {
+		t.Errorf("Delete, got %v, want true", ok)
+	}
+	wt.Delete(mustPfx("10.1.9.0/24")) // missing, no event
+
+	if len(all) != 5 {
+		t.Errorf("watch 0.0.0.0/0, got %v, want 5 events", all)
+	}
+	if len(alloc) != 4 || alloc[0] != mustPfx("10.1.0.0/16") {
+		t.Errorf("watch 10.1.0.0/16, got %v, want 4 events", alloc)
+	}
+	if len(host) != 1 || host[0] != mustPfx("10.1.2.3/32") {
+		t.Errorf("watch 10.1.2.3/32, got %v, want [10.1.2.3/32]", host)
+	}
+	if len(deleted) != 1 || deleted[0].Prefix != mustPfx("10.1.2.0/24") || deleted[0].Value != 0 {
+		t.Errorf("deleted events, got %v", deleted)
+	}
+
+	// the watched table is mutated
+	if _, value, _ := wt.Lookup(mustAddr("10.1.2.1")); value != 2 {
+		t.Errorf("Lookup, got %v, want %v", value, 2)
+	}
+
+	cancelAll()
+	cancelAll() // idempotent
+	wt.Insert(mustPfx("192.168.0.0/16"), 6)
+	if len(all) != 5 {
+		t.Errorf("watch after cancel, got %v, want 5 events", all)
+	}
+}
+
+func TestWatchedCancelInCallback(t *testing.T) {
+	t.Parallel()
+
+	wt := cidrtree.NewWatched(new(cidrtree.Table[int]))
+
+	var second int
+	var cancelSecond func()
+	wt.Watch(mustPfx("10.0.0.0/8"), func(cidrtree.Event[int]) { cancelSecond() })
+	cancelSecond = wt.Watch(mustPfx("10.0.0.0/8"), func(cidrtree.Event[int]) { second++ })
+
+	wt.Insert(mustPfx("10.0.0.0/24"), 1)
+	wt.Insert(mustPfx("10.0.1.0/24"), 1)
+
+	if second != 0 {
+		t.Errorf("cancelled in callback, got %v events, want 0", second)
+	}
+}
+
+func TestWatchedBulk(t *testing.T) {
+	t.Parallel()
+
+	wt := cidrtree.NewWatched(new(cidrtree.Table[int]))
+	wt.Insert(mustPfx("10.1.0.0/16"), 1)
+	wt.Insert(mustPfx("10.1.2.0/24"), 2)
+	wt.Insert(mustPfx("10.2.0.0/16"), 3)
+
+	var events []cidrtree.Event[int]
+	wt.Watch(mustPfx("10.1.0.0/16"), func(ev cidrtree.Event[int]) { events = append(events, ev) })
+
+	check := func(name string, want []cidrtree.Event[int]) {
+		t.Helper()
+		if !slices.Equal(events, want) {
+			t.Errorf("%s, got events %v, want %v", name, events, want)
+		}
+		events = nil
+	}
+
+	other := new(cidrtree.Table[int])
+	other.Insert(mustPfx("10.1.3.0/24"), 4)
+	other.Insert(mustPfx("10.3.0.0/16"), 5) // outside the scope
+	wt.Union(*other)
+	check("Union", []cidrtree.Event[int]{{Prefix: mustPfx("10.1.3.0/24"), Value: 4}})
+
+	n := wt.ModifyAll(func(pfx netip.Prefix, value int) (int, bool) {
+		return value * 10, pfx == mustPfx("10.1.3.0/24")
+	})
+	if n != 1 {
+		t.Errorf("ModifyAll, got %v, want %v", n, 1)
+	}
+	check("ModifyAll", []cidrtree.Event[int]{
+		{Prefix: mustPfx("10.1.0.0/16"), Value: 10},
+		{Prefix: mustPfx("10.1.2.0/24"), Value: 20},
+		{Prefix: mustPfx("10.1.3.0/24"), Deleted: true},
+	})
+
+	sub := new(cidrtree.Table[int])
+	sub.Insert(mustPfx("10.1.0.0/16"), 6)
+	sub.Insert(mustPfx("10.1.4.0/24"), 7)
+	wt.ReplaceSubtree(mustPfx("10.1.0.0/16"), *sub)
+	check("ReplaceSubtree", []cidrtree.Event[int]{
+		{Prefix: mustPfx("10.1.2.0/24"), Deleted: true},
+		{Prefix: mustPfx("10.1.0.0/16"), Value: 6},
+		{Prefix: mustPfx("10.1.4.0/24"), Value: 7},
+	})
+
+	if ok := wt.DeleteSubtree(mustPfx("10.1.0.0/16")); !ok {
+		t.Errorf("DeleteSubtree, got %v, want true", ok)
+	}
+	check("DeleteSubtree", []cidrtree.Event[int]{
+		{Prefix: mustPfx("10.1.0.0/16"), Deleted: true},
+		{Prefix: mustPfx("10.1.4.0/24"), Deleted: true},
+	})
+
+	// the read-only view
+	tbl := wt.Table()
+	if got := tbl.Size(); got != 2 {
+		t.Errorf("Table().Size(), got %v, want %v", got, 2)
+	}
+	tbl.Insert(mustPfx("10.1.5.0/24"), 8)
+	check("Insert into the clone", nil)
+	if lpm, _, ok := wt.LookupPrefix(mustPfx("10.1.5.0/24")); ok {
+		t.Errorf("LookupPrefix after Insert into the clone, got %v, want not found", lpm)
+	}
+}
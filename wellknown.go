@@ -0,0 +1,117 @@
+package cidrtree
+
+import "net/netip"
+
+// WellKnown describes a well-known prefix.
+type WellKnown struct {
+	Name string
+	RFC  string
+}
+
+type wellKnownEntry struct {
+	cidr string
+	WellKnown
+}
+
+// IANA IPv4 and IPv6 Special-Purpose Address Registries
+var specialPurpose = []wellKnownEntry{
+	{"0.0.0.0/8", WellKnown{"This network", "RFC 791"}},
+	{"0.0.0.0/32", WellKnown{"This host on this network", "RFC 1122"}},
+	{"10.0.0.0/8", WellKnown{"Private-Use", "RFC 1918"}},
+	{"100.64.0.0/10", WellKnown{"Shared Address Space", "RFC 6598"}},
+	{"127.0.0.0/8", WellKnown{"Loopback", "RFC 1122"}},
+	{"169.254.0.0/16", WellKnown{"Link Local", "RFC 3927"}},
+	{"172.16.0.0/12", WellKnown{"Private-Use", "RFC 1918"}},
+	{"192.0.0.0/24", WellKnown{"IETF Protocol Assignments", "RFC 6890"}},
+	{"192.0.0.0/29", WellKnown{"IPv4 Service Continuity Prefix", "RFC 7335"}},
+	{"192.0.0.8/32", WellKnown{"IPv4 dummy address", "RFC 7600"}},
+	{"192.0.0.9/32", WellKnown{"Port Control Protocol Anycast", "RFC 7723"}},
+	{"192.0.0.10/32", WellKnown{"Traversal Using Relays around NAT Anycast", "RFC 8155"}},
+	{"192.0.0.170/32", WellKnown{"NAT64/DNS64 Discovery", "RFC 8880"}},
+	{"192.0.0.171/32", WellKnown{"NAT64/DNS64 Discovery", "RFC 8880"}},
+	{"192.0.2.0/24", WellKnown{"Documentation (TEST-NET-1)", "RFC 5737"}},
+	{"192.31.196.0/24", WellKnown{"AS112-v4", "RFC 7535"}},
+	{"192.52.193.0/24", WellKnown{"AMT", "RFC 7450"}},
+	{"192.88.99.0/24", WellKnown{"Deprecated (6to4 Relay Anycast)", "RFC 7526"}},
+	{"192.168.0.0/16", WellKnown{"Private-Use", "RFC 1918"}},
+	{"192.175.48.0/24", WellKnown{"Direct Delegation AS112 Service", "RFC 7534"}},
+	{"198.18.0.0/15", WellKnown{"Benchmarking", "RFC 2544"}},
+	{"198.51.100.0/24", WellKnown{"Documentation (TEST-NET-2)", "RFC 5737"}},
+	{"203.0.113.0/24", WellKnown{"Documentation (TEST-NET-3)", "RFC 5737"}},
+	{"240.0.0.0/4", WellKnown{"Reserved", "RFC 1112"}},
+	{"255.255.255.255/32", WellKnown{"Limited Broadcast", "RFC 919"}},
+
+	{"::/128", WellKnown{"Unspecified Address", "RFC 4291"}},
+	{"::1/128", WellKnown{"Loopback Address", "RFC 4291"}},
+	{"::ffff:0:0/96", WellKnown{"IPv4-mapped Address", "RFC 4291"}},
+	{"64:ff9b::/96", WellKnown{"IPv4-IPv6 Translation", "RFC 6052"}},
+	{"64:ff9b:1::/48", WellKnown{"IPv4-IPv6 Translation", "RFC 8215"}},
+	{"100::/64", WellKnown{"Discard-Only Address Block", "RFC 6666"}},
+	{"2001::/23", WellKnown{"IETF Protocol Assignments", "RFC 2928"}},
+	{"2001::/32", WellKnown{"TEREDO", "RFC 4380"}},
+	{"2001:1::1/128", WellKnown{"Port Control Protocol Anycast", "RFC 7723"}},
+	{"2001:1::2/128", WellKnown{"Traversal Using Relays around NAT Anycast", "RFC 8155"}},
+	{"2001:2::/48", WellKnown{"Benchmarking", "RFC 5180"}},
+	{"2001:3::/32", WellKnown{"AMT", "RFC 7450"}},
+	{"2001:4:112::/48", WellKnown{"AS112-v6", "RFC 7535"}},
+	{"2001:10::/28", WellKnown{"Deprecated (previously ORCHID)", "RFC 4843"}},
+	{"2001:20::/28", WellKnown{"ORCHIDv2", "RFC 7343"}},
+	{"2001:30::/28", WellKnown{"Drone Remote ID Protocol Entity Tags (DETs) Prefix", "RFC 9374"}},
+	{"2001:db8::/32", WellKnown{"Documentation", "RFC 3849"}},
+	{"2002::/16", WellKnown{"6to4", "RFC 3056"}},
+	{"2620:4f:8000::/48", WellKnown{"Direct Delegation AS112 Service", "RFC 7534"}},
+	{"3fff::/20", WellKnown{"Documentation", "RFC 9637"}},
+	{"5f00::/16", WellKnown{"Segment Routing (SRv6) SIDs", "RFC 9602"}},
+	{"fc00::/7", WellKnown{"Unique-Local", "RFC 4193"}},
+	{"fe80::/10", WellKnown{"Link-Local Unicast", "RFC 4291"}},
+}
+
+// prefixes that should never appear in the global routing table
+var bogons = []wellKnownEntry{
+	{"0.0.0.0/8", WellKnown{"This network", "RFC 791"}},
+	{"10.0.0.0/8", WellKnown{"Private-Use", "RFC 1918"}},
+	{"100.64.0.0/10", WellKnown{"Shared Address Space", "RFC 6598"}},
+	{"127.0.0.0/8", WellKnown{"Loopback", "RFC 1122"}},
+	{"169.254.0.0/16", WellKnown{"Link Local", "RFC 3927"}},
+	{"172.16.0.0/12", WellKnown{"Private-Use", "RFC 1918"}},
+	{"192.0.0.0/24", WellKnown{"IETF Protocol Assignments", "RFC 6890"}},
+	{"192.0.2.0/24", WellKnown{"Documentation (TEST-NET-1)", "RFC 5737"}},
+	{"192.168.0.0/16", WellKnown{"Private-Use", "RFC 1918"}},
+	{"198.18.0.0/15", WellKnown{"Benchmarking", "RFC 2544"}},
+	{"198.51.100.0/24", WellKnown{"Documentation (TEST-NET-2)", "RFC 5737"}},
+	{"203.0.113.0/24", WellKnown{"Documentation (TEST-NET-3)", "RFC 5737"}},
+	{"224.0.0.0/4", WellKnown{"Multicast", "RFC 5771"}},
+	{"240.0.0.0/4", WellKnown{"Reserved", "RFC 1112"}},
+
+	{"::/8", WellKnown{"Reserved by IETF", "RFC 4291"}},
+	{"100::/64", WellKnown{"Discard-Only Address Block", "RFC 6666"}},
+	{"2001:2::/48", WellKnown{"Benchmarking", "RFC 5180"}},
+	{"2001:10::/28", WellKnown{"Deprecated (previously ORCHID)", "RFC 4843"}},
+	{"2001:db8::/32", WellKnown{"Documentation", "RFC 3849"}},
+	{"3ffe::/16", WellKnown{"Deprecated (6bone)", "RFC 3701"}},
+	{"3fff::/20", WellKnown{"Documentation", "RFC 9637"}},
+	{"fc00::/7", WellKnown{"Unique-Local", "RFC 4193"}},
+	{"fe80::/10", WellKnown{"Link-Local Unicast", "RFC 4291"}},
+	{"fec0::/10", WellKnown{"Deprecated (Site-Local)", "RFC 3879"}},
+	{"ff00::/8", WellKnown{"Multicast", "RFC 4291"}},
+}
+
+// SpecialPurpose returns a new table with the IANA IPv4 and IPv6 Special-Purpose Address Registries.
+func SpecialPurpose() *Table[WellKnown] {
+	return wellKnownTable(specialPurpose)
+}
+
+// Bogons returns a new table with the bogon prefixes, the martians that should never
+// appear as source or destination in the public internet. The unallocated address space
+// (fullbogons) changes over time, it isn't part of this table.
+func Bogons() *Table[WellKnown] {
+	return wellKnownTable(bogons)
+}
+
+func wellKnownTable(list []wellKnownEntry) *Table[WellKnown] {
+	entries := make([]Entry[WellKnown], 0, len(list))
+	for _, e := range list {
+		entries = append(entries, Entry[WellKnown]{Prefix: netip.MustParsePrefix(e.cidr), Value: e.WellKnown})
+	}
+	return new(Table[WellKnown]).InsertManyImmutable(entries)
+}
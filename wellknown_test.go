@@ -0,0 +1,59 @@
+package cidrtree_test
+
+import (
+	"testing"
+
+	"github.com/gaissmai/cidrtree"
+)
+
+func TestSpecialPurpose(t *testing.T) {
+	t.Parallel()
+
+	sp := cidrtree.SpecialPurpose()
+
+	tests := []struct {
+		ip   string
+		want string
+		ok   bool
+	}{
+		{"10.1.2.3", "Private-Use", true},
+		{"192.0.0.9", "Port Control Protocol Anycast", true},
+		{"192.0.0.200", "IETF Protocol Assignments", true},
+		{"0.0.0.0", "This host on this network", true},
+		{"::1", "Loopback Address", true},
+		{"2001:db8::1", "Documentation", true},
+		{"8.8.8.8", "", false},
+		{"2a00::1", "", false},
+	}
+
+	for _, tt := range tests {
+		_, wk, ok := sp.Lookup(mustAddr(tt.ip))
+		if ok != tt.ok || wk.Name != tt.want {
+			t.Errorf("SpecialPurpose().Lookup(%v), got (%q, %v), want (%q, %v)", tt.ip, wk.Name, ok, tt.want, tt.ok)
+		}
+	}
+
+	// a new table for every call
+	sp.Delete(mustPfx("10.0.0.0/8"))
+	if !cidrtree.SpecialPurpose().Contains(mustAddr("10.1.2.3")) {
+		t.Errorf("SpecialPurpose() isn't a new table")
+	}
+}
+
+func TestBogons(t *testing.T) {
+	t.Parallel()
+
+	bogons := cidrtree.Bogons()
+
+	for _, s := range []string{"10.1.2.3", "224.0.0.1", "255.255.255.255", "fe80::1", "ff02::1", "3ffe::1"} {
+		if !bogons.Contains(mustAddr(s)) {
+			t.Errorf("Bogons().Contains(%v), got false, want true", s)
+		}
+	}
+
+	for _, s := range []string{"8.8.8.8", "2a00:1450::1", "192.0.3.1"} {
+		if bogons.Contains(mustAddr(s)) {
+			t.Errorf("Bogons().Contains(%v), got true, want false", s)
+		}
+	}
+}
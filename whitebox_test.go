@@ -1,15 +1,16 @@
 package cidrtree
 
 import (
-	"bufio"
-	"compress/gzip"
 	crand "crypto/rand"
 	"log"
+	"math"
+	"math/bits"
 	mrand "math/rand"
 	"net/netip"
-	"os"
 	"strings"
 	"testing"
+	"time"
+	"unsafe"
 )
 
 func TestFprintBSTVerbose(t *testing.T) {
@@ -148,6 +149,110 @@ func TestLPMFullTableWithDefaultRoutesVerbose(t *testing.T) {
 	}
 }
 
+func TestAugmentedSize(t *testing.T) {
+	rtbl := new(Table[any])
+	for c := 0; c <= 10_000; c++ {
+		rtbl.Insert(randPfx(), nil)
+	}
+	for c := 0; c <= 1_000; c++ {
+		rtbl.Delete(randPfx())
+		rtbl, _ = rtbl.DeleteImmutable(randPfx())
+		rtbl = rtbl.InsertImmutable(randPfx(), nil)
+	}
+
+	other := new(Table[any])
+	for c := 0; c <= 1_000; c++ {
+		other.Insert(randPfx(), nil)
+	}
+	rtbl = rtbl.UnionImmutable(*other)
+	rtbl.DeleteSubtree(randPfx4())
+
+	var check func(n *node[any]) (int, int)
+	check = func(n *node[any]) (size, height int) {
+		if n == nil {
+			return 0, 0
+		}
+		lsize, lheight := check(n.left)
+		rsize, rheight := check(n.right)
+
+		minBits, maxBits := n.cidr.Bits(), n.cidr.Bits()
+		n.walkNodes(func(m *node[any]) bool {
+			minBits, maxBits = min(minBits, m.cidr.Bits()), max(maxBits, m.cidr.Bits())
+			return true
+		})
+		if int(n.minBits) != minBits || int(n.maxBits) != maxBits {
+			t.Fatalf("augmented bits of %v are [%d, %d], want [%d, %d]", n.cidr, n.minBits, n.maxBits, minBits, maxBits)
+		}
+
+		size = 1 + lsize + rsize
+		if int(n.size) != size {
+			t.Fatalf("augmented size of %v is %d, want %d", n.cidr, n.size, size)
+		}
+
+		height = 1 + max(lheight, rheight)
+		if int(n.height) != height {
+			t.Fatalf("augmented height of %v is %d, want %d", n.cidr, n.height, height)
+		}
+		return size, height
+	}
+
+	_, want4 := check(rtbl.root4)
+	_, want6 := check(rtbl.root6)
+
+	if got4, got6 := rtbl.Height(); got4 != want4 || got6 != want6 {
+		t.Errorf("Height, got (%d, %d), want (%d, %d)", got4, got6, want4, want6)
+	}
+}
+
+func TestPromote(t *testing.T) {
+	rtbl := new(Table[any])
+	for c := 0; c <= 10_000; c++ {
+		rtbl.Insert(randPfx4(), nil)
+	}
+
+	hot := randPfx4()
+	rtbl.Insert(hot, nil)
+	size := rtbl.root4.getSize()
+
+	// about 32 halvings of the prio distance reach the top band of the promoted entries
+	for c := 0; c < 100; c++ {
+		if !rtbl.Promote(hot) {
+			t.Fatalf("Promote(%v), got false, want true", hot)
+		}
+	}
+
+	if rtbl.root4.cidr != hot {
+		t.Errorf("Promote(%v), root is %v", hot, rtbl.root4.cidr)
+	}
+	if got := rtbl.root4.getSize(); got != size {
+		t.Errorf("Promote(%v), size is %d, want %d", hot, got, size)
+	}
+}
+
+func TestPromoteHeightBounded(t *testing.T) {
+	t.Parallel()
+
+	rtbl := new(Table[any])
+	for c := 0; c <= 10_000; c++ {
+		rtbl.Insert(randPfx4(), nil)
+	}
+
+	// many hot prefixes, promoted over and over in ascending order
+	hot := rtbl.Sample(1_000)
+	for round := 0; round < 100; round++ {
+		for _, e := range hot {
+			rtbl.Promote(e.Prefix)
+		}
+	}
+
+	// randomized treap, the expected height is about 3*log2(n)
+	height, _ := rtbl.Height()
+	if limit := 4 * bits.Len(uint(rtbl.Size())); height > limit {
+		t.Errorf("Height after repeated Promote, got %d, want at most %d", height, limit)
+	}
+	t.Logf("height %d, size %d", height, rtbl.Size())
+}
+
 // ###################################################
 // ### helpers
 // ###################################################
@@ -220,27 +325,111 @@ var (
 )
 
 func loadFullTable() []netip.Prefix {
-	var routes []netip.Prefix
-
-	file, err := os.Open(prefixFile)
+	routes, err := LoadPrefixFile(prefixFile)
 	if err != nil {
 		log.Fatal(err)
 	}
+	return routes
+}
 
-	rgz, err := gzip.NewReader(file)
-	if err != nil {
-		log.Fatal(err)
+func TestFprintWithColor(t *testing.T) {
+	rtbl := new(Table[string])
+	rtbl.Insert(netip.MustParsePrefix("10.0.0.0/8"), "a")
+	rtbl.Insert(netip.MustParsePrefix("10.0.0.0/24"), "b")
+	rtbl.Insert(netip.MustParsePrefix("10.1.0.0/16"), "plain")
+
+	depths := make(map[netip.Prefix]int)
+	opts := fprintOptions[string]{
+		color: func(pfx netip.Prefix, value string, depth int) string {
+			depths[pfx] = depth
+			if value == "plain" {
+				return ""
+			}
+			return colorByDepth(pfx, value, depth)
+		},
+	}
+
+	w := new(strings.Builder)
+	if err := rtbl.root4.fprintWith(w, opts); err != nil {
+		t.Fatal(err)
 	}
 
-	scanner := bufio.NewScanner(rgz)
-	for scanner.Scan() {
-		line := scanner.Text()
-		line = strings.TrimSpace(line)
-		cidr := netip.MustParsePrefix(line)
-		routes = append(routes, cidr)
+	want := "▼\n" +
+		"└─ \x1b[32m10.0.0.0/8 (a)\x1b[0m\n" +
+		"   ├─ \x1b[33m10.0.0.0/24 (b)\x1b[0m\n" +
+		"   └─ 10.1.0.0/16 (plain)\n"
+
+	if w.String() != want {
+		t.Errorf("fprintWith color\nwant:\n%q\ngot:\n%q", want, w.String())
 	}
-	if err := scanner.Err(); err != nil {
-		log.Printf("reading from %v, %v", rgz, err)
+
+	// 0-based as WalkTopology
+	rtbl.WalkTopology(func(pfx netip.Prefix, _ string, depth int) bool {
+		if depths[pfx] != depth {
+			t.Errorf("color depth of %v, got %d, want %d", pfx, depths[pfx], depth)
+		}
+		return true
+	})
+}
+
+func TestNodeSize(t *testing.T) {
+	t.Parallel()
+
+	// the layout with netip.Addr and int augments, before the compaction
+	type wideNode struct {
+		maxLast netip.Addr
+		left    *node[any]
+		right   *node[any]
+		value   any
+		cidr    netip.Prefix
+		prio    uint64
+		size    int
+		height  int
+		meta    *meta[any]
+	}
+
+	if unsafe.Sizeof(uintptr(0)) != 8 {
+		t.Skip("layout checked on 64 bit platforms")
+	}
+
+	got, wide := unsafe.Sizeof(node[any]{}), unsafe.Sizeof(wideNode{})
+	t.Logf("node size %d bytes, wide layout %d bytes", got, wide)
+
+	// 8 bytes of the zone pointer and 8 bytes of the narrowed augments
+	if want := wide - 16; got > want {
+		t.Errorf("unsafe.Sizeof(node), got %d, want at most %d", got, want)
+	}
+}
+
+func TestSpaceSavingEvictsMin(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, halfLife := range []time.Duration{0, time.Second} {
+		s := newSpaceSaving(8, halfLife)
+
+		for i := 0; i < 10_000; i++ {
+			now = now.Add(time.Duration(mrand.Intn(100)) * time.Millisecond)
+			pfx := netip.PrefixFrom(netip.AddrFrom4([4]byte{10, 0, 0, byte(mrand.Intn(32))}), 32)
+
+			_, known := s.counters[pfx]
+			var minScore float64
+			if !known && len(s.counters) == s.size {
+				// the min score by scan, the heap must evict a counter with the same score
+				minScore = math.Inf(1)
+				for _, c := range s.counters {
+					minScore = min(minScore, (ssEntry{counts: s.decayed(c, now)}).score())
+				}
+			}
+
+			s.add(pfx, [2]float64{1, 0}, now)
+
+			if got := (ssEntry{counts: s.decayed(s.counters[pfx], now)}).score(); minScore != 0 && math.Abs(got-minScore-1) > 1e-9 {
+				t.Fatalf("halfLife %v, evicted score %v, want min score %v", halfLife, got-1, minScore)
+			}
+			if len(s.counters) != len(s.minHeap) {
+				t.Fatalf("halfLife %v, %d counters, %d in the heap", halfLife, len(s.counters), len(s.minHeap))
+			}
+		}
 	}
-	return routes
 }